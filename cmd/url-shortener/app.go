@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/migrator"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/migrations"
+)
+
+// app связывает хранилище и сервис одинаково для сервера и команд CLI
+type app struct {
+	database *postgres.StoragePool
+	storage  *storage.Storage
+	service  *service.Service
+	migrator *migrator.Migrator
+	// shortURLs собирает short_url для ответов API и вывода CLI
+	shortURLs shorturl.Builder
+}
+
+func newApp(ctx context.Context, cfg config.Config) (*app, error) {
+	shortURLs, err := shorturl.New(cfg.PublicBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	database, err := postgres.NewDatabase(ctx, cfg.DB_config_path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init db: %w", err)
+	}
+	migrator, err := migrator.New(database.GetPool(), migrations.FS)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	storage := storage.NewStorage(database, storage.Timeouts{
+		Read:  cfg.Storage.QueryTimeouts.Read,
+		Write: cfg.Storage.QueryTimeouts.Write,
+		Batch: cfg.Storage.QueryTimeouts.Batch,
+	})
+
+	return &app{
+		database:  database,
+		storage:   storage,
+		service:   service.NewService(storage, cfg.Redirect.DefaultDomain),
+		migrator:  migrator,
+		shortURLs: shortURLs,
+	}, nil
+}
+
+func (a *app) Close() {
+	a.database.Close()
+}
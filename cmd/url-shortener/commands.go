@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/list"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// stringsFlag собирает значения повторяющегося флага
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// timeFlag - необязательное время в формате RFC 3339
+type timeFlag struct{ t *time.Time }
+
+func (f *timeFlag) String() string {
+	if f.t == nil {
+		return ""
+	}
+	return f.t.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(v string) error {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return err
+	}
+	f.t = &t
+	return nil
+}
+
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", outputTable, "output format: table or json")
+}
+
+func checkOutput(output string) error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unknown output format %q, expected table or json", output)
+	}
+	return nil
+}
+
+// variantsFlag собирает варианты A/B-теста в формате NAME:WEIGHT:URL
+type variantsFlag []postgres.Variant
+
+func (f *variantsFlag) String() string {
+	parts := make([]string, 0, len(*f))
+	for _, v := range *f {
+		parts = append(parts, fmt.Sprintf("%s:%d:%s", v.Name, v.Weight, v.URL))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *variantsFlag) Set(v string) error {
+	parts := strings.SplitN(v, ":", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("variant must be NAME:WEIGHT:URL, got %q", v)
+	}
+	weight, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("variant weight must be a number, got %q", parts[1])
+	}
+	*f = append(*f, postgres.Variant{Name: parts[0], Weight: weight, URL: parts[2]})
+	return nil
+}
+
+// runCreate создает ссылку через тот же service.CreateLink, что и POST /url
+func runCreate(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	alias := fs.String("alias", "", "custom alias (default: random)")
+	host := fs.String("domain", "", "registered brand domain (default: main domain)")
+	owner := fs.String("owner", "", "link owner")
+	var tags stringsFlag
+	fs.Var(&tags, "tag", "link tag, may be repeated")
+	redirectType := fs.Int("redirect-type", 0, "redirect status: 301, 302, 307 or 308 (default: from config)")
+	passthrough := fs.String("passthrough", "", "forward extra path and query to the url: keep, replace or append")
+	utmTemplate := fs.String("utm-template", "", "name of the owner's utm template to apply")
+	var variants variantsFlag
+	fs.Var(&variants, "variant", "A/B test variant NAME:WEIGHT:URL, may be repeated")
+	sticky := fs.String("sticky", "", "keep a visitor on one variant: cookie or hash")
+	password := fs.String("password", "", "password required to follow the link")
+	maxClicks := fs.Int("max-clicks", 0, "number of allowed redirects (default: unlimited)")
+	var activeFrom, expiresAt timeFlag
+	fs.Var(&activeFrom, "active-from", "RFC 3339 time the link starts redirecting (default: now)")
+	fs.Var(&expiresAt, "expires-at", "RFC 3339 time the link stops redirecting (default: never)")
+	title := fs.String("title", "", "title shown on the preview page")
+	interstitial := fs.Bool("interstitial", false, "show the preview page instead of redirecting")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: url-shortener create [-alias ALIAS] [-domain HOST] [-owner OWNER] [-tag TAG]... [-redirect-type CODE] [-passthrough POLICY] [-utm-template NAME] [-variant NAME:WEIGHT:URL]... [-sticky MODE] [-password PASSWORD] [-max-clicks N] [-active-from TIME] [-expires-at TIME] [-title TITLE] [-interstitial] URL")
+	}
+
+	req := links.Request{URL: fs.Arg(0), Alias: *alias, Domain: *host, Owner: *owner, Tags: tags, RedirectType: *redirectType, Passthrough: *passthrough, UTMTemplate: *utmTemplate, Password: *password, MaxClicks: *maxClicks, ActiveFrom: activeFrom.t, ExpiresAt: expiresAt.t, Title: *title, Interstitial: *interstitial}
+	if len(variants) > 0 || *sticky != "" {
+		req.Split = &postgres.Split{Sticky: *sticky, Variants: variants}
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	created, err := app.service.CreateLink(ctx, req)
+	if detail, ok := links.ClientError(err); ok {
+		return errors.New(detail)
+	}
+	if errors.Is(err, storage.ErrURLExists) {
+		return errors.New("url already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add url: %w", err)
+	}
+
+	link, err := app.service.GetLink(ctx, created.Domain, created.Alias)
+	if err != nil {
+		return err
+	}
+	log.Info("url added", slog.Int64("id", link.ID))
+	return printLinks(os.Stdout, *output, []get.Link{get.NewLink(link, app.shortURLs.URL(nil, link.Domain, link.Alias))}, false)
+}
+
+func runGet(ctx context.Context, _ *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	host := fs.String("domain", "", "brand domain of the link (default: main domain)")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: url-shortener get [-domain HOST] ALIAS")
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	link, err := app.service.GetLink(ctx, domain.Normalize(*host), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printLinks(os.Stdout, *output, []get.Link{get.NewLink(link, app.shortURLs.URL(nil, link.Domain, link.Alias))}, false)
+}
+
+func runDelete(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	host := fs.String("domain", "", "brand domain of the link (default: main domain)")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: url-shortener delete [-domain HOST] ALIAS")
+	}
+	alias := fs.Arg(0)
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := app.service.DeleteURl(ctx, domain.Normalize(*host), alias); err != nil {
+		return err
+	}
+	log.Info("url deleted", slog.String("alias", alias))
+
+	if *output == outputJSON {
+		return json.NewEncoder(os.Stdout).Encode(resp.OK())
+	}
+	_, err = fmt.Fprintf(os.Stdout, "deleted %s\n", alias)
+	return err
+}
+
+func runList(ctx context.Context, _ *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	owner := fs.String("owner", "", "only links of this owner")
+	tag := fs.String("tag", "", "only links with this tag")
+	host := fs.String("domain", "", "only links of this brand domain")
+	limit := fs.Int("limit", list.DefaultLimit, "page size")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *limit <= 0 || *limit > list.MaxLimit {
+		return fmt.Errorf("limit must be between 1 and %d", list.MaxLimit)
+	}
+	if *offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	links, err := app.service.ListURLs(ctx, postgres.Filter{Owner: *owner, Tag: *tag, Domain: domain.Normalize(*host)}, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	views := make([]get.Link, 0, len(links))
+	for _, link := range links {
+		views = append(views, get.NewLink(link, app.shortURLs.URL(nil, link.Domain, link.Alias)))
+	}
+	return printLinks(os.Stdout, *output, views, true)
+}
+
+// printLinks печатает ссылки таблицей или JSON в том же виде, что и HTTP API.
+// asList выводит JSON массивом, иначе печатается единственная ссылка.
+func printLinks(w io.Writer, output string, links []get.Link, asList bool) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if asList {
+			return enc.Encode(links)
+		}
+		return enc.Encode(links[0])
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tALIAS\tURL\tOWNER\tTAGS\tSTATE\tCREATED")
+	for _, link := range links {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			link.Id, link.Alias, link.URL, link.Owner, strings.Join(link.Tags, ","), link.State, link.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+)
+
+// runExport реализует команду:
+//
+//	url-shortener export [-format csv|jsonl] [-owner OWNER] [-from DATE] [-to DATE] [-out FILE]
+//
+// Без -out выгрузка печатается в stdout.
+func runExport(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := fs.String("format", string(transfer.FormatJSONL), "output format: csv or jsonl")
+	owner := fs.String("owner", "", "export only links of this owner")
+	from := fs.String("from", "", "export links created at or after this time (RFC 3339 or 2006-01-02)")
+	to := fs.String("to", "", "export links created before this time (RFC 3339 or 2006-01-02)")
+	out := fs.String("out", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	filter := postgres.Filter{Owner: *owner}
+	if *from != "" {
+		if filter.CreatedAfter, err = transfer.ParseTime(*from); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *to != "" {
+		if filter.CreatedBefore, err = transfer.ParseTime(*to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	count, err := transfer.Export(ctx, app.service, bw, format, filter, bw)
+	if err != nil {
+		return err
+	}
+
+	log.Info("export finished", slog.Int("count", count))
+	return nil
+}
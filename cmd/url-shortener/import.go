@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+)
+
+// runImport реализует команду:
+//
+//	url-shortener import [-format csv|jsonl] [-on-conflict skip|overwrite|fail] FILE
+//
+// FILE "-" означает stdin. Отчет печатается в stdout в формате JSON.
+func runImport(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := fs.String("format", "", "input format: csv or jsonl (default: by file extension)")
+	onConflict := fs.String("on-conflict", string(postgres.ConflictSkip), "conflict policy: skip, overwrite or fail")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: url-shortener import [-format csv|jsonl] [-on-conflict skip|overwrite|fail] FILE")
+	}
+
+	policy := postgres.ConflictPolicy(*onConflict)
+	if !policy.Valid() {
+		return fmt.Errorf("unknown conflict policy %q", *onConflict)
+	}
+
+	name := fs.Arg(0)
+	if *formatName == "" && filepath.Ext(name) != "" {
+		*formatName = filepath.Ext(name)[1:]
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	report, importErr := transfer.Import(ctx, app.service, in, format, policy)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if importErr != nil {
+		return importErr
+	}
+
+	log.Info("import finished", slog.Int("imported", report.Imported), slog.Int("rejected", len(report.Rejected)))
+	return nil
+}
@@ -2,114 +2,108 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 	"url-shortener/internal/config"
-	"url-shortener/internal/http-server/handlers/redirect"
-	"url-shortener/internal/http-server/handlers/url/delete"
-	"url-shortener/internal/http-server/handlers/url/save"
-	"url-shortener/internal/http-server/middleware/logger"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
+	"url-shortener/internal/lib/logger/handlers/slogredact"
 	slogger "url-shortener/internal/lib/logger/slog"
-	"url-shortener/internal/service"
-	"url-shortener/internal/storage"
-	"url-shortener/internal/storage/postgres"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error
+}
+
+const configPath = "config/local.yaml"
+
+// logLevel - текущий уровень логирования, общий для всех обработчиков slog
+var logLevel slog.LevelVar
+
+var commands = []command{
+	{name: "serve", usage: "serve", run: runServe},
+	{name: "create", usage: "create [-alias ALIAS] [-owner OWNER] [-tag TAG]... [-o table|json] URL", run: runCreate},
+	{name: "get", usage: "get [-o table|json] ALIAS", run: runGet},
+	{name: "delete", usage: "delete [-o table|json] ALIAS", run: runDelete},
+	{name: "list", usage: "list [-owner OWNER] [-tag TAG] [-limit N] [-offset N] [-o table|json]", run: runList},
+	{name: "migrate", usage: "migrate up|down [N]|status", run: runMigrate},
+	{name: "import", usage: "import [-format csv|jsonl] [-on-conflict skip|overwrite|fail] FILE", run: runImport},
+	{name: "export", usage: "export [-format csv|jsonl] [-owner OWNER] [-from DATE] [-to DATE] [-out FILE]", run: runExport},
+}
+
+// Без аргументов бинарник запускает сервер, как и раньше
 func main() {
 	ctx := context.Background()
-	log := setupPrettySlog()
-	cfg, err := config.MustLoadConfig("config/local.yaml")
-	if err != nil {
-		log.Error("Ошибка загрузки конфига", slogger.Err(err))
-		os.Exit(1)
-	}
-
-	fmt.Println(cfg.DB_config_path)
-	log.Info("starting url-shortener", slog.String("env", cfg.HTTPServer.Address))
-	log.Debug("debug messages are enabled")
 
-	database, err := postgres.NewDatabase(ctx, cfg.DB_config_path)
-	if err != nil {
-		log.Error("failed to init db", slogger.Err(err))
-		os.Exit(1)
-	}
-	storage := storage.NewStorage(database)
-	service := service.NewService(storage)
-	handlers := save.NewHandlers(service)
-
-	router := chi.NewRouter()
-	router.Use(middleware.RequestID)
-	router.Use(middleware.Logger)
-	router.Use(logger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
-	router.Route("/url", func(r chi.Router) {
-		r.Post("/", handlers.New(ctx, log))
-		r.Delete("/{alias}", delete.New(ctx, log, storage))
-
-	})
-	router.Get("/{alias}", redirect.New(ctx, log, storage))
-
-	log.Info("starting server", slog.String("address", cfg.Address))
-
-	srv := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	serverErr := make(chan error, 1)
-	go func() {
-		log.Info("Starting server", slog.String("address", cfg.Address))
-		err := srv.ListenAndServe()
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error("failed to start server", slogger.Err(err))
+	cmd, ok := findCommand(name)
+	if !ok {
+		printUsage(os.Stderr)
+		if name != "help" {
+			os.Exit(2)
 		}
-		close(serverErr)
-	}()
+		return
+	}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	// Вывод команд CLI идет в stdout, поэтому их логи пишутся в stderr
+	logOut := os.Stderr
+	if cmd.name == "serve" {
+		logOut = os.Stdout
+	}
 
-	log.Info("server started")
+	cfg, err := config.MustLoadConfig(configPath)
+	if err != nil {
+		setupLogger(config.EnvLocal, logOut).Error("Ошибка загрузки конфига", slogger.Err(err))
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Level)
+	log := setupLogger(cfg.Env, logOut)
 
-	select {
-	case err := <-serverErr:
-		log.Error("server error", slogger.Err(err))
-		return
-	case sig := <-done:
-		log.Info("Shutting down...", slog.String("signal", sig.String()))
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	if err := cmd.run(ctx, log, cfg, args); err != nil {
+		log.Error(cmd.name+" failed", slogger.Err(err))
+		os.Exit(1)
+	}
+}
 
-		if err := srv.Shutdown(ctx); err != nil {
-			log.Error("server shutdown failed", slogger.Err(err))
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
-		log.Info("Server gracefully stopped", slog.String("address", cfg.Address))
-		return
 	}
+	return command{}, false
 }
 
-func setupPrettySlog() *slog.Logger {
-	opts := slogpretty.PrettyHandlerOptions{
-		SlogOpts: &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		},
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: url-shortener <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(w, "  "+cmd.usage)
 	}
+}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+// setupLogger выбирает формат логов по окружению. Уровень берется из logLevel,
+// поэтому его можно менять на лету через /admin/log-level или SIGHUP.
+func setupLogger(env string, out io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: &logLevel}
+
+	var handler slog.Handler
+	switch env {
+	case config.EnvProd:
+		handler = slog.NewJSONHandler(out, opts)
+	case config.EnvDev:
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		handler = slogpretty.PrettyHandlerOptions{SlogOpts: opts}.NewPrettyHandler(out)
+	}
 
-	return slog.New(handler)
+	return slog.New(slogredact.New(handler))
 }
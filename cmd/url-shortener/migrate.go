@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"url-shortener/internal/config"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/migrator"
+)
+
+func runMigrate(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) error {
+	direction := "up"
+	if len(args) > 0 {
+		direction, args = args[0], args[1:]
+	}
+
+	steps := 1
+	switch direction {
+	case "up", "status":
+		if len(args) > 0 {
+			return fmt.Errorf("migrate %s takes no arguments", direction)
+		}
+	case "down":
+		if len(args) > 1 {
+			return errors.New("migrate down takes at most one argument")
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[0])
+			}
+			steps = n
+		}
+	default:
+		return fmt.Errorf("unknown migrate direction %q, expected up, down or status", direction)
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+	m := app.migrator
+
+	switch direction {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("migrations applied", slog.Int("applied", applied), slog.Uint64("version", uint64(m.Latest())))
+	case "down":
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		version, _, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("migrations reverted", slog.Int("reverted", reverted), slog.Uint64("version", uint64(version)))
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "version: %d\nlatest:  %d\ndirty:   %t\n", status.Version, status.Latest, status.Dirty)
+		for _, mig := range status.Pending {
+			fmt.Fprintf(os.Stdout, "pending: %06d_%s\n", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// migrateOnStart применяет миграции при старте сервера. Если автоприменение
+// выключено, несовпадение версии схемы только логируется, а сервер продолжает работу.
+func migrateOnStart(ctx context.Context, log *slog.Logger, m *migrator.Migrator, apply bool) error {
+	if !apply {
+		if err := m.Check(ctx); err != nil {
+			log.Warn("database schema is not up to date, run `url-shortener migrate up`", slogger.Err(err))
+		}
+		return nil
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	if applied > 0 {
+		log.Info("migrations applied", slog.Int("applied", applied), slog.Uint64("version", uint64(m.Latest())))
+	}
+	return nil
+}
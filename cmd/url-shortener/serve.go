@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/admin/export"
+	"url-shortener/internal/http-server/handlers/admin/importer"
+	"url-shortener/internal/http-server/handlers/admin/loglevel"
+	"url-shortener/internal/http-server/handlers/domains"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/url/batch"
+	"url-shortener/internal/http-server/handlers/url/batchdelete"
+	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/qr"
+	"url-shortener/internal/http-server/handlers/url/restore"
+	"url-shortener/internal/http-server/handlers/url/rules"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/split"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/utm"
+	"url-shortener/internal/http-server/middleware/logger"
+	mwmetrics "url-shortener/internal/http-server/middleware/metrics"
+	mwtracing "url-shortener/internal/http-server/middleware/tracing"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/geoip"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/migrator"
+	"url-shortener/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// newRouter создает роутер с общими middleware. URLFormat срезает расширение из пути
+// (/url/{alias}/qr.png), поэтому для /admin он не применяется: там в пути бывают хосты
+// вроде go.brand-a.com.
+func newRouter(log *slog.Logger) *chi.Mux {
+	router := chi.NewRouter()
+	router.NotFound(response.NotFound)
+	router.MethodNotAllowed(response.MethodNotAllowed)
+	router.Use(middleware.RequestID)
+	router.Use(mwtracing.New())
+	router.Use(logger.New(log))
+	router.Use(mwmetrics.New())
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.Maybe(middleware.URLFormat, func(r *http.Request) bool {
+		return !strings.HasPrefix(r.URL.Path, "/admin/")
+	}))
+	return router
+}
+
+// adminRoutes - ручки /admin за Basic Auth
+func adminRoutes(log *slog.Logger, service service.ServiceInterface, admin config.Admin) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.BasicAuth("url-shortener", map[string]string{admin.User: admin.Password}))
+		r.Post("/import", importer.New(log, service))
+		r.Get("/export", export.New(log, service))
+		r.Get("/domains", domains.List(log, service))
+		r.Post("/domains", domains.Save(log, service))
+		r.Delete("/domains/{host}", domains.Delete(log, service))
+		r.Get("/log-level", loglevel.Get(&logLevel))
+		r.Put("/log-level", loglevel.New(log, &logLevel))
+	}
+}
+
+func runServe(ctx context.Context, log *slog.Logger, cfg config.Config, _ []string) error {
+	log.Info("starting url-shortener", slog.String("env", cfg.Env), slog.String("level", logLevel.Level().String()))
+	log.Debug("debug messages are enabled")
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := migrateOnStart(ctx, log, app.migrator, cfg.Storage.MigrateOnStart); err != nil {
+		return err
+	}
+	if err := checkDefaultDomain(ctx, log, app.service, cfg.Redirect.DefaultDomain); err != nil {
+		return err
+	}
+
+	tracer := newTracer(log, cfg.Tracing)
+	tracing.SetTracer(tracer)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			log.Error("failed to flush spans", slogger.Err(err))
+		}
+	}()
+
+	storage := app.storage
+	service := app.service
+	handlers := save.NewHandlers(service, app.shortURLs)
+
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	defer stopPurge()
+	go runPurger(purgeCtx, log, service, cfg.Storage)
+
+	// Переходы дописываются после остановки HTTP-сервера и до закрытия пула соединений
+	recorder := clicks.NewRecorder(log, service, cfg.Clicks.QueueSize, cfg.Clicks.BatchSize, cfg.Clicks.FlushInterval)
+	clicksCtx, stopClicks := context.WithCancel(context.Background())
+	clicksDone := make(chan struct{})
+	go func() {
+		defer close(clicksDone)
+		recorder.Run(clicksCtx)
+	}()
+	defer func() {
+		stopClicks()
+		<-clicksDone
+	}()
+
+	checks := health.New(health.DefaultTimeout)
+	checks.Add("postgres", app.database.GetPool().Ping)
+	checks.Add("migrations", func(ctx context.Context) error {
+		// Когда новая реплика уже применила миграции, старые должны дослужить раскатку
+		err := app.migrator.Check(ctx)
+		if errors.Is(err, migrator.ErrSchemaAhead) {
+			return health.Warn(err)
+		}
+		return err
+	})
+
+	response.SetLegacyErrors(cfg.HTTPServer.LegacyErrors)
+
+	router := newRouter(log)
+	router.Get("/healthz", checks.Live())
+	router.Get("/readyz", checks.Ready(log))
+	router.Route("/url", func(r chi.Router) {
+		r.Post("/", handlers.New(log))
+		r.Get("/", list.New(log, service, app.shortURLs))
+		r.Post("/batch", batch.New(log, service, app.shortURLs, cfg.BatchMaxItems))
+		r.Post("/batch/delete", batchdelete.New(log, service, cfg.BatchMaxItems))
+		r.Get("/{alias}", get.New(log, service, app.shortURLs))
+		r.Delete("/{alias}", delete.New(log, storage))
+		r.Post("/{alias}/restore", restore.New(log, storage))
+		r.Get("/{alias}/rules", rules.Get(log, service))
+		r.Put("/{alias}/rules", rules.Replace(log, service))
+		r.Post("/{alias}/rules", rules.Add(log, service))
+		r.Delete("/{alias}/rules/{index}", rules.Delete(log, service))
+		r.Put("/{alias}/split", split.Save(log, service))
+		r.Delete("/{alias}/split", split.Delete(log, service))
+		r.Get("/{alias}/stats", stats.New(log, service))
+		r.Get("/{alias}/qr", qr.New(log, service, app.shortURLs))
+
+	})
+	router.Route("/utm-templates/{owner}", func(r chi.Router) {
+		r.Get("/", utm.List(log, service))
+		r.Put("/{name}", utm.Save(log, service))
+		r.Delete("/{name}", utm.Delete(log, service))
+	})
+	geo, err := newGeoResolver(log, cfg.Geo)
+	if err != nil {
+		return err
+	}
+	redirectHandler := redirect.New(log, storage, redirect.Options{
+		DefaultType:     cfg.Redirect.DefaultType,
+		PermanentMaxAge: cfg.Redirect.PermanentMaxAge,
+		ComingSoonURL:   cfg.Redirect.ComingSoonURL,
+		Interstitial:    cfg.Redirect.Interstitial,
+		DefaultDomain:   cfg.Redirect.DefaultDomain,
+		Geo:             geo,
+		Clicks:          recorder,
+		Password: redirect.PasswordOptions{
+			Secret:        []byte(cfg.Protected.CookieSecret),
+			CookieTTL:     cfg.Protected.CookieTTL,
+			MaxAttempts:   cfg.Protected.MaxAttempts,
+			AttemptWindow: cfg.Protected.AttemptWindow,
+		},
+	})
+	if cfg.Protected.CookieSecret == "" {
+		log.Warn("LINK_COOKIE_SECRET is not set, password cookies will reset on restart")
+	}
+	router.Get("/{alias}", redirectHandler)
+	// Форма ввода пароля для ссылок с паролем
+	router.Post("/{alias}", redirectHandler)
+	router.Post("/{alias}/*", redirectHandler)
+	// Путь после алиаса переносится в целевой URL, если у ссылки включен passthrough
+	router.Get("/{alias}/*", redirectHandler)
+
+	if cfg.Admin.Password != "" {
+		router.Route("/admin", adminRoutes(log, service, cfg.Admin))
+	} else {
+		log.Warn("admin api disabled: HTTP_SERVER_PASSWORD is not set")
+	}
+
+	log.Info("starting server", slog.String("address", cfg.Address))
+
+	srv := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		log.Info("Starting server", slog.String("address", cfg.Address))
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	metricsSrv := newMetricsServer(log, cfg.Metrics, app)
+	if metricsSrv != nil {
+		go func() {
+			err := metricsSrv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("metrics server: %w", err)
+			}
+		}()
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go reloadLogLevel(log, reload)
+
+	log.Info("server started")
+
+	select {
+	case err := <-serverErr:
+		return err
+	case sig := <-done:
+		log.Info("Shutting down...", slog.String("signal", sig.String()))
+		checks.Drain()
+		time.Sleep(cfg.HTTPServer.ShutdownDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error("server shutdown failed", slogger.Err(err))
+		}
+		if metricsSrv != nil {
+			if err := metricsSrv.Shutdown(ctx); err != nil {
+				log.Error("metrics server shutdown failed", slogger.Err(err))
+			}
+		}
+		log.Info("Server gracefully stopped", slog.String("address", cfg.Address))
+		return nil
+	}
+}
+
+// checkDefaultDomain не дает запустить сервер без redirect.default_domain, если домены брендов
+// уже зарегистрированы: без него редирект не смотрит на Host и их ссылки недоступны
+func checkDefaultDomain(ctx context.Context, log *slog.Logger, service *service.Service, defaultDomain string) error {
+	if defaultDomain != "" {
+		return nil
+	}
+	domains, err := service.ListDomains(ctx)
+	if err != nil {
+		// Таблицы domains может не быть до миграций, их проверяет /readyz
+		log.Warn("failed to check brand domains", slogger.Err(err))
+		return nil
+	}
+	if len(domains) > 0 {
+		return fmt.Errorf("redirect.default_domain must be set: %d brand domains are registered", len(domains))
+	}
+	return nil
+}
+
+func newTracer(log *slog.Logger, cfg config.Tracing) *tracing.Tracer {
+	switch cfg.Exporter {
+	case config.TracingStdout:
+		log.Info("tracing to stdout")
+		return tracing.NewTracer(tracing.NewStdoutExporter(os.Stdout))
+	case config.TracingOTLP:
+		log.Info("tracing to otlp collector", slog.String("endpoint", cfg.Endpoint))
+		return tracing.NewTracer(tracing.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName, func(err error) {
+			log.Warn("failed to export spans", slogger.Err(err))
+		}))
+	}
+	return tracing.NewTracer(nil)
+}
+
+// newGeoResolver открывает базу стран, если она задана. Без базы и заголовка возвращает nil.
+func newGeoResolver(log *slog.Logger, cfg config.Geo) (*geoip.Resolver, error) {
+	if cfg.Database == "" && cfg.CountryHeader == "" {
+		log.Info("geo redirect rules disabled: geo.database and geo.country_header are not set")
+		return nil, nil
+	}
+	resolver := &geoip.Resolver{CountryHeader: cfg.CountryHeader, IPHeader: cfg.IPHeader}
+	if cfg.Database != "" {
+		db, err := geoip.Open(cfg.Database)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open geo database: %w", err)
+		}
+		resolver.DB = db
+		log.Info("geo database loaded", slog.String("path", cfg.Database))
+	}
+	return resolver, nil
+}
+
+// reloadLogLevel перечитывает log_level из конфига по SIGHUP
+func reloadLogLevel(log *slog.Logger, signals <-chan os.Signal) {
+	for range signals {
+		level, err := config.ReadLogLevel(configPath)
+		if err != nil {
+			log.Error("failed to reload log level", slogger.Err(err))
+			continue
+		}
+		old := logLevel.Level()
+		logLevel.Set(level)
+		log.Warn("log level reloaded", slog.String("from", old.String()), slog.String("to", level.String()))
+	}
+}
+
+// newMetricsServer поднимает /metrics на отдельном адресе. Возвращает nil, если адрес не задан.
+func newMetricsServer(log *slog.Logger, cfg config.Metrics, app *app) *http.Server {
+	if cfg.Address == "" {
+		log.Info("metrics disabled: metrics.address is not set")
+		return nil
+	}
+	metrics.RegisterPool(app.database.GetPool())
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Registry.Handler())
+
+	log.Info("starting metrics server", slog.String("address", cfg.Address))
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// runPurger периодически удаляет ссылки, срок хранения которых в корзине истек
+func runPurger(ctx context.Context, log *slog.Logger, service *service.Service, cfg config.Storage) {
+	log = log.With(slog.String("component", "purger"))
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := service.PurgeDeletedURLs(ctx, cfg.DeletedRetention)
+		if err != nil {
+			log.Error("failed to purge deleted urls", slogger.Err(err))
+		} else if purged > 0 {
+			log.Info("deleted urls purged", slog.Int64("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
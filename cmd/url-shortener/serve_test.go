@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestAdminDeleteDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		host string
+	}{
+		{name: "Host with TLD", path: "/admin/domains/go.brand-a.com", host: "go.brand-a.com"},
+		{name: "Host with file-like TLD", path: "/admin/domains/brand.json", host: "brand.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slogdiscard.NewDiscardLogger()
+			serviceMock := mocks.NewServiceInterface(t)
+			serviceMock.On("DeleteDomain", mock.Anything, tt.host).Return(nil)
+
+			router := newRouter(log)
+			router.Route("/admin", adminRoutes(log, serviceMock, config.Admin{User: "admin", Password: "secret"}))
+
+			req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+			req.SetBasicAuth("admin", "secret")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+func TestRouterURLFormat(t *testing.T) {
+	router := newRouter(slogdiscard.NewDiscardLogger())
+	var alias, format string
+	router.Get("/url/{alias}/qr", func(w http.ResponseWriter, r *http.Request) {
+		alias = chi.URLParam(r, "alias")
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/docs/qr.png", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "docs", alias)
+	require.Equal(t, "png", format)
+}
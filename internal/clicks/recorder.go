@@ -0,0 +1,101 @@
+// Package clicks копит переходы по ссылкам в памяти и пишет их в БД пачками,
+// чтобы редирект не ждал записи аналитики
+package clicks
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage/postgres"
+)
+
+// Store - куда пишутся пачки переходов
+type Store interface {
+	RecordClicks(ctx context.Context, clicks []postgres.Click) error
+}
+
+// shutdownTimeout ограничивает запись последней пачки при остановке
+const shutdownTimeout = 5 * time.Second
+
+// Recorder принимает переходы без блокировки. Если очередь заполнена, переход
+// отбрасывается и учитывается в url_shortener_clicks_dropped_total.
+type Recorder struct {
+	log           *slog.Logger
+	store         Store
+	queue         chan postgres.Click
+	batchSize     int
+	flushInterval time.Duration
+}
+
+func NewRecorder(log *slog.Logger, store Store, queueSize, batchSize int, flushInterval time.Duration) *Recorder {
+	return &Recorder{
+		log:           log.With(slog.String("component", "clicks")),
+		store:         store,
+		queue:         make(chan postgres.Click, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+func (r *Recorder) Record(click postgres.Click) {
+	select {
+	case r.queue <- click:
+	default:
+		metrics.ClicksDropped.Inc()
+	}
+}
+
+// Run пишет переходы, пока не отменен ctx. Перед выходом записывает то, что осталось в очереди.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]postgres.Click, 0, r.batchSize)
+	for {
+		select {
+		case click := <-r.queue:
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(ctx, batch)
+		case <-ctx.Done():
+			r.drain(batch)
+			return
+		}
+	}
+}
+
+// drain записывает остаток очереди с отдельным таймаутом: контекст Run уже отменен
+func (r *Recorder) drain(batch []postgres.Click) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for {
+		select {
+		case click := <-r.queue:
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(ctx, batch)
+			}
+		default:
+			r.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+func (r *Recorder) flush(ctx context.Context, batch []postgres.Click) []postgres.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := r.store.RecordClicks(ctx, batch); err != nil {
+		r.log.Error("failed to record clicks", slogger.Err(err), slog.Int("count", len(batch)))
+		metrics.ClicksDropped.Add(float64(len(batch)))
+	} else {
+		metrics.ClicksRecorded.Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
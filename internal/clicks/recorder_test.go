@@ -0,0 +1,86 @@
+package clicks_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/postgres"
+)
+
+type store struct {
+	mu      sync.Mutex
+	batches [][]postgres.Click
+	err     error
+}
+
+func (s *store) RecordClicks(_ context.Context, batch []postgres.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]postgres.Click(nil), batch...))
+	return s.err
+}
+
+func (s *store) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sizes := make([]int, 0, len(s.batches))
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestRecorderFlushesBySize(t *testing.T) {
+	s := &store{}
+	r := clicks.NewRecorder(slogdiscard.NewDiscardLogger(), s, 10, 2, time.Hour)
+	for i := range 5 {
+		r.Record(postgres.Click{LinkID: int64(i)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return len(s.sizes()) == 2 }, time.Second, 5*time.Millisecond)
+
+	// Остаток записывается при остановке
+	cancel()
+	<-done
+	require.Equal(t, []int{2, 2, 1}, s.sizes())
+}
+
+func TestRecorderFlushesByInterval(t *testing.T) {
+	s := &store{}
+	r := clicks.NewRecorder(slogdiscard.NewDiscardLogger(), s, 10, 100, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	r.Record(postgres.Click{LinkID: 1, Variant: "a"})
+	require.Eventually(t, func() bool { return len(s.sizes()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestRecorderDropsWhenFull(t *testing.T) {
+	s := &store{err: errors.New("db error")}
+	r := clicks.NewRecorder(slogdiscard.NewDiscardLogger(), s, 1, 10, time.Hour)
+
+	// Run не запущен: второй переход не помещается в очередь и не блокирует вызывающего
+	r.Record(postgres.Click{LinkID: 1})
+	r.Record(postgres.Click{LinkID: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.Run(ctx)
+	require.Equal(t, []int{1}, s.sizes())
+}
@@ -3,8 +3,14 @@ package config
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
+	"url-shortener/internal/lib/domain"
+	"url-shortener/internal/lib/shorturl"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v2"
@@ -12,9 +18,85 @@ import (
 
 type Config struct {
 	DB_config_path string
-	HTTPServer     `yaml:"http_server"`
+	// Env - local, dev или prod, см. logging.go
+	Env        string     `yaml:"env" env-default:"local"`
+	LogLevel   string     `yaml:"log_level"`
+	Level      slog.Level `yaml:"-"`
+	HTTPServer `yaml:"http_server"`
+	Storage    Storage   `yaml:"storage"`
+	Redirect   Redirect  `yaml:"redirect"`
+	Geo        Geo       `yaml:"geo"`
+	Clicks     Clicks    `yaml:"clicks"`
+	Protected  Protected `yaml:"protected_links"`
+	Metrics    Metrics   `yaml:"metrics"`
+	Tracing    Tracing   `yaml:"tracing"`
+	// PublicBaseURL - публичный адрес сервиса для short_url в ответах, например https://sho.rt.
+	// Пустой допустим только в env: local - тогда адрес строится по хосту запроса.
+	// За прокси хост и схема запроса не совпадают с публичными, а Host задает клиент.
+	PublicBaseURL string `yaml:"public_base_url"`
+	Admin         Admin  `yaml:"-"`
 }
 
+// Redirect - статус редиректа для ссылок без своего redirect_type и время,
+// на которое браузеры и CDN могут закешировать постоянный редирект (301, 308).
+// ComingSoonURL - куда вести по ссылке до ее active_from, пустой - отвечать 404.
+// Interstitial показывает страницу с адресом назначения вместо редиректа для всех ссылок.
+// DefaultDomain - хост основного домена. Пока он пуст, заголовок Host не учитывается
+// и все запросы идут в основной домен; с ним остальные хосты ищутся в таблице domains.
+// Без него домены брендов и ссылки на них не создаются, а сервер не стартует,
+// если домены уже зарегистрированы.
+type Redirect struct {
+	DefaultType     int           `yaml:"default_type" env-default:"302"`
+	PermanentMaxAge time.Duration `yaml:"permanent_max_age" env-default:"24h"`
+	ComingSoonURL   string        `yaml:"coming_soon_url"`
+	Interstitial    bool          `yaml:"interstitial"`
+	DefaultDomain   string        `yaml:"default_domain"`
+}
+
+// Geo - откуда правила редиректа берут страну клиента. CountryHeader - доверенный заголовок
+// с ISO-кодом страны от CDN или балансировщика, Database - файл базы в формате MaxMind DB
+// для поиска по IP. IPHeader - доверенный заголовок с IP клиента, если сервер стоит за прокси.
+// Без заголовка и базы правила со странами не срабатывают.
+type Geo struct {
+	Database      string `yaml:"database"`
+	CountryHeader string `yaml:"country_header"`
+	IPHeader      string `yaml:"ip_header"`
+}
+
+// Clicks - очередь записи переходов для аналитики. Переходы пишутся пачками по BatchSize
+// или раз в FlushInterval. Если очередь из QueueSize переходов заполнена, новые отбрасываются.
+type Clicks struct {
+	QueueSize     int           `yaml:"queue_size" env-default:"10000"`
+	BatchSize     int           `yaml:"batch_size" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+}
+
+// Protected - ссылки с паролем. После ввода пароля клиент получает подписанную cookie
+// на CookieTTL. После MaxAttempts неверных паролей за AttemptWindow ссылка перестает
+// принимать пароль до конца окна. CookieSecret берется из LINK_COOKIE_SECRET в .env;
+// без него ключ создается при запуске, и cookie сбрасываются при перезапуске.
+type Protected struct {
+	CookieTTL     time.Duration `yaml:"cookie_ttl" env-default:"1h"`
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	AttemptWindow time.Duration `yaml:"attempt_window" env-default:"15m"`
+	CookieSecret  string        `yaml:"-"`
+}
+
+// Metrics - отдельный listener для /metrics, чтобы не публиковать метрики на публичном адресе.
+// Пустой адрес отключает метрики.
+type Metrics struct {
+	Address string `yaml:"address"`
+}
+
+// Admin - учетные данные Basic Auth для /admin. Берутся из .env;
+// если пароль не задан, административные маршруты не подключаются.
+type Admin struct {
+	User     string
+	Password string
+}
+
+const defaultAdminUser = "admin"
+
 func SetConfig() (string, error) {
 	err := godotenv.Load(".env")
 	if err != nil {
@@ -29,8 +111,66 @@ type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"0.0.0.0:8082"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// BatchMaxItems - максимальное число ссылок в одном запросе POST /url/batch
+	BatchMaxItems int `yaml:"batch_max_items" env-default:"1000"`
+	// ShutdownDelay - сколько /readyz отвечает отказом перед остановкой сервера,
+	// чтобы балансировщик успел снять трафик
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env-default:"0s"`
+	// LegacyErrors возвращает ошибки в старом формате {"status":"Error","error":"..."}
+	// вместо application/problem+json
+	LegacyErrors bool `yaml:"legacy_errors"`
+}
+
+// Tracing - куда отправлять спаны: none, stdout или otlp (OTLP/HTTP на Endpoint)
+type Tracing struct {
+	Exporter    string `yaml:"exporter" env-default:"none"`
+	Endpoint    string `yaml:"endpoint" env-default:"http://localhost:4318"`
+	ServiceName string `yaml:"service_name" env-default:"url-shortener"`
+}
+
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+type Storage struct {
+	// DeletedRetention - сколько удаленная ссылка хранится до окончательной очистки
+	DeletedRetention time.Duration `yaml:"deleted_retention" env-default:"720h"`
+	PurgeInterval    time.Duration `yaml:"purge_interval" env-default:"1h"`
+	// MigrateOnStart - применять встроенные миграции при запуске сервера
+	MigrateOnStart bool          `yaml:"migrate_on_start"`
+	QueryTimeouts  QueryTimeouts `yaml:"query_timeouts"`
+}
+
+// QueryTimeouts - предельное время одного обращения к БД: чтение, запись одной ссылки
+// и пакетные операции (batch, массовое удаление, очистка)
+type QueryTimeouts struct {
+	Read  time.Duration `yaml:"read" env-default:"2s"`
+	Write time.Duration `yaml:"write" env-default:"3s"`
+	Batch time.Duration `yaml:"batch" env-default:"30s"`
 }
 
+const (
+	defaultBatchMaxItems    = 1000
+	defaultDeletedRetention = 720 * time.Hour
+	defaultPurgeInterval    = time.Hour
+	defaultReadTimeout      = 2 * time.Second
+	defaultWriteTimeout     = 3 * time.Second
+	defaultBatchTimeout     = 30 * time.Second
+	defaultRedirectType     = http.StatusFound
+	defaultPermanentMaxAge  = 24 * time.Hour
+	defaultClicksQueueSize  = 10000
+	defaultClicksBatchSize  = 500
+	defaultClicksFlush      = time.Second
+	defaultPasswordTTL      = time.Hour
+	defaultMaxAttempts      = 5
+	defaultAttemptWindow    = 15 * time.Minute
+	minCookieSecretLength   = 32
+	defaultTracingEndpoint  = "http://localhost:4318"
+	defaultServiceName      = "url-shortener"
+)
+
 func MustLoadConfig(server_configPath string) (Config, error) {
 	db_configPath, err := SetConfig()
 	if err != nil {
@@ -53,12 +193,154 @@ func MustLoadConfig(server_configPath string) (Config, error) {
 		return Config{}, fmt.Errorf("не удалось распарсить YAML: %w", err)
 	}
 
+	if port.BatchMaxItems <= 0 {
+		port.BatchMaxItems = defaultBatchMaxItems
+	}
+	if port.Storage.DeletedRetention <= 0 {
+		port.Storage.DeletedRetention = defaultDeletedRetention
+	}
+	if port.Storage.PurgeInterval <= 0 {
+		port.Storage.PurgeInterval = defaultPurgeInterval
+	}
+	port.Storage.QueryTimeouts.setDefaults()
+	port.Clicks.setDefaults()
+	port.Protected.CookieSecret = os.Getenv("LINK_COOKIE_SECRET")
+	if err := port.Protected.normalize(); err != nil {
+		return Config{}, err
+	}
+	if err := port.Redirect.normalize(); err != nil {
+		return Config{}, err
+	}
+	if err := port.Tracing.normalize(); err != nil {
+		return Config{}, err
+	}
+	port.PublicBaseURL = strings.TrimSpace(port.PublicBaseURL)
+	if _, err := shorturl.New(port.PublicBaseURL); err != nil {
+		return Config{}, err
+	}
+	logs := logging{Env: port.Env, LogLevel: port.LogLevel}
+	level, err := logs.resolve()
+	if err != nil {
+		return Config{}, err
+	}
+	if port.PublicBaseURL == "" && logs.Env != EnvLocal {
+		return Config{}, fmt.Errorf("public_base_url is required outside env %q", EnvLocal)
+	}
+
 	return Config{
 		DB_config_path: db_configPath,
+		Env:            logs.Env,
+		LogLevel:       port.LogLevel,
+		Level:          level,
 		HTTPServer: HTTPServer{
-			Address:     port.Address,
-			Timeout:     port.Timeout,
-			IdleTimeout: port.IdleTimeout,
+			Address:       port.Address,
+			Timeout:       port.Timeout,
+			IdleTimeout:   port.IdleTimeout,
+			BatchMaxItems: port.BatchMaxItems,
+			ShutdownDelay: max(port.ShutdownDelay, 0),
+			LegacyErrors:  port.LegacyErrors,
 		},
+		Storage:       port.Storage,
+		Redirect:      port.Redirect,
+		Geo:           port.Geo,
+		Clicks:        port.Clicks,
+		Protected:     port.Protected,
+		Metrics:       port.Metrics,
+		Tracing:       port.Tracing,
+		PublicBaseURL: port.PublicBaseURL,
+		Admin:         loadAdmin(),
 	}, nil
 }
+
+func (t *QueryTimeouts) setDefaults() {
+	if t.Read <= 0 {
+		t.Read = defaultReadTimeout
+	}
+	if t.Write <= 0 {
+		t.Write = defaultWriteTimeout
+	}
+	if t.Batch <= 0 {
+		t.Batch = defaultBatchTimeout
+	}
+}
+
+func (c *Clicks) setDefaults() {
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultClicksQueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultClicksBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultClicksFlush
+	}
+}
+
+func (p *Protected) normalize() error {
+	if p.CookieTTL <= 0 {
+		p.CookieTTL = defaultPasswordTTL
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.AttemptWindow <= 0 {
+		p.AttemptWindow = defaultAttemptWindow
+	}
+	if p.CookieSecret != "" && len(p.CookieSecret) < minCookieSecretLength {
+		return fmt.Errorf("LINK_COOKIE_SECRET must be at least %d bytes", minCookieSecretLength)
+	}
+	return nil
+}
+
+func (r *Redirect) normalize() error {
+	if r.DefaultType == 0 {
+		r.DefaultType = defaultRedirectType
+	}
+	if r.PermanentMaxAge <= 0 {
+		r.PermanentMaxAge = defaultPermanentMaxAge
+	}
+	switch r.DefaultType {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("unsupported redirect.default_type %d, expected 301, 302, 307 or 308", r.DefaultType)
+	}
+	if r.ComingSoonURL != "" {
+		u, err := url.Parse(r.ComingSoonURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("redirect.coming_soon_url must be an absolute http(s) url, got %q", r.ComingSoonURL)
+		}
+	}
+	r.DefaultDomain = domain.Normalize(r.DefaultDomain)
+	if strings.ContainsAny(r.DefaultDomain, "/:@ ") {
+		return fmt.Errorf("redirect.default_domain must be a host without scheme and port, got %q", r.DefaultDomain)
+	}
+	return nil
+}
+
+func (t *Tracing) normalize() error {
+	if t.Exporter == "" {
+		t.Exporter = TracingNone
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = defaultTracingEndpoint
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = defaultServiceName
+	}
+	switch t.Exporter {
+	case TracingNone, TracingStdout, TracingOTLP:
+		return nil
+	}
+	return fmt.Errorf("unknown tracing.exporter %q, expected none, stdout or otlp", t.Exporter)
+}
+
+func loadAdmin() Admin {
+	admin := Admin{
+		User:     os.Getenv("HTTP_SERVER_USER"),
+		Password: os.Getenv("HTTP_SERVER_PASSWORD"),
+	}
+	if admin.User == "" {
+		admin.User = defaultAdminUser
+	}
+	return admin
+}
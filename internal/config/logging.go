@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Окружения определяют формат логов и уровень по умолчанию:
+// local - цветной slogpretty и debug, dev - текст и debug, prod - JSON и info.
+const (
+	EnvLocal = "local"
+	EnvDev   = "dev"
+	EnvProd  = "prod"
+)
+
+type logging struct {
+	Env      string `yaml:"env"`
+	LogLevel string `yaml:"log_level"`
+}
+
+// resolve проверяет env и вычисляет уровень: log_level, если задан, иначе уровень окружения
+func (l *logging) resolve() (slog.Level, error) {
+	if l.Env == "" {
+		l.Env = EnvLocal
+	}
+
+	level := slog.LevelDebug
+	switch l.Env {
+	case EnvLocal, EnvDev:
+	case EnvProd:
+		level = slog.LevelInfo
+	default:
+		return 0, fmt.Errorf("unknown env %q, expected local, dev or prod", l.Env)
+	}
+
+	if l.LogLevel != "" {
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(l.LogLevel))); err != nil {
+			return 0, fmt.Errorf("invalid log_level %q: %w", l.LogLevel, err)
+		}
+	}
+	return level, nil
+}
+
+// ReadLogLevel перечитывает из файла только уровень логирования. Используется по SIGHUP,
+// поэтому, в отличие от MustLoadConfig, не завершает процесс при ошибке.
+func ReadLogLevel(configPath string) (slog.Level, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось прочитать файл %s: %w", configPath, err)
+	}
+	var l logging
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return 0, fmt.Errorf("не удалось распарсить YAML: %w", err)
+	}
+	return l.resolve()
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var contentTypes = map[transfer.Format]string{
+	transfer.FormatCSV:   "text/csv; charset=utf-8",
+	transfer.FormatJSONL: "application/x-ndjson",
+}
+
+// New отдает выгрузку ссылок потоком. Параметры запроса:
+// format (csv или jsonl, по умолчанию jsonl), owner, from и to (RFC 3339 или 2006-01-02).
+func New(log *slog.Logger, exporter transfer.URLExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.admin.export.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		query := r.URL.Query()
+		formatName := query.Get("format")
+		if formatName == "" {
+			formatName = string(transfer.FormatJSONL)
+		}
+		format, err := transfer.ParseFormat(formatName)
+		if err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		filter := postgres.Filter{Owner: query.Get("owner")}
+		if from := query.Get("from"); from != "" {
+			if filter.CreatedAfter, err = transfer.ParseTime(from); err != nil {
+				resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "invalid from")
+				return
+			}
+		}
+		if to := query.Get("to"); to != "" {
+			if filter.CreatedBefore, err = transfer.ParseTime(to); err != nil {
+				resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "invalid to")
+				return
+			}
+		}
+
+		// Выгрузка может идти дольше таймаута записи сервера
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", contentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="urls.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+
+		count, err := transfer.Export(r.Context(), exporter, w, format, filter, rc)
+		if err != nil {
+			// Заголовки уже отправлены, остается только оборвать поток
+			log.Error("export interrupted", slogger.Err(err), slog.Int("exported", count))
+			panic(http.ErrAbortHandler)
+		}
+
+		log.Info("urls exported", slog.Int("count", count), slog.String("format", string(format)))
+	}
+}
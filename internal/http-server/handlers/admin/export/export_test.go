@@ -0,0 +1,101 @@
+package export_test
+
+import (
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/export"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/postgres"
+)
+
+func links(links ...postgres.Link) iter.Seq2[postgres.ExportedLink, error] {
+	return func(yield func(postgres.ExportedLink, error) bool) {
+		for _, link := range links {
+			if !yield(postgres.ExportedLink{Link: link}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestExportHandler(t *testing.T) {
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                string
+		query               string
+		mockBehavior        func(s *mocks.ServiceInterface)
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:  "CSV with filters",
+			query: "?format=csv&owner=marketing&from=2025-01-01&to=2025-06-01T00:00:00Z",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ExportURLs", mock.Anything, postgres.Filter{
+					Owner:         "marketing",
+					CreatedAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					CreatedBefore: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				}).Return(links(postgres.Link{ID: 1, Alias: "a", URL: "https://a.com", Owner: "marketing", CreatedAt: created}))
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "id,alias,url,owner,tags,created_at,deleted_at,domain,password_hash,max_clicks,clicks_used,active_from,expires_at," +
+				"redirect_type,passthrough,utm,rules,split,title,interstitial,clicks,variant_clicks\n" +
+				"1,a,https://a.com,marketing,,2025-03-01T00:00:00Z,,,,,,,,,,,,,,,0,\n",
+		},
+		{
+			name:  "JSONL by default",
+			query: "",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ExportURLs", mock.Anything, postgres.Filter{}).
+					Return(links(postgres.Link{ID: 1, Alias: "a", URL: "https://a.com", CreatedAt: created}))
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        `{"id":1,"alias":"a","url":"https://a.com","created_at":"2025-03-01T00:00:00Z","clicks":0}` + "\n",
+		},
+		{
+			name:         "Unknown format",
+			query:        "?format=xml",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid date",
+			query:        "?from=yesterday",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			handler := export.New(slogdiscard.NewDiscardLogger(), serviceMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/export"+tt.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedCode == http.StatusOK {
+				require.Equal(t, tt.expectedContentType, rr.Header().Get("Content-Type"))
+				require.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// uploadField - имя поля с файлом в multipart/form-data
+const uploadField = "file"
+
+type Response struct {
+	resp.Response
+	transfer.Report
+}
+
+// New принимает файл импорта телом запроса или полем file в multipart/form-data.
+// Формат задается параметром format или расширением загруженного файла,
+// политика конфликтов - параметром on_conflict (skip, overwrite, fail).
+func New(log *slog.Logger, importer transfer.URLImporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.admin.importer.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		policy := postgres.ConflictPolicy(r.URL.Query().Get("on_conflict"))
+		if policy == "" {
+			policy = postgres.ConflictSkip
+		}
+		if !policy.Valid() {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "on_conflict must be skip, overwrite or fail")
+			return
+		}
+
+		body, filename, err := uploadBody(r)
+		if err != nil {
+			log.Error("failed to read upload", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to read upload")
+			return
+		}
+
+		formatName := r.URL.Query().Get("format")
+		if formatName == "" {
+			formatName = path.Ext(filename)
+			if formatName != "" {
+				formatName = formatName[1:]
+			}
+		}
+		format, err := transfer.ParseFormat(formatName)
+		if err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		// Импорт большого файла может длиться дольше таймаутов сервера
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		report, err := transfer.Import(r.Context(), importer, body, format, policy)
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Info("import aborted on conflict", slog.Int("conflicts", len(report.Conflicts)))
+			resp.WriteErrorExt(w, r, http.StatusConflict, resp.CodeImportConflict, "import aborted on conflict, nothing was imported", reportFields(report))
+			return
+		}
+		if errors.Is(err, links.ErrDomainsDisabled) {
+			log.Info("import aborted on brand domain")
+			resp.WriteErrorExt(w, r, http.StatusBadRequest, resp.CodeValidationFailed, links.ErrDomainsDisabled.Error()+", nothing was imported", reportFields(report))
+			return
+		}
+		if err != nil {
+			log.Error("failed to import urls", slogger.Err(err))
+			resp.WriteErrorExt(w, r, http.StatusInternalServerError, resp.CodeInternal, "import failed, nothing was imported", reportFields(report))
+			return
+		}
+
+		log.Info("urls imported",
+			slog.Int("imported", report.Imported),
+			slog.Int("overwritten", report.Overwritten),
+			slog.Int("skipped", report.Skipped),
+			slog.Int("rejected", len(report.Rejected)),
+		)
+
+		render.JSON(w, r, Response{Response: resp.OK(), Report: report})
+	}
+}
+
+// uploadBody возвращает поток с содержимым файла без буферизации всей загрузки в памяти
+func uploadBody(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, "", nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", fmt.Errorf("field %q not found", uploadField)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == uploadField {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+// reportFields раскладывает отчет в поля ответа с ошибкой
+func reportFields(report transfer.Report) map[string]any {
+	fields := map[string]any{
+		"imported":    report.Imported,
+		"overwritten": report.Overwritten,
+		"skipped":     report.Skipped,
+	}
+	if len(report.Conflicts) > 0 {
+		fields["conflicts"] = report.Conflicts
+	}
+	if len(report.Rejected) > 0 {
+		fields["rejected"] = report.Rejected
+	}
+	return fields
+}
@@ -0,0 +1,162 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/importer"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/postgres"
+)
+
+// importAll имитирует хранилище: все строки создаются, кроме алиаса "taken"
+func importAll(_ context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error) {
+	var stats postgres.ImportStats
+	for row, err := range rows {
+		if err != nil {
+			return stats, err
+		}
+		if row.Link.Alias == "taken" {
+			stats.Conflicts = append(stats.Conflicts, row)
+			if policy == postgres.ConflictFail {
+				return stats, postgres.ErrURLExists
+			}
+			stats.Skipped++
+			continue
+		}
+		stats.Created++
+	}
+	return stats, nil
+}
+
+func multipartBody(t *testing.T, filename, content string) (*bytes.Buffer, string) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+	return buf, mw.FormDataContentType()
+}
+
+func TestImportHandler(t *testing.T) {
+	const csvBody = "alias,url\na,https://a.com\ntaken,https://t.com\nb,bad\n"
+	const jsonlBody = `{"alias": "a", "url": "https://a.com"}` + "\n"
+
+	tests := []struct {
+		name             string
+		query            string
+		body             func(t *testing.T) (*bytes.Buffer, string)
+		expectImport     postgres.ConflictPolicy
+		expectedCode     int
+		expectedErr      string
+		expectedImported int
+		expectedRejected int
+		expectConflict   bool
+	}{
+		{
+			name:  "Raw CSV body",
+			query: "?format=csv",
+			body: func(t *testing.T) (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvBody), "text/csv"
+			},
+			expectImport:     postgres.ConflictSkip,
+			expectedCode:     http.StatusOK,
+			expectedImported: 1,
+			expectedRejected: 1,
+		},
+		{
+			name:  "Multipart JSONL with format from filename",
+			query: "?on_conflict=overwrite",
+			body: func(t *testing.T) (*bytes.Buffer, string) {
+				return multipartBody(t, "links.jsonl", jsonlBody)
+			},
+			expectImport:     postgres.ConflictOverwrite,
+			expectedCode:     http.StatusOK,
+			expectedImported: 1,
+		},
+		{
+			name:  "Fail on conflict",
+			query: "?format=csv&on_conflict=fail",
+			body: func(t *testing.T) (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvBody), "text/csv"
+			},
+			expectImport:   postgres.ConflictFail,
+			expectedCode:   http.StatusConflict,
+			expectedErr:    response.CodeImportConflict,
+			expectConflict: true,
+		},
+		{
+			name:  "Unknown conflict policy",
+			query: "?format=csv&on_conflict=merge",
+			body: func(t *testing.T) (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvBody), "text/csv"
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+		{
+			name: "Unknown format",
+			body: func(t *testing.T) (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvBody), "text/csv"
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			if tt.expectImport != "" {
+				serviceMock.On("ImportURLs", mock.Anything, mock.Anything, tt.expectImport).Return(importAll)
+			}
+
+			handler := importer.New(slogdiscard.NewDiscardLogger(), serviceMock)
+
+			body, contentType := tt.body(t)
+			req, err := http.NewRequest(http.MethodPost, "/admin/import"+tt.query, body)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedErr != "" {
+				// Отчет об импорте приходит полями problem+json
+				var problem struct {
+					response.Problem
+					Imported  int               `json:"imported"`
+					Conflicts []json.RawMessage `json:"conflicts"`
+				}
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				require.Equal(t, tt.expectedImported, problem.Imported)
+				require.Equal(t, tt.expectConflict, len(problem.Conflicts) > 0)
+				return
+			}
+
+			var resp importer.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "OK", resp.Status)
+			require.Equal(t, tt.expectedImported, resp.Imported)
+			require.Len(t, resp.Rejected, tt.expectedRejected)
+		})
+	}
+}
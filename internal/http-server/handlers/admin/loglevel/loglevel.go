@@ -0,0 +1,55 @@
+package loglevel
+
+import (
+	"log/slog"
+	"net/http"
+	resp "url-shortener/internal/lib/api/response"
+	slogger "url-shortener/internal/lib/logger/slog"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Level string `json:"level"`
+}
+
+type Response struct {
+	resp.Response
+	Level string `json:"level,omitempty"`
+}
+
+// Get отдает текущий уровень логирования
+func Get(level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Response: resp.OK(), Level: level.Level().String()})
+	}
+}
+
+// New меняет уровень логирования без перезапуска. Уровень из конфига вернется после SIGHUP или рестарта.
+func New(log *slog.Logger, level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.admin.loglevel.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Info("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+
+		var newLevel slog.Level
+		if err := newLevel.UnmarshalText([]byte(req.Level)); err != nil {
+			log.Info("invalid log level", slog.String("level", req.Level))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "invalid level, expected debug, info, warn or error")
+			return
+		}
+
+		old := level.Level()
+		level.Set(newLevel)
+		log.Warn("log level changed", slog.String("from", old.String()), slog.String("to", newLevel.String()))
+
+		render.JSON(w, r, Response{Response: resp.OK(), Level: newLevel.String()})
+	}
+}
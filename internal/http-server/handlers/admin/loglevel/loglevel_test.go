@@ -0,0 +1,89 @@
+package loglevel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/loglevel"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestSetLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCode  int
+		expectedLevel slog.Level
+		expectedError string
+	}{
+		{
+			name:          "Debug",
+			body:          `{"level": "debug"}`,
+			expectedCode:  http.StatusOK,
+			expectedLevel: slog.LevelDebug,
+		},
+		{
+			name:          "Upper case with offset",
+			body:          `{"level": "WARN+2"}`,
+			expectedCode:  http.StatusOK,
+			expectedLevel: slog.LevelWarn + 2,
+		},
+		{
+			name:          "Unknown level",
+			body:          `{"level": "verbose"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedLevel: slog.LevelInfo,
+			expectedError: "invalid level, expected debug, info, warn or error",
+		},
+		{
+			name:          "Invalid JSON",
+			body:          `{"level":`,
+			expectedCode:  http.StatusBadRequest,
+			expectedLevel: slog.LevelInfo,
+			expectedError: "failed to decode request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := &slog.LevelVar{}
+			handler := loglevel.New(slogdiscard.NewDiscardLogger(), level)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			require.Equal(t, tt.expectedLevel, level.Level())
+
+			if tt.expectedError != "" {
+				var problem response.Problem
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				require.Equal(t, tt.expectedError, problem.Detail)
+				return
+			}
+
+			var resp loglevel.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tt.expectedLevel.String(), resp.Level)
+		})
+	}
+}
+
+func TestGetLevel(t *testing.T) {
+	level := &slog.LevelVar{}
+	level.Set(slog.LevelError)
+
+	rr := httptest.NewRecorder()
+	loglevel.Get(level).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"status":"OK","level":"ERROR"}`, rr.Body.String())
+}
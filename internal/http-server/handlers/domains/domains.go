@@ -0,0 +1,141 @@
+package domains
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/links"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Domain - короткий домен бренда в ответах API
+type Domain struct {
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewDomain(d postgres.Domain) Domain {
+	return Domain{Host: d.Host, CreatedAt: d.CreatedAt}
+}
+
+type Request struct {
+	Host string `json:"host" validate:"required,fqdn"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Domains []Domain `json:"domains"`
+}
+
+type SaveResponse struct {
+	resp.Response
+	Domain
+}
+
+// List отдает зарегистрированные домены: GET /admin/domains
+func List(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.domains.List"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		domains, err := service.ListDomains(r.Context())
+		if err != nil {
+			log.Error("failed to list domains", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		views := make([]Domain, 0, len(domains))
+		for _, d := range domains {
+			views = append(views, NewDomain(d))
+		}
+
+		render.JSON(w, r, ListResponse{Response: resp.OK(), Domains: views})
+	}
+}
+
+// Save регистрирует домен: POST /admin/domains, тело - {"host": "go.brand.com"}.
+// Хост хранится в нижнем регистре и без порта, DNS домена должен указывать на сервис.
+func Save(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.domains.Save"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+		req.Host = domain.Normalize(req.Host)
+		if err := validator.New().Struct(req); err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "host must be a domain name without scheme and port")
+			return
+		}
+
+		saved, err := service.SaveDomain(r.Context(), req.Host)
+		if errors.Is(err, links.ErrDomainsDisabled) {
+			log.Warn("brand domain without default domain", slog.String("host", req.Host))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, links.ErrDomainsDisabled.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrDomainExists) {
+			resp.WriteError(w, r, http.StatusConflict, resp.CodeAlreadyExists, "domain already exists")
+			return
+		}
+		if err != nil {
+			log.Error("failed to save domain", slogger.Err(err), slog.String("host", req.Host))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		log.Info("domain saved", slog.String("host", saved.Host))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, SaveResponse{Response: resp.OK(), Domain: NewDomain(saved)})
+	}
+}
+
+// Delete удаляет домен без ссылок: DELETE /admin/domains/{host}
+func Delete(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.domains.Delete"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		host := domain.Normalize(chi.URLParam(r, "host"))
+		if host == "" {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty host")
+			return
+		}
+
+		err := service.DeleteDomain(r.Context(), host)
+		if errors.Is(err, storage.ErrDomainNotFound) {
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "domain not found")
+			return
+		}
+		if errors.Is(err, storage.ErrDomainInUse) {
+			resp.WriteError(w, r, http.StatusConflict, resp.CodeDomainInUse, "domain has links, delete them first")
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete domain", slogger.Err(err), slog.String("host", host))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		log.Info("domain deleted", slog.String("host", host))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
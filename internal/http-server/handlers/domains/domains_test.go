@@ -0,0 +1,175 @@
+package domains_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/domains"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func requireProblem(t *testing.T, rr *httptest.ResponseRecorder, code int, errCode string) {
+	require.Equal(t, code, rr.Code)
+	require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+	var problem response.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	require.Equal(t, errCode, problem.Code)
+}
+
+func TestSave(t *testing.T) {
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		mockBehavior func(s *mocks.ServiceInterface)
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name: "Success",
+			body: `{"host":"Go.Brand-A.com."}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveDomain", mock.Anything, "go.brand-a.com").
+					Return(postgres.Domain{Host: "go.brand-a.com", CreatedAt: created}, nil)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Invalid body",
+			body:         `{`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidBody,
+		},
+		{
+			name:         "Host with scheme",
+			body:         `{"host":"https://go.brand-a.com"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name:         "Empty host",
+			body:         `{}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name: "Already exists",
+			body: `{"host":"go.brand-a.com"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveDomain", mock.Anything, "go.brand-a.com").Return(postgres.Domain{}, storage.ErrDomainExists)
+			},
+			expectedCode: http.StatusConflict,
+			expectedErr:  response.CodeAlreadyExists,
+		},
+		{
+			name: "Default domain is not set",
+			body: `{"host":"go.brand-a.com"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveDomain", mock.Anything, "go.brand-a.com").Return(postgres.Domain{}, links.ErrDomainsDisabled)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name: "Storage error",
+			body: `{"host":"go.brand-a.com"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveDomain", mock.Anything, "go.brand-a.com").Return(postgres.Domain{}, errors.New("db down"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/domains", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			domains.Save(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, req)
+
+			if tt.expectedErr != "" {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+			require.Equal(t, tt.expectedCode, rr.Code)
+			var resp domains.SaveResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "go.brand-a.com", resp.Host)
+			require.Equal(t, created, resp.CreatedAt)
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("ListDomains", mock.Anything).
+		Return([]postgres.Domain{{Host: "go.brand-a.com"}, {Host: "go.brand-b.com"}}, nil)
+
+	rr := httptest.NewRecorder()
+	domains.List(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/domains", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp domains.ListResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Domains, 2)
+	require.Equal(t, "go.brand-b.com", resp.Domains[1].Host)
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		host         string
+		err          error
+		expectedCode int
+		expectedErr  string
+	}{
+		{name: "Success", host: "go.brand-a.com", expectedCode: http.StatusOK},
+		{name: "Not found", host: "go.brand-a.com", err: storage.ErrDomainNotFound, expectedCode: http.StatusNotFound, expectedErr: response.CodeNotFound},
+		{name: "In use", host: "go.brand-a.com", err: storage.ErrDomainInUse, expectedCode: http.StatusConflict, expectedErr: response.CodeDomainInUse},
+		{name: "Empty host", expectedCode: http.StatusBadRequest, expectedErr: response.CodeInvalidParameter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			if tt.host != "" {
+				serviceMock.On("DeleteDomain", mock.Anything, tt.host).Return(tt.err)
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/admin/domains/"+tt.host, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("host", tt.host)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rr := httptest.NewRecorder()
+			domains.Delete(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, req)
+
+			if tt.expectedErr != "" {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+			require.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+	// StatusWarn - проверка сообщила о проблеме, но сервер остается готовым
+	StatusWarn = "warn"
+)
+
+// DefaultTimeout - сколько ждать ответа каждой зависимости при проверке готовности
+const DefaultTimeout = 2 * time.Second
+
+// CheckFunc проверяет одну зависимость и возвращает ошибку, если она недоступна
+type CheckFunc func(ctx context.Context) error
+
+// warning - ошибка проверки, которая не снимает сервер с трафика
+type warning struct {
+	err error
+}
+
+func (w warning) Error() string { return w.err.Error() }
+
+func (w warning) Unwrap() error { return w.err }
+
+// Warn помечает ошибку проверки как предупреждение: она попадет в ответ /readyz
+// со статусом warn, но готовность не сорвет
+func Warn(err error) error {
+	if err == nil {
+		return nil
+	}
+	return warning{err: err}
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Health хранит проверки зависимостей и признак начала остановки сервера
+type Health struct {
+	timeout  time.Duration
+	checks   []check
+	draining atomic.Bool
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func New(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Health{timeout: timeout}
+}
+
+// Add регистрирует зависимость, без которой сервер не готов принимать трафик
+func (h *Health) Add(name string, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Drain переводит /readyz в состояние отказа, чтобы балансировщик снял трафик до остановки сервера
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Live отвечает на /healthz: процесс жив, пока обрабатывает запросы
+func (h *Health) Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Status: StatusOK})
+	}
+}
+
+// Ready отвечает на /readyz и проверяет все зависимости параллельно
+func (h *Health) Ready(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.Ready"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		if h.draining.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{Status: StatusDraining})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		results := make([]CheckResult, len(h.checks))
+		var wg sync.WaitGroup
+		for i, c := range h.checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = CheckResult{Status: StatusOK}
+				var warn warning
+				if err := c.fn(ctx); errors.As(err, &warn) {
+					results[i] = CheckResult{Status: StatusWarn, Error: err.Error()}
+				} else if err != nil {
+					results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+				}
+			}()
+		}
+		wg.Wait()
+
+		resp := Response{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+		for i, c := range h.checks {
+			resp.Checks[c.name] = results[i]
+			switch results[i].Status {
+			case StatusWarn:
+				log.Warn("dependency check warning", slog.String("check", c.name), slog.String("error", results[i].Error))
+			case StatusFail:
+				resp.Status = StatusFail
+				log.Warn("dependency is not ready", slog.String("check", c.name), slog.String("error", results[i].Error))
+			}
+		}
+
+		if resp.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLive(t *testing.T) {
+	h := New(time.Second)
+	h.Add("postgres", func(context.Context) error { return errors.New("down") })
+
+	rr := httptest.NewRecorder()
+	h.Live().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+}
+
+func TestReady(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	cases := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		draining   bool
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]CheckResult
+	}{
+		{
+			name:       "All ok",
+			checks:     map[string]CheckFunc{"postgres": ok, "migrations": ok},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+			wantChecks: map[string]CheckResult{"postgres": {Status: StatusOK}, "migrations": {Status: StatusOK}},
+		},
+		{
+			name:       "Dependency fails",
+			checks:     map[string]CheckFunc{"postgres": fail, "migrations": ok},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+			wantChecks: map[string]CheckResult{
+				"postgres":   {Status: StatusFail, Error: "connection refused"},
+				"migrations": {Status: StatusOK},
+			},
+		},
+		{
+			name:       "Warning keeps server ready",
+			checks:     map[string]CheckFunc{"postgres": ok, "migrations": func(context.Context) error { return Warn(errors.New("schema is newer")) }},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+			wantChecks: map[string]CheckResult{
+				"postgres":   {Status: StatusOK},
+				"migrations": {Status: StatusWarn, Error: "schema is newer"},
+			},
+		},
+		{
+			name:       "Dependency times out",
+			checks:     map[string]CheckFunc{"postgres": slow},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+			wantChecks: map[string]CheckResult{"postgres": {Status: StatusFail, Error: context.DeadlineExceeded.Error()}},
+		},
+		{
+			name:       "Draining",
+			checks:     map[string]CheckFunc{"postgres": ok},
+			draining:   true,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusDraining,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(50 * time.Millisecond)
+			for name, fn := range tc.checks {
+				h.Add(name, fn)
+			}
+			if tc.draining {
+				h.Drain()
+			}
+
+			rr := httptest.NewRecorder()
+			h.Ready(slogdiscard.NewDiscardLogger()).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.wantStatus, resp.Status)
+			require.Equal(t, tc.wantChecks, resp.Checks)
+		})
+	}
+}
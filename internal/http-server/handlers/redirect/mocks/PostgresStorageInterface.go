@@ -5,6 +5,12 @@ package mocks
 import (
 	context "context"
 
+	iter "iter"
+
+	time "time"
+
+	postgres "url-shortener/internal/storage/postgres"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,17 +19,105 @@ type PostgresStorageInterface struct {
 	mock.Mock
 }
 
-// DeleteURl provides a mock function with given fields: ctx, alias
-func (_m *PostgresStorageInterface) DeleteURl(ctx context.Context, alias string) error {
-	ret := _m.Called(ctx, alias)
+// AddRedirectRule provides a mock function with given fields: ctx, domain, alias, rule, limit
+func (_m *PostgresStorageInterface) AddRedirectRule(ctx context.Context, domain string, alias string, rule postgres.RedirectRule, limit int) ([]postgres.RedirectRule, error) {
+	ret := _m.Called(ctx, domain, alias, rule, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteURl")
+		panic("no return value specified for AddRedirectRule")
+	}
+
+	var r0 []postgres.RedirectRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, postgres.RedirectRule, int) ([]postgres.RedirectRule, error)); ok {
+		return rf(ctx, domain, alias, rule, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, postgres.RedirectRule, int) []postgres.RedirectRule); ok {
+		r0 = rf(ctx, domain, alias, rule, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.RedirectRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, postgres.RedirectRule, int) error); ok {
+		r1 = rf(ctx, domain, alias, rule, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClickStats provides a mock function with given fields: ctx, domain, alias
+func (_m *PostgresStorageInterface) ClickStats(ctx context.Context, domain string, alias string) ([]postgres.VariantClicks, error) {
+	ret := _m.Called(ctx, domain, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClickStats")
+	}
+
+	var r0 []postgres.VariantClicks
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]postgres.VariantClicks, error)); ok {
+		return rf(ctx, domain, alias)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []postgres.VariantClicks); ok {
+		r0 = rf(ctx, domain, alias)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.VariantClicks)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConsumeClick provides a mock function with given fields: ctx, id
+func (_m *PostgresStorageInterface) ConsumeClick(ctx context.Context, id int64) (int, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeClick")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (int, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) int); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteDomain provides a mock function with given fields: ctx, host
+func (_m *PostgresStorageInterface) DeleteDomain(ctx context.Context, host string) error {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDomain")
 	}
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, alias)
+		r0 = rf(ctx, host)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -31,9 +125,153 @@ func (_m *PostgresStorageInterface) DeleteURl(ctx context.Context, alias string)
 	return r0
 }
 
-// GetURL provides a mock function with given fields: ctx, alias
-func (_m *PostgresStorageInterface) GetURL(ctx context.Context, alias string) (string, error) {
-	ret := _m.Called(ctx, alias)
+// DeleteRedirectRule provides a mock function with given fields: ctx, domain, alias, index
+func (_m *PostgresStorageInterface) DeleteRedirectRule(ctx context.Context, domain string, alias string, index int) ([]postgres.RedirectRule, error) {
+	ret := _m.Called(ctx, domain, alias, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRedirectRule")
+	}
+
+	var r0 []postgres.RedirectRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) ([]postgres.RedirectRule, error)); ok {
+		return rf(ctx, domain, alias, index)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) []postgres.RedirectRule); ok {
+		r0 = rf(ctx, domain, alias, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.RedirectRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, domain, alias, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteURLs provides a mock function with given fields: ctx, filter, dryRun
+func (_m *PostgresStorageInterface) DeleteURLs(ctx context.Context, filter postgres.Filter, dryRun bool) ([]string, error) {
+	ret := _m.Called(ctx, filter, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteURLs")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Filter, bool) ([]string, error)); ok {
+		return rf(ctx, filter, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Filter, bool) []string); ok {
+		r0 = rf(ctx, filter, dryRun)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.Filter, bool) error); ok {
+		r1 = rf(ctx, filter, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteURl provides a mock function with given fields: ctx, domain, alias
+func (_m *PostgresStorageInterface) DeleteURl(ctx context.Context, domain string, alias string) error {
+	ret := _m.Called(ctx, domain, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteURl")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, domain, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUTMTemplate provides a mock function with given fields: ctx, owner, name
+func (_m *PostgresStorageInterface) DeleteUTMTemplate(ctx context.Context, owner string, name string) error {
+	ret := _m.Called(ctx, owner, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUTMTemplate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, owner, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportURLs provides a mock function with given fields: ctx, filter
+func (_m *PostgresStorageInterface) ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error] {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportURLs")
+	}
+
+	var r0 iter.Seq2[postgres.ExportedLink, error]
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Filter) iter.Seq2[postgres.ExportedLink, error]); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(iter.Seq2[postgres.ExportedLink, error])
+		}
+	}
+
+	return r0
+}
+
+// GetLink provides a mock function with given fields: ctx, domain, alias
+func (_m *PostgresStorageInterface) GetLink(ctx context.Context, domain string, alias string) (postgres.Link, error) {
+	ret := _m.Called(ctx, domain, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLink")
+	}
+
+	var r0 postgres.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (postgres.Link, error)); ok {
+		return rf(ctx, domain, alias)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) postgres.Link); ok {
+		r0 = rf(ctx, domain, alias)
+	} else {
+		r0 = ret.Get(0).(postgres.Link)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetURL provides a mock function with given fields: ctx, domain, alias
+func (_m *PostgresStorageInterface) GetURL(ctx context.Context, domain string, alias string) (string, error) {
+	ret := _m.Called(ctx, domain, alias)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetURL")
@@ -41,17 +279,255 @@ func (_m *PostgresStorageInterface) GetURL(ctx context.Context, alias string) (s
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, domain, alias)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, domain, alias)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUTMTemplate provides a mock function with given fields: ctx, owner, name
+func (_m *PostgresStorageInterface) GetUTMTemplate(ctx context.Context, owner string, name string) (postgres.UTMTemplate, error) {
+	ret := _m.Called(ctx, owner, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUTMTemplate")
+	}
+
+	var r0 postgres.UTMTemplate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (postgres.UTMTemplate, error)); ok {
+		return rf(ctx, owner, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) postgres.UTMTemplate); ok {
+		r0 = rf(ctx, owner, name)
+	} else {
+		r0 = ret.Get(0).(postgres.UTMTemplate)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, owner, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportURLs provides a mock function with given fields: ctx, rows, policy
+func (_m *PostgresStorageInterface) ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error) {
+	ret := _m.Called(ctx, rows, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportURLs")
+	}
+
+	var r0 postgres.ImportStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, iter.Seq2[postgres.ImportRow, error], postgres.ConflictPolicy) (postgres.ImportStats, error)); ok {
+		return rf(ctx, rows, policy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, iter.Seq2[postgres.ImportRow, error], postgres.ConflictPolicy) postgres.ImportStats); ok {
+		r0 = rf(ctx, rows, policy)
+	} else {
+		r0 = ret.Get(0).(postgres.ImportStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, iter.Seq2[postgres.ImportRow, error], postgres.ConflictPolicy) error); ok {
+		r1 = rf(ctx, rows, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDomains provides a mock function with given fields: ctx
+func (_m *PostgresStorageInterface) ListDomains(ctx context.Context) ([]postgres.Domain, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDomains")
+	}
+
+	var r0 []postgres.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]postgres.Domain, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []postgres.Domain); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListURLs provides a mock function with given fields: ctx, filter, limit, offset
+func (_m *PostgresStorageInterface) ListURLs(ctx context.Context, filter postgres.Filter, limit int, offset int) ([]postgres.Link, error) {
+	ret := _m.Called(ctx, filter, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListURLs")
+	}
+
+	var r0 []postgres.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Filter, int, int) ([]postgres.Link, error)); ok {
+		return rf(ctx, filter, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Filter, int, int) []postgres.Link); ok {
+		r0 = rf(ctx, filter, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.Filter, int, int) error); ok {
+		r1 = rf(ctx, filter, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUTMTemplates provides a mock function with given fields: ctx, owner
+func (_m *PostgresStorageInterface) ListUTMTemplates(ctx context.Context, owner string) ([]postgres.UTMTemplate, error) {
+	ret := _m.Called(ctx, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUTMTemplates")
+	}
+
+	var r0 []postgres.UTMTemplate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]postgres.UTMTemplate, error)); ok {
+		return rf(ctx, owner)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []postgres.UTMTemplate); ok {
+		r0 = rf(ctx, owner)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.UTMTemplate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeDeletedURLs provides a mock function with given fields: ctx, deletedBefore
+func (_m *PostgresStorageInterface) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, deletedBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeDeletedURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, deletedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, deletedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, deletedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordClicks provides a mock function with given fields: ctx, clicks
+func (_m *PostgresStorageInterface) RecordClicks(ctx context.Context, clicks []postgres.Click) error {
+	ret := _m.Called(ctx, clicks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordClicks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.Click) error); ok {
+		r0 = rf(ctx, clicks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreURL provides a mock function with given fields: ctx, domain, alias
+func (_m *PostgresStorageInterface) RestoreURL(ctx context.Context, domain string, alias string) error {
+	ret := _m.Called(ctx, domain, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreURL")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, domain, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveDomain provides a mock function with given fields: ctx, host
+func (_m *PostgresStorageInterface) SaveDomain(ctx context.Context, host string) (postgres.Domain, error) {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveDomain")
+	}
+
+	var r0 postgres.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (postgres.Domain, error)); ok {
+		return rf(ctx, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) postgres.Domain); ok {
+		r0 = rf(ctx, host)
+	} else {
+		r0 = ret.Get(0).(postgres.Domain)
+	}
+
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, alias)
+		r1 = rf(ctx, host)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -59,9 +535,9 @@ func (_m *PostgresStorageInterface) GetURL(ctx context.Context, alias string) (s
 	return r0, r1
 }
 
-// SaveURL provides a mock function with given fields: ctx, urlToSave, alias
-func (_m *PostgresStorageInterface) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(ctx, urlToSave, alias)
+// SaveURL provides a mock function with given fields: ctx, link
+func (_m *PostgresStorageInterface) SaveURL(ctx context.Context, link postgres.Link) (int64, error) {
+	ret := _m.Called(ctx, link)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveURL")
@@ -69,17 +545,17 @@ func (_m *PostgresStorageInterface) SaveURL(ctx context.Context, urlToSave strin
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int64, error)); ok {
-		return rf(ctx, urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Link) (int64, error)); ok {
+		return rf(ctx, link)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
-		r0 = rf(ctx, urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Link) int64); ok {
+		r0 = rf(ctx, link)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.Link) error); ok {
+		r1 = rf(ctx, link)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -87,9 +563,115 @@ func (_m *PostgresStorageInterface) SaveURL(ctx context.Context, urlToSave strin
 	return r0, r1
 }
 
-// URLExists provides a mock function with given fields: ctx, url
-func (_m *PostgresStorageInterface) URLExists(ctx context.Context, url string) (bool, error) {
-	ret := _m.Called(ctx, url)
+// SaveURLs provides a mock function with given fields: ctx, links, atomic
+func (_m *PostgresStorageInterface) SaveURLs(ctx context.Context, links []postgres.Link, atomic bool) ([]postgres.SaveResult, error) {
+	ret := _m.Called(ctx, links, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveURLs")
+	}
+
+	var r0 []postgres.SaveResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.Link, bool) ([]postgres.SaveResult, error)); ok {
+		return rf(ctx, links, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.Link, bool) []postgres.SaveResult); ok {
+		r0 = rf(ctx, links, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.SaveResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []postgres.Link, bool) error); ok {
+		r1 = rf(ctx, links, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveUTMTemplate provides a mock function with given fields: ctx, t
+func (_m *PostgresStorageInterface) SaveUTMTemplate(ctx context.Context, t postgres.UTMTemplate) (postgres.UTMTemplate, error) {
+	ret := _m.Called(ctx, t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveUTMTemplate")
+	}
+
+	var r0 postgres.UTMTemplate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.UTMTemplate) (postgres.UTMTemplate, error)); ok {
+		return rf(ctx, t)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.UTMTemplate) postgres.UTMTemplate); ok {
+		r0 = rf(ctx, t)
+	} else {
+		r0 = ret.Get(0).(postgres.UTMTemplate)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.UTMTemplate) error); ok {
+		r1 = rf(ctx, t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRedirectRules provides a mock function with given fields: ctx, domain, alias, rules
+func (_m *PostgresStorageInterface) SetRedirectRules(ctx context.Context, domain string, alias string, rules []postgres.RedirectRule) ([]postgres.RedirectRule, error) {
+	ret := _m.Called(ctx, domain, alias, rules)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRedirectRules")
+	}
+
+	var r0 []postgres.RedirectRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []postgres.RedirectRule) ([]postgres.RedirectRule, error)); ok {
+		return rf(ctx, domain, alias, rules)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []postgres.RedirectRule) []postgres.RedirectRule); ok {
+		r0 = rf(ctx, domain, alias, rules)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.RedirectRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []postgres.RedirectRule) error); ok {
+		r1 = rf(ctx, domain, alias, rules)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetSplit provides a mock function with given fields: ctx, domain, alias, split
+func (_m *PostgresStorageInterface) SetSplit(ctx context.Context, domain string, alias string, split *postgres.Split) error {
+	ret := _m.Called(ctx, domain, alias, split)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSplit")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *postgres.Split) error); ok {
+		r0 = rf(ctx, domain, alias, split)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLExists provides a mock function with given fields: ctx, url, domain
+func (_m *PostgresStorageInterface) URLExists(ctx context.Context, url string, domain string) (bool, error) {
+	ret := _m.Called(ctx, url, domain)
 
 	if len(ret) == 0 {
 		panic("no return value specified for URLExists")
@@ -97,17 +679,17 @@ func (_m *PostgresStorageInterface) URLExists(ctx context.Context, url string) (
 
 	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
-		return rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, url, domain)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
-		r0 = rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, url, domain)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, url)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, url, domain)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,147 @@
+package redirect
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Политики passthrough: что делать, если параметр из запроса уже есть в сохраненном URL.
+// Пустое значение отключает passthrough для ссылки.
+const (
+	// PassthroughKeep оставляет значение из сохраненного URL
+	PassthroughKeep = "keep"
+	// PassthroughReplace заменяет его значением из запроса
+	PassthroughReplace = "replace"
+	// PassthroughAppend оставляет оба значения
+	PassthroughAppend = "append"
+)
+
+var errUnknownPassthrough = errors.New("unknown passthrough policy")
+
+// passthroughTarget дописывает к сохраненному URL лишние сегменты пути и параметры запроса.
+// extraPath и rawQuery передаются в экранированном виде, как пришли от клиента.
+// Кодировка сохраненного URL не меняется, фрагмент остается в конце.
+func passthroughTarget(stored, extraPath, rawQuery, policy string) (string, error) {
+	switch policy {
+	case PassthroughKeep, PassthroughReplace, PassthroughAppend:
+	default:
+		return "", errUnknownPassthrough
+	}
+
+	u, err := url.Parse(stored)
+	if err != nil {
+		return "", err
+	}
+
+	if segments := pathSegments(extraPath); len(segments) > 0 {
+		escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+		path, err := url.PathUnescape(escaped)
+		if err != nil {
+			return "", err
+		}
+		u.Path, u.RawPath = path, escaped
+	}
+
+	u.RawQuery = mergeQuery(u.RawQuery, rawQuery, policy)
+
+	return u.String(), nil
+}
+
+// pathSegments разбивает экранированный путь на сегменты. Пустые сегменты, "." и ".."
+// отбрасываются, чтобы лишний путь не мог подняться выше пути сохраненного URL.
+func pathSegments(escaped string) []string {
+	var segments []string
+	for _, segment := range strings.Split(escaped, "/") {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil || decoded == "" || decoded == "." || decoded == ".." {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// queryPair - параметр запроса в исходной записи и его раскодированный ключ
+type queryPair struct {
+	raw string
+	key string
+}
+
+// mergeQuery объединяет параметры, не перекодируя сохраненные. Параметры из запроса
+// перекодируются заново, а параметры с некорректным экранированием отбрасываются.
+func mergeQuery(storedQuery, incomingQuery, policy string) string {
+	stored := splitQuery(storedQuery)
+	var incoming []queryPair
+	for _, pair := range splitQuery(incomingQuery) {
+		if normalized, ok := normalizePair(pair.raw); ok {
+			incoming = append(incoming, queryPair{raw: normalized, key: pair.key})
+		}
+	}
+	if len(incoming) == 0 {
+		return storedQuery
+	}
+
+	storedKeys := make(map[string]bool, len(stored))
+	for _, pair := range stored {
+		storedKeys[pair.key] = true
+	}
+	incomingKeys := make(map[string]bool, len(incoming))
+	for _, pair := range incoming {
+		incomingKeys[pair.key] = true
+	}
+
+	merged := make([]string, 0, len(stored)+len(incoming))
+	for _, pair := range stored {
+		if policy == PassthroughReplace && incomingKeys[pair.key] {
+			continue
+		}
+		merged = append(merged, pair.raw)
+	}
+	for _, pair := range incoming {
+		if policy == PassthroughKeep && storedKeys[pair.key] {
+			continue
+		}
+		merged = append(merged, pair.raw)
+	}
+	return strings.Join(merged, "&")
+}
+
+func splitQuery(rawQuery string) []queryPair {
+	var pairs []queryPair
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(raw, "=")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+		pairs = append(pairs, queryPair{raw: raw, key: key})
+	}
+	return pairs
+}
+
+// extraPath возвращает экранированный путь после алиаса: для /abc/x/y это x/y
+func extraPath(r *http.Request) string {
+	_, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	return rest
+}
+
+// normalizePair перекодирует параметр из запроса. Параметр без "=" остается флагом.
+func normalizePair(raw string) (string, bool) {
+	key, value, hasValue := strings.Cut(raw, "=")
+	key, err := url.QueryUnescape(key)
+	if err != nil || key == "" {
+		return "", false
+	}
+	if !hasValue {
+		return url.QueryEscape(key), true
+	}
+	value, err = url.QueryUnescape(value)
+	if err != nil {
+		return "", false
+	}
+	return url.QueryEscape(key) + "=" + url.QueryEscape(value), true
+}
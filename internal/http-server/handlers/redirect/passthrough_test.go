@@ -0,0 +1,158 @@
+package redirect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassthroughTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    string
+		extraPath string
+		rawQuery  string
+		policy    string
+		expected  string
+	}{
+		{
+			name:     "Query added to url without query",
+			stored:   "https://example.com/landing",
+			rawQuery: "utm_source=mail",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/landing?utm_source=mail",
+		},
+		{
+			name:     "Nothing to forward",
+			stored:   "https://example.com/landing?a=1",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/landing?a=1",
+		},
+		{
+			name:     "Keep stored value on conflict",
+			stored:   "https://example.com/?utm_source=site&a=1",
+			rawQuery: "utm_source=mail&b=2",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/?utm_source=site&a=1&b=2",
+		},
+		{
+			name:     "Replace stored value on conflict",
+			stored:   "https://example.com/?utm_source=site&a=1",
+			rawQuery: "utm_source=mail&b=2",
+			policy:   PassthroughReplace,
+			expected: "https://example.com/?a=1&utm_source=mail&b=2",
+		},
+		{
+			name:     "Append keeps both values",
+			stored:   "https://example.com/?tag=a",
+			rawQuery: "tag=b",
+			policy:   PassthroughAppend,
+			expected: "https://example.com/?tag=a&tag=b",
+		},
+		{
+			name:     "Replace drops every stored value of repeated key",
+			stored:   "https://example.com/?tag=a&tag=b&x=1",
+			rawQuery: "tag=c",
+			policy:   PassthroughReplace,
+			expected: "https://example.com/?x=1&tag=c",
+		},
+		{
+			name:     "Stored encoding is preserved",
+			stored:   "https://example.com/?q=a%20b&path=%2Fdocs&sig=x+y",
+			rawQuery: "page=2",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/?q=a%20b&path=%2Fdocs&sig=x+y&page=2",
+		},
+		{
+			name:     "Escaped key conflicts with plain key",
+			stored:   "https://example.com/?utm_source=site",
+			rawQuery: "utm%5Fsource=mail",
+			policy:   PassthroughReplace,
+			expected: "https://example.com/?utm_source=mail",
+		},
+		{
+			name:     "Incoming values are reencoded",
+			stored:   "https://example.com/",
+			rawQuery: "q=a+b&name=%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82&amp=%26%3D",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/?q=a+b&name=%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82&amp=%26%3D",
+		},
+		{
+			name:     "Invalid escaping and empty pairs are dropped",
+			stored:   "https://example.com/",
+			rawQuery: "bad=%zz&&=orphan&ok=1&%zz=2",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/?ok=1",
+		},
+		{
+			name:     "Flag without value",
+			stored:   "https://example.com/?a=1",
+			rawQuery: "debug&b=",
+			policy:   PassthroughAppend,
+			expected: "https://example.com/?a=1&debug&b=",
+		},
+		{
+			name:     "Fragment stays at the end",
+			stored:   "https://example.com/docs?a=1#install",
+			rawQuery: "b=2",
+			policy:   PassthroughKeep,
+			expected: "https://example.com/docs?a=1&b=2#install",
+		},
+		{
+			name:      "Path appended to url without path",
+			stored:    "https://example.com",
+			extraPath: "extra/path",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/extra/path",
+		},
+		{
+			name:      "Path appended after trailing slash",
+			stored:    "https://example.com/docs/?v=1",
+			extraPath: "guide/intro",
+			rawQuery:  "lang=en",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/docs/guide/intro?v=1&lang=en",
+		},
+		{
+			name:      "Escaped segments are kept as is",
+			stored:    "https://example.com/files",
+			extraPath: "a%2Fb/%D1%84%D0%B0%D0%B9%D0%BB%20one.pdf",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/files/a%2Fb/%D1%84%D0%B0%D0%B9%D0%BB%20one.pdf",
+		},
+		{
+			name:      "Escaped stored path is kept as is",
+			stored:    "https://example.com/a%2Fb",
+			extraPath: "c",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/a%2Fb/c",
+		},
+		{
+			name:      "Dot segments and empty segments are dropped",
+			stored:    "https://example.com/docs",
+			extraPath: "../admin//./x/%2E%2E/",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/docs/admin/x",
+		},
+		{
+			name:      "Leading slashes do not change host",
+			stored:    "https://example.com",
+			extraPath: "/evil.com/x",
+			policy:    PassthroughKeep,
+			expected:  "https://example.com/evil.com/x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := passthroughTarget(tt.stored, tt.extraPath, tt.rawQuery, tt.policy)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPassthroughTargetUnknownPolicy(t *testing.T) {
+	_, err := passthroughTarget("https://example.com", "", "a=1", "merge")
+	require.ErrorIs(t, err, errUnknownPassthrough)
+}
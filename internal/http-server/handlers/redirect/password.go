@@ -0,0 +1,215 @@
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/storage/postgres"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordOptions - защита ссылок паролем. Secret подписывает cookie, с которой пароль
+// не спрашивается повторно в течение CookieTTL; без Secret ключ создается при запуске,
+// и cookie перестают действовать после перезапуска. После MaxAttempts неверных паролей
+// за AttemptWindow ссылка не принимает пароль до конца окна.
+type PasswordOptions struct {
+	Secret        []byte
+	CookieTTL     time.Duration
+	MaxAttempts   int
+	AttemptWindow time.Duration
+}
+
+const (
+	defaultPasswordCookieTTL = time.Hour
+	defaultMaxAttempts       = 5
+	defaultAttemptWindow     = 15 * time.Minute
+	// maxPasswordFormBytes ограничивает тело формы с паролем
+	maxPasswordFormBytes = 4 << 10
+	// maxTrackedLinks - после стольких ссылок с неудачными попытками устаревшие записи вычищаются
+	maxTrackedLinks = 10000
+)
+
+// passwordGuard проверяет пароль ссылки и считает попытки по id ссылки:
+// алиасы на разных доменах могут совпадать
+type passwordGuard struct {
+	opts PasswordOptions
+
+	mu       sync.Mutex
+	failures map[int64]*failedAttempts
+}
+
+type failedAttempts struct {
+	count   int
+	resetAt time.Time
+}
+
+func newPasswordGuard(opts PasswordOptions) *passwordGuard {
+	if len(opts.Secret) == 0 {
+		opts.Secret = make([]byte, 32)
+		rand.Read(opts.Secret)
+	}
+	if opts.CookieTTL <= 0 {
+		opts.CookieTTL = defaultPasswordCookieTTL
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.AttemptWindow <= 0 {
+		opts.AttemptWindow = defaultAttemptWindow
+	}
+	return &passwordGuard{opts: opts, failures: make(map[int64]*failedAttempts)}
+}
+
+// unlock возвращает true, если у клиента есть подписанная cookie или он прислал верный пароль.
+// Иначе отвечает формой ввода пароля.
+func (g *passwordGuard) unlock(w http.ResponseWriter, r *http.Request, log *slog.Logger, link postgres.Link) bool {
+	now := time.Now()
+	if r.Method != http.MethodPost {
+		if g.validCookie(r, link, now) {
+			return true
+		}
+		g.serveForm(w, http.StatusOK, "")
+		return false
+	}
+
+	// Попытка резервируется до сравнения пароля: иначе параллельные запросы успевают
+	// пройти проверку лимита, пока идет медленный bcrypt
+	attempt, wait := g.reserve(link.ID, now)
+	if wait > 0 {
+		log.Warn("too many password attempts", slog.String("alias", link.Alias))
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		g.serveForm(w, http.StatusTooManyRequests, "Too many attempts. Try again later.")
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormBytes)
+	password := r.PostFormValue("password")
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		log.Info("wrong password", slog.String("alias", link.Alias))
+		g.serveForm(w, http.StatusForbidden, "Wrong password.")
+		return false
+	}
+	g.release(link.ID, attempt)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     passwordCookieName(link),
+		Value:    g.sign(link, now.Add(g.opts.CookieTTL)),
+		Path:     "/",
+		MaxAge:   int(g.opts.CookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return true
+}
+
+// passwordCookieName - имя cookie с доступом к ссылке
+func passwordCookieName(link postgres.Link) string {
+	return "pw_" + strconv.FormatInt(link.ID, 10)
+}
+
+// sign подписывает срок действия cookie вместе с id ссылки и хешем пароля:
+// после смены пароля старые cookie перестают подходить
+func (g *passwordGuard) sign(link postgres.Link, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + base64.RawURLEncoding.EncodeToString(g.mac(link, exp))
+}
+
+func (g *passwordGuard) mac(link postgres.Link, exp string) []byte {
+	h := hmac.New(sha256.New, g.opts.Secret)
+	h.Write([]byte(strconv.FormatInt(link.ID, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(link.PasswordHash))
+	h.Write([]byte{0})
+	h.Write([]byte(exp))
+	return h.Sum(nil)
+}
+
+func (g *passwordGuard) validCookie(r *http.Request, link postgres.Link, now time.Time) bool {
+	cookie, err := r.Cookie(passwordCookieName(link))
+	if err != nil {
+		return false
+	}
+	exp, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	return err == nil && hmac.Equal(got, g.mac(link, exp))
+}
+
+// reserve засчитывает попытку ввода пароля. Если лимит попыток исчерпан, попытка не
+// засчитывается, а возвращается время до конца окна.
+func (g *passwordGuard) reserve(id int64, now time.Time) (*failedAttempts, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f, ok := g.failures[id]
+	if !ok || !now.Before(f.resetAt) {
+		if len(g.failures) >= maxTrackedLinks {
+			for old, attempts := range g.failures {
+				if !now.Before(attempts.resetAt) {
+					delete(g.failures, old)
+				}
+			}
+		}
+		f = &failedAttempts{resetAt: now.Add(g.opts.AttemptWindow)}
+		g.failures[id] = f
+	}
+	if f.count >= g.opts.MaxAttempts {
+		return nil, f.resetAt.Sub(now)
+	}
+	f.count++
+	return f, 0
+}
+
+// release снимает попытку, зарезервированную reserve, если пароль оказался верным
+func (g *passwordGuard) release(id int64, f *failedAttempts) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f.count--
+	if f.count == 0 && g.failures[id] == f {
+		delete(g.failures, id)
+	}
+}
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is protected. Enter the password to continue.</p>
+{{if .}}<p role="alert">{{.}}</p>
+{{end}}<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// serveForm отдает форму ввода пароля. Форма отправляется на тот же адрес, поэтому путь
+// и параметры запроса для passthrough сохраняются.
+func (g *passwordGuard) serveForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	passwordForm.Execute(w, message)
+}
@@ -0,0 +1,111 @@
+package redirect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestPasswordGuardAttempts(t *testing.T) {
+	g := newPasswordGuard(PasswordOptions{MaxAttempts: 3, AttemptWindow: time.Minute})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for range 3 {
+		_, wait := g.reserve(1, now)
+		require.Zero(t, wait)
+	}
+	_, wait := g.reserve(1, now)
+	require.Equal(t, time.Minute, wait)
+	_, wait = g.reserve(1, now.Add(40*time.Second))
+	require.Equal(t, 20*time.Second, wait)
+	// Другие ссылки, в том числе с тем же алиасом на другом домене, не блокируются
+	_, wait = g.reserve(2, now)
+	require.Zero(t, wait)
+	// После окна попытки снова принимаются
+	_, wait = g.reserve(1, now.Add(time.Minute))
+	require.Zero(t, wait)
+
+	// Верный пароль снимает свою попытку и не расходует лимит
+	attempt, wait := g.reserve(3, now)
+	require.Zero(t, wait)
+	g.release(3, attempt)
+	require.NotContains(t, g.failures, int64(3))
+	for range 3 {
+		_, wait = g.reserve(3, now)
+		require.Zero(t, wait)
+	}
+}
+
+func TestPasswordGuardConcurrentAttempts(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	link := postgres.Link{ID: 1, Alias: "docs", PasswordHash: string(hash)}
+	g := newPasswordGuard(PasswordOptions{MaxAttempts: 3, AttemptWindow: time.Minute})
+
+	const requests = 20
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := strings.NewReader(url.Values{"password": {"wrong"}}.Encode())
+			r := httptest.NewRequest(http.MethodPost, "/docs", body)
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			g.unlock(rr, r, slogdiscard.NewDiscardLogger(), link)
+			codes <- rr.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	got := map[int]int{}
+	for code := range codes {
+		got[code]++
+	}
+	// Параллельные запросы не обходят лимит: пароль сравнивается не больше MaxAttempts раз
+	require.Equal(t, map[int]int{
+		http.StatusForbidden:       3,
+		http.StatusTooManyRequests: requests - 3,
+	}, got)
+}
+
+func TestPasswordGuardCookie(t *testing.T) {
+	g := newPasswordGuard(PasswordOptions{Secret: []byte("0123456789abcdef0123456789abcdef")})
+	link := postgres.Link{ID: 1, Alias: "docs", PasswordHash: "$2a$04$hash"}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	value := g.sign(link, now.Add(time.Hour))
+
+	withCookie := func(value string) bool {
+		r := httptest.NewRequest("GET", "/docs", nil)
+		r.Header.Set("Cookie", passwordCookieName(link)+"="+value)
+		return g.validCookie(r, link, now)
+	}
+
+	require.True(t, withCookie(value))
+	require.False(t, withCookie(value+"x"), "tampered signature")
+	require.False(t, withCookie(g.sign(link, now)), "expired")
+	require.False(t, withCookie("garbage"))
+
+	changed := link
+	changed.PasswordHash = "$2a$04$other"
+	require.False(t, withCookie(g.sign(changed, now.Add(time.Hour))), "password changed")
+
+	sameAlias := link
+	sameAlias.ID, sameAlias.Domain = 2, "go.brand-a.com"
+	require.False(t, withCookie(g.sign(sameAlias, now.Add(time.Hour))), "other link with the same alias")
+
+	other := newPasswordGuard(PasswordOptions{})
+	require.False(t, withCookie(other.sign(link, now.Add(time.Hour))), "different secret")
+}
@@ -0,0 +1,79 @@
+package redirect
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"url-shortener/internal/storage/postgres"
+)
+
+// previewSuffix после алиаса открывает страницу предпросмотра вместо редиректа: GET /{alias}+
+const previewSuffix = "+"
+
+// previewRequest определяет, нужен ли предпросмотр. Параметр preview=1 убирается из запроса,
+// чтобы при passthrough он не попал в целевой URL.
+func previewRequest(r *http.Request, alias string) (string, *http.Request, bool) {
+	if trimmed, ok := strings.CutSuffix(alias, previewSuffix); ok {
+		return trimmed, r, true
+	}
+	query := r.URL.Query()
+	if query.Get("preview") != "1" {
+		return alias, r, false
+	}
+	query.Del("preview")
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	r2 := r.Clone(r.Context())
+	r2.URL = &u
+	return alias, r2, true
+}
+
+type previewPage struct {
+	Title   string
+	Target  string
+	Host    string
+	Created string
+	// Followable - ссылку на цель можно показать как ссылку: только http и https
+	Followable bool
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>This short link leads to{{if .Host}} <strong>{{.Host}}</strong>{{end}}:</p>
+<p><code>{{.Target}}</code></p>
+{{if .Created}}<p>Created {{.Created}}</p>
+{{end}}{{if .Followable}}<p><a href="{{.Target}}" rel="noopener noreferrer nofollow">Continue</a></p>
+{{end}}</body>
+</html>
+`))
+
+// servePreview отдает страницу с адресом назначения. Адрес берется только из ссылки,
+// параметры запроса не могут подменить цель, а вывод экранируется html/template.
+func servePreview(w http.ResponseWriter, link postgres.Link, target string) error {
+	page := previewPage{Title: link.Title, Target: target}
+	if page.Title == "" {
+		page.Title = link.Alias
+	}
+	if !link.CreatedAt.IsZero() {
+		page.Created = link.CreatedAt.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		page.Host = u.Hostname()
+		page.Followable = true
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	return previewTemplate.Execute(w, page)
+}
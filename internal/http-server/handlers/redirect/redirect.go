@@ -3,21 +3,77 @@ package redirect
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"log/slog"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	"url-shortener/internal/lib/geoip"
 	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/metrics"
 
 	storages "url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
 )
 
-func New(ctx context.Context, log *slog.Logger, storage *storages.Storage) http.HandlerFunc {
+// Options - статус редиректа для ссылок без своего redirect_type и время кеширования постоянных редиректов.
+// Geo определяет страну для правил со странами, без него такие правила не срабатывают.
+// Clicks получает каждый успешный переход, nil отключает запись аналитики.
+// ComingSoonURL - куда вести по ссылке до ее active_from, пустой - отвечать 404.
+// Interstitial показывает страницу предпросмотра вместо редиректа для всех ссылок.
+type Options struct {
+	DefaultType     int
+	PermanentMaxAge time.Duration
+	ComingSoonURL   string
+	Interstitial    bool
+	Geo             *geoip.Resolver
+	Clicks          ClickRecorder
+	Password        PasswordOptions
+	// DefaultDomain - хост основного домена. Если задан, алиас ищется в домене из Host запроса,
+	// а запросы на незарегистрированные хосты получают 404. Пустой - Host не учитывается.
+	DefaultDomain string
+}
+
+// domain возвращает домен ссылки по Host запроса, пустая строка - основной домен
+func (o Options) domain(r *http.Request) string {
+	if o.DefaultDomain == "" {
+		return ""
+	}
+	host := domain.FromHost(r.Host)
+	if host == o.DefaultDomain {
+		return ""
+	}
+	return host
+}
+
+// ClickRecorder записывает переходы, см. clicks.Recorder. Record не должен блокировать редирект.
+type ClickRecorder interface {
+	Record(click postgres.Click)
+}
+
+// status возвращает статус редиректа для ссылки
+func (o Options) status(link postgres.Link) int {
+	if link.RedirectType != 0 {
+		return link.RedirectType
+	}
+	if o.DefaultType != 0 {
+		return o.DefaultType
+	}
+	return http.StatusFound
+}
+
+// New обрабатывает GET /{alias}. Для ссылок с паролем POST /{alias} принимает форму с паролем.
+// GET /{alias}+ и ?preview=1 показывают страницу с адресом назначения вместо редиректа.
+func New(log *slog.Logger, storage *storages.Storage, opts Options) http.HandlerFunc {
+	guard := newPasswordGuard(opts.Password)
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -26,34 +82,233 @@ func New(ctx context.Context, log *slog.Logger, storage *storages.Storage) http.
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		alias := chi.URLParam(r, "alias")
+		// Запрос к БД отменяется вместе с запросом клиента
+		ctx, span := tracing.Start(r.Context(), op)
+		defer span.End()
+
+		alias, r, preview := previewRequest(r, chi.URLParam(r, "alias"))
 		if alias == "" {
 			log.Info("alias is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "invalid request")
 
 			return
 		}
 
-		resURL, err := storage.GetURL(ctx, alias)
+		span.SetAttributes(tracing.String("alias", alias))
+
+		linkDomain := opts.domain(r)
+		link, err := storage.GetLink(ctx, linkDomain, alias)
 		if errors.Is(err, storages.ErrURLNotFound) {
-			log.Info("url not found", "alias", alias)
+			log.Info("url not found", "alias", alias, "domain", linkDomain)
+			metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "not found")
+
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Info("request cancelled", slog.String("alias", alias))
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Warn("storage timeout", slog.String("alias", alias))
+			span.RecordError(err)
 
-			render.JSON(w, r, resp.Error("not found"))
+			resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeTimeout, "storage timeout")
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", slogger.Err(err))
+			span.RecordError(err)
+
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+
+			return
+		}
 
-			render.JSON(w, r, resp.Error("internal error"))
+		switch link.State(time.Now()) {
+		case postgres.StateScheduled:
+			log.Info("link is not active yet", slog.String("alias", alias))
+			metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+			if opts.ComingSoonURL != "" {
+				w.Header().Set("Cache-Control", "no-store")
+				http.Redirect(w, r, opts.ComingSoonURL, http.StatusFound)
+				return
+			}
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "not found")
+
+			return
+		case postgres.StateExpired:
+			log.Info("link has expired", slog.String("alias", alias))
+			metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+			resp.WriteError(w, r, http.StatusGone, resp.CodeGone, "link has expired")
 
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		if link.Passthrough == "" && len(pathSegments(extraPath(r))) > 0 {
+			// Без passthrough ссылка не принимает вложенные пути
+			log.Info("extra path without passthrough", slog.String("alias", alias))
+			metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "not found")
+
+			return
+		}
+
+		if link.MaxClicks > 0 && link.ClicksUsed >= link.MaxClicks {
+			log.Info("click limit exhausted", slog.String("alias", alias))
+			metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+			resp.WriteError(w, r, http.StatusGone, resp.CodeGone, "link is no longer available")
+
+			return
+		}
+		if link.PasswordHash == "" && r.Method == http.MethodPost {
+			resp.WriteError(w, r, http.StatusMethodNotAllowed, resp.CodeMethodNotAllowed, "method not allowed")
+
+			return
+		}
+		if link.PasswordHash != "" && !guard.unlock(w, r, log, link) {
+			return
+		}
+
+		// Правила по устройству и стране важнее A/B-теста: iOS всегда уходит в App Store
+		destination, variant := link.URL, ""
+		c := client{r: r, geo: opts.Geo}
+		if rule := c.matchRule(link.Rules); rule >= 0 {
+			destination = link.Rules[rule].Target
+			span.SetAttributes(tracing.Int("rule", rule))
+			log.Debug("redirect rule matched", slog.Int("rule", rule))
+		} else if link.Split != nil {
+			if v, cookie, ok := c.pickVariant(alias, link.Split); ok {
+				destination, variant = v.URL, v.Name
+				if cookie != nil {
+					http.SetCookie(w, cookie)
+				}
+				span.SetAttributes(tracing.String("variant", variant))
+			}
+		}
+		if c.countryErr != nil {
+			// Страна не определилась: правила со странами просто не срабатывают
+			log.Warn("failed to resolve country", slogger.Err(c.countryErr))
+		}
+		if vary := varyHeaders(link.Rules, opts.Geo); len(vary) > 0 {
+			w.Header().Set("Vary", strings.Join(vary, ", "))
+		}
+
+		target, err := buildTarget(destination, link, r)
+		if err != nil {
+			log.Error("failed to build target url", slogger.Err(err), slog.String("alias", alias))
+			span.RecordError(err)
+
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+
+			return
+		}
+
+		if link.MaxClicks > 0 {
+			// Переход списывается в БД только сейчас: показ формы пароля и ошибки выше лимит не тратят
+			left, err := storage.ConsumeClick(ctx, link.ID)
+			switch {
+			case errors.Is(err, storages.ErrClicksExhausted):
+				log.Info("click limit exhausted", slog.String("alias", alias))
+				metrics.Redirects.WithLabelValues(metrics.ResultMiss).Inc()
+
+				resp.WriteError(w, r, http.StatusGone, resp.CodeGone, "link is no longer available")
+
+				return
+			case errors.Is(err, context.Canceled):
+				log.Info("request cancelled", slog.String("alias", alias))
+				return
+			case errors.Is(err, context.DeadlineExceeded):
+				log.Warn("storage timeout", slog.String("alias", alias))
+				span.RecordError(err)
+
+				resp.WriteError(w, r, http.StatusGatewayTimeout, resp.CodeTimeout, "storage timeout")
+
+				return
+			case err != nil:
+				log.Error("failed to consume click", slogger.Err(err))
+				span.RecordError(err)
+
+				resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+
+				return
+			}
+			span.SetAttributes(tracing.Int("clicks_left", left))
+		}
+
+		status := opts.status(link)
+		if link.PasswordHash != "" || link.MaxClicks > 0 {
+			// Постоянный редирект браузер запомнил бы и открывал ссылку без пароля и сверх лимита
+			status = uncachedStatus(status, r.Method)
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		log.Info("got url", slog.String("url", target), slog.Int("status", status), slog.String("variant", variant))
+		metrics.Redirects.WithLabelValues(metrics.ResultHit).Inc()
+
+		// Постоянный редирект браузер запоминает, поэтому срок задается явно,
+		// иначе смена адреса ссылки не дойдет до клиентов, которые уже переходили по ней
+		if status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect {
+			// Цель по стране из базы и вариант A/B-теста не выразить через Vary,
+			// поэтому такие ссылки не кешируются в общих кешах
+			scope := "public"
+			if len(link.Rules) > 0 || link.Split != nil {
+				scope = "private"
+			}
+			maxAge := opts.PermanentMaxAge
+			if link.ExpiresAt != nil {
+				// Браузер не должен помнить редирект дольше, чем живет ссылка
+				maxAge = max(min(maxAge, time.Until(*link.ExpiresAt)), 0)
+			}
+			w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(maxAge.Seconds())))
+		}
+
+		// Явный предпросмотр не попадает в статистику и результаты A/B-теста.
+		// Страница interstitial показывается вместо редиректа, поэтому считается переходом.
+		if opts.Clicks != nil && !preview {
+			opts.Clicks.Record(postgres.Click{LinkID: link.ID, Variant: variant, ClickedAt: time.Now()})
+		}
+
+		// Предпросмотр проходит те же проверки, что и редирект, и списывает переход из max_clicks:
+		// иначе по нему можно было бы узнать цель одноразовой ссылки, не тратя переход
+		if preview || opts.Interstitial || link.Interstitial {
+			if err := servePreview(w, link, target); err != nil {
+				log.Error("failed to render preview", slogger.Err(err))
+			}
+			return
+		}
 
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, target, status)
+	}
+}
+
+// uncachedStatus заменяет постоянный редирект временным с тем же методом,
+// а после отправки формы с паролем переводит браузер на GET
+func uncachedStatus(status int, method string) int {
+	switch {
+	case method == http.MethodPost:
+		return http.StatusSeeOther
+	case status == http.StatusMovedPermanently:
+		return http.StatusFound
+	case status == http.StatusPermanentRedirect:
+		return http.StatusTemporaryRedirect
+	}
+	return status
+}
+
+// buildTarget собирает итоговый адрес из выбранной цели: сначала utm-метки ссылки,
+// затем путь и параметры запроса при passthrough
+func buildTarget(destination string, link postgres.Link, r *http.Request) (string, error) {
+	target, err := utmTarget(destination, link.UTM)
+	if err != nil || link.Passthrough == "" {
+		return target, err
 	}
+	return passthroughTarget(target, extraPath(r), r.URL.RawQuery, link.Passthrough)
 }
@@ -4,19 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/geoip"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
 )
 
 func TestRedirectHandler(t *testing.T) {
@@ -24,66 +33,310 @@ func TestRedirectHandler(t *testing.T) {
 	// mockery --name=PostgresStorageInterface --dir=internal/storage/postgres --output=internal/storage/mocks --outpkg=mocks
 	storageMock := mocks.NewPostgresStorageInterface(t)
 	log := slogdiscard.NewDiscardLogger()
-	ctx := context.Background()
+	// Обработчик передает в хранилище контекст запроса со спаном и таймаутом, поэтому сам ctx не сравнивается
+	anyCtx := mock.Anything
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+	soon := time.Now().Add(30*time.Minute + 30*time.Second + 500*time.Millisecond)
+	appRules := []postgres.RedirectRule{
+		{Device: redirect.DeviceIOS, Target: "https://apps.apple.com/app/id1"},
+		{Countries: []string{"DE"}, Target: "https://example.de/"},
+	}
 
 	tests := []struct {
-		name         string
-		alias        string
-		mockBehavior func()
-		expectedCode int
-		expectedURL  string
-		expectedResp *response.Response
+		name                 string
+		alias                string
+		path                 string
+		mockBehavior         func()
+		expectedCode         int
+		expectedURL          string
+		expectedErr          string
+		timeouts             storage.Timeouts
+		opts                 redirect.Options
+		headers              map[string]string
+		expectedCacheControl string
+		expectedVary         string
 	}{
 		{
 			name:  "Success",
 			alias: "test-alias",
 			mockBehavior: func() {
-				storageMock.On("GetURL", ctx, "test-alias").
-					Return("https://google.com", nil).
+				storageMock.On("GetLink", anyCtx, "", "test-alias").
+					Return(postgres.Link{Alias: "test-alias", URL: "https://google.com"}, nil).
 					Once()
 			},
 			expectedCode: http.StatusFound,
 			expectedURL:  "https://google.com",
 		},
+		{
+			name:  "Default type from config",
+			alias: "seo",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "seo").
+					Return(postgres.Link{Alias: "seo", URL: "https://google.com"}, nil).
+					Once()
+			},
+			opts:                 redirect.Options{DefaultType: http.StatusMovedPermanently, PermanentMaxAge: time.Hour},
+			expectedCode:         http.StatusMovedPermanently,
+			expectedURL:          "https://google.com",
+			expectedCacheControl: "public, max-age=3600",
+		},
+		{
+			name:  "Link type overrides default",
+			alias: "api",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "api").
+					Return(postgres.Link{Alias: "api", URL: "https://google.com/v2", RedirectType: http.StatusTemporaryRedirect}, nil).
+					Once()
+			},
+			opts:         redirect.Options{DefaultType: http.StatusMovedPermanently, PermanentMaxAge: time.Hour},
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedURL:  "https://google.com/v2",
+		},
+		{
+			name:  "Permanent link type",
+			alias: "perm",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "perm").
+					Return(postgres.Link{Alias: "perm", URL: "https://google.com", RedirectType: http.StatusPermanentRedirect}, nil).
+					Once()
+			},
+			opts:                 redirect.Options{PermanentMaxAge: 24 * time.Hour},
+			expectedCode:         http.StatusPermanentRedirect,
+			expectedURL:          "https://google.com",
+			expectedCacheControl: "public, max-age=86400",
+		},
+		{
+			name:  "Passthrough path and query",
+			alias: "docs",
+			path:  "/docs/guide/intro?utm_source=mail",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "docs").
+					Return(postgres.Link{Alias: "docs", URL: "https://example.com/v2?utm_source=site", Passthrough: redirect.PassthroughReplace}, nil).
+					Once()
+			},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://example.com/v2/guide/intro?utm_source=mail",
+		},
+		{
+			name:  "UTM parameters added",
+			alias: "promo",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "promo").
+					Return(postgres.Link{Alias: "promo", URL: "https://example.com/sale?utm_source=site",
+						UTM: postgres.UTM{Source: "newsletter", Medium: "email", Campaign: "spring sale"}}, nil).
+					Once()
+			},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://example.com/sale?utm_source=site&utm_medium=email&utm_campaign=spring+sale",
+		},
+		{
+			name:  "Passthrough query overrides UTM",
+			alias: "promo-pass",
+			path:  "/promo-pass?utm_medium=sms",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "promo-pass").
+					Return(postgres.Link{Alias: "promo-pass", URL: "https://example.com/", Passthrough: redirect.PassthroughReplace,
+						UTM: postgres.UTM{Source: "newsletter", Medium: "email"}}, nil).
+					Once()
+			},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://example.com/?utm_source=newsletter&utm_medium=sms",
+		},
+		{
+			name:    "Rule target with utm",
+			alias:   "app",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"},
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "app").
+					Return(postgres.Link{Alias: "app", URL: "https://example.com/", UTM: postgres.UTM{Source: "qr"}, Rules: appRules}, nil).
+					Once()
+			},
+			opts:         redirect.Options{Geo: &geoip.Resolver{CountryHeader: "CF-IPCountry"}},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://apps.apple.com/app/id1?utm_source=qr",
+			expectedVary: "User-Agent, CF-IPCountry",
+		},
+		{
+			name:    "Rule by country is not cached publicly",
+			alias:   "app-de",
+			headers: map[string]string{"CF-IPCountry": "DE"},
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "app-de").
+					Return(postgres.Link{Alias: "app-de", URL: "https://example.com/", RedirectType: http.StatusMovedPermanently, Rules: appRules}, nil).
+					Once()
+			},
+			opts:                 redirect.Options{PermanentMaxAge: time.Hour, Geo: &geoip.Resolver{CountryHeader: "CF-IPCountry"}},
+			expectedCode:         http.StatusMovedPermanently,
+			expectedURL:          "https://example.de/",
+			expectedCacheControl: "private, max-age=3600",
+			expectedVary:         "User-Agent, CF-IPCountry",
+		},
+		{
+			name:  "No rule matched",
+			alias: "app-web",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "app-web").
+					Return(postgres.Link{Alias: "app-web", URL: "https://example.com/", Rules: appRules}, nil).
+					Once()
+			},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://example.com/",
+			expectedVary: "User-Agent",
+		},
+		{
+			name:  "Query ignored without passthrough",
+			alias: "plain",
+			path:  "/plain?utm_source=mail",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "plain").
+					Return(postgres.Link{Alias: "plain", URL: "https://example.com/"}, nil).
+					Once()
+			},
+			expectedCode: http.StatusFound,
+			expectedURL:  "https://example.com/",
+		},
+		{
+			name:  "Extra path without passthrough",
+			alias: "plain",
+			path:  "/plain/extra",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "plain").
+					Return(postgres.Link{Alias: "plain", URL: "https://example.com/"}, nil).
+					Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:  "Click limit consumed",
+			alias: "invite",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "invite").
+					Return(postgres.Link{ID: 9, Alias: "invite", URL: "https://example.com/invite", MaxClicks: 1}, nil).
+					Once()
+				storageMock.On("ConsumeClick", anyCtx, int64(9)).Return(0, nil).Once()
+			},
+			opts:                 redirect.Options{DefaultType: http.StatusMovedPermanently, PermanentMaxAge: time.Hour},
+			expectedCode:         http.StatusFound,
+			expectedURL:          "https://example.com/invite",
+			expectedCacheControl: "no-store",
+		},
+		{
+			name:  "Click limit already exhausted",
+			alias: "invite",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "invite").
+					Return(postgres.Link{ID: 9, Alias: "invite", URL: "https://example.com/invite", MaxClicks: 1, ClicksUsed: 1}, nil).
+					Once()
+			},
+			expectedCode: http.StatusGone,
+			expectedErr:  response.CodeGone,
+		},
+		{
+			name:  "Click limit exhausted by concurrent redirect",
+			alias: "invite",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "invite").
+					Return(postgres.Link{ID: 9, Alias: "invite", URL: "https://example.com/invite", MaxClicks: 3, ClicksUsed: 2}, nil).
+					Once()
+				storageMock.On("ConsumeClick", anyCtx, int64(9)).
+					Return(0, fmt.Errorf("postgres.storage.ConsumeClick: %w", storage.ErrClicksExhausted)).
+					Once()
+			},
+			expectedCode: http.StatusGone,
+			expectedErr:  response.CodeGone,
+		},
+		{
+			name:  "Scheduled link",
+			alias: "launch",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "launch").
+					Return(postgres.Link{Alias: "launch", URL: "https://example.com/launch", ActiveFrom: &tomorrow}, nil).
+					Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:  "Scheduled link with coming soon page",
+			alias: "launch",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "launch").
+					Return(postgres.Link{Alias: "launch", URL: "https://example.com/launch", ActiveFrom: &tomorrow}, nil).
+					Once()
+			},
+			opts:                 redirect.Options{ComingSoonURL: "https://example.com/soon"},
+			expectedCode:         http.StatusFound,
+			expectedURL:          "https://example.com/soon",
+			expectedCacheControl: "no-store",
+		},
+		{
+			name:  "Expired link",
+			alias: "sale",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "sale").
+					Return(postgres.Link{Alias: "sale", URL: "https://example.com/sale", ExpiresAt: &yesterday}, nil).
+					Once()
+			},
+			expectedCode: http.StatusGone,
+			expectedErr:  response.CodeGone,
+		},
+		{
+			name:  "Permanent redirect cached until expiry",
+			alias: "sale",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "sale").
+					Return(postgres.Link{Alias: "sale", URL: "https://example.com/sale", ActiveFrom: &yesterday, ExpiresAt: &soon}, nil).
+					Once()
+			},
+			opts:                 redirect.Options{DefaultType: http.StatusMovedPermanently, PermanentMaxAge: time.Hour},
+			expectedCode:         http.StatusMovedPermanently,
+			expectedURL:          "https://example.com/sale",
+			expectedCacheControl: "public, max-age=1830",
+		},
 		{
 			name:  "Empty alias",
 			alias: "",
 			mockBehavior: func() {
 				// Нет вызовов к storage при пустом алиасе
 			},
-			expectedCode: http.StatusOK,
-			expectedResp: &response.Response{
-				Status: "Error",
-				Error:  "invalid request",
-			},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
 		},
 		{
 			name:  "URL not found",
 			alias: "not-found",
 			mockBehavior: func() {
-				storageMock.On("GetURL", ctx, "not-found").
-					Return("", storage.ErrURLNotFound).
+				storageMock.On("GetLink", anyCtx, "", "not-found").
+					Return(postgres.Link{}, storage.ErrURLNotFound).
 					Once()
 			},
-			expectedCode: http.StatusOK,
-			expectedResp: &response.Response{
-				Status: "Error",
-				Error:  "not found",
-			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
 		},
 		{
 			name:  "Internal error",
 			alias: "error-case",
 			mockBehavior: func() {
-				storageMock.On("GetURL", ctx, "error-case").
-					Return("", errors.New("some db error")).
+				storageMock.On("GetLink", anyCtx, "", "error-case").
+					Return(postgres.Link{}, errors.New("some db error")).
 					Once()
 			},
-			expectedCode: http.StatusOK,
-			expectedResp: &response.Response{
-				Status: "Error",
-				Error:  "internal error",
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+		{
+			name:  "Storage timeout",
+			alias: "slow",
+			mockBehavior: func() {
+				storageMock.On("GetLink", anyCtx, "", "slow").
+					Return(blockUntilDone).
+					Once()
 			},
+			timeouts:     storage.Timeouts{Read: 10 * time.Millisecond},
+			expectedCode: http.StatusGatewayTimeout,
+			expectedErr:  response.CodeTimeout,
 		},
 	}
 
@@ -93,11 +346,18 @@ func TestRedirectHandler(t *testing.T) {
 			tt.mockBehavior()
 
 			// Создаем хендлер
-			handler := redirect.New(ctx, log, &storage.Storage{Postgres: storageMock})
+			handler := redirect.New(log, &storage.Storage{Postgres: storageMock, Timeouts: tt.timeouts}, tt.opts)
 
 			// Создаем запрос
-			req, err := http.NewRequest("GET", "/"+tt.alias, nil)
+			path := tt.path
+			if path == "" {
+				path = "/" + tt.alias
+			}
+			req, err := http.NewRequest("GET", path, nil)
 			require.NoError(t, err)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
 
 			// Добавляем alias в контекст роутера
 			rctx := chi.NewRouteContext()
@@ -117,15 +377,19 @@ func TestRedirectHandler(t *testing.T) {
 			require.Equal(t, tt.expectedCode, rr.Code)
 
 			// Для успешного редиректа проверяем Location
-			if tt.expectedCode == http.StatusFound {
+			if tt.expectedURL != "" {
 				require.Equal(t, tt.expectedURL, rr.Header().Get("Location"))
+				require.Equal(t, tt.expectedCacheControl, rr.Header().Get("Cache-Control"))
+				require.Equal(t, tt.expectedVary, rr.Header().Get("Vary"))
 			} else {
-				// Для ошибок проверяем JSON ответ
-				var resp response.Response
-				err = json.NewDecoder(rr.Body).Decode(&resp)
+				// Для ошибок проверяем problem+json
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				err = json.NewDecoder(rr.Body).Decode(&problem)
 				require.NoError(t, err)
-				require.Equal(t, tt.expectedResp.Status, resp.Status)
-				require.Equal(t, tt.expectedResp.Error, resp.Error)
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				require.Equal(t, "test-request-id", problem.RequestID)
 			}
 
 			// Проверяем что все ожидания по моку выполнены
@@ -133,3 +397,291 @@ func TestRedirectHandler(t *testing.T) {
 		})
 	}
 }
+
+// blockUntilDone имитирует долгий запрос к БД, который прерывается только отменой ctx
+func blockUntilDone(ctx context.Context, domain, alias string) (postgres.Link, error) {
+	<-ctx.Done()
+	return postgres.Link{}, fmt.Errorf("postgres.storage.GetLink: %w", ctx.Err())
+}
+
+func TestRedirectCancelledRequest(t *testing.T) {
+	storageMock := mocks.NewPostgresStorageInterface(t)
+	started := make(chan struct{})
+	storageMock.On("GetLink", mock.Anything, "", "slow").
+		Return(func(ctx context.Context, domain, alias string) (postgres.Link, error) {
+			close(started)
+			return blockUntilDone(ctx, domain, alias)
+		}).
+		Once()
+
+	handler := redirect.New(slogdiscard.NewDiscardLogger(), &storage.Storage{Postgres: storageMock}, redirect.Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("alias", "slow")
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil).
+		WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	// Клиент отключается, пока запрос к БД еще выполняется
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not stop after request was cancelled")
+	}
+}
+
+type clickRecorder struct {
+	clicks []postgres.Click
+}
+
+func (r *clickRecorder) Record(click postgres.Click) {
+	r.clicks = append(r.clicks, click)
+}
+
+func TestRedirectSplit(t *testing.T) {
+	split := &postgres.Split{
+		Sticky: postgres.StickyCookie,
+		Variants: []postgres.Variant{
+			{Name: "control", URL: "https://example.com/a", Weight: 1},
+			{Name: "new", URL: "https://example.com/b", Weight: 1},
+		},
+	}
+	storageMock := mocks.NewPostgresStorageInterface(t)
+	storageMock.On("GetLink", mock.Anything, "", "promo").
+		Return(postgres.Link{ID: 7, Alias: "promo", URL: "https://example.com/", Split: split}, nil)
+
+	recorder := &clickRecorder{}
+	handler := redirect.New(slogdiscard.NewDiscardLogger(), &storage.Storage{Postgres: storageMock},
+		redirect.Options{DefaultType: http.StatusMovedPermanently, PermanentMaxAge: time.Hour, Clicks: recorder})
+
+	serve := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("alias", "promo")
+		req := httptest.NewRequest(http.MethodGet, "/promo", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Первый переход назначает вариант и запоминает его в cookie
+	rr := serve()
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, "private, max-age=3600", rr.Header().Get("Cache-Control"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assigned := cookies[0].Value
+	require.Contains(t, []string{"control", "new"}, assigned)
+	first := rr.Header().Get("Location")
+
+	// Повторные переходы с cookie попадают в тот же вариант без новой cookie
+	for range 10 {
+		rr = serve(cookies[0])
+		require.Equal(t, first, rr.Header().Get("Location"))
+		require.Empty(t, rr.Result().Cookies())
+	}
+
+	require.Len(t, recorder.clicks, 11)
+	for _, click := range recorder.clicks {
+		require.Equal(t, int64(7), click.LinkID)
+		require.Equal(t, assigned, click.Variant)
+		require.False(t, click.ClickedAt.IsZero())
+	}
+}
+
+func TestRedirectPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	storageMock := mocks.NewPostgresStorageInterface(t)
+	storageMock.On("GetLink", mock.Anything, "", "docs").
+		Return(postgres.Link{Alias: "docs", URL: "https://example.com/docs", RedirectType: http.StatusMovedPermanently, PasswordHash: string(hash)}, nil)
+	storageMock.On("GetLink", mock.Anything, "", "open").
+		Return(postgres.Link{Alias: "open", URL: "https://example.com/"}, nil)
+
+	handler := redirect.New(slogdiscard.NewDiscardLogger(), &storage.Storage{Postgres: storageMock},
+		redirect.Options{Password: redirect.PasswordOptions{MaxAttempts: 2, AttemptWindow: time.Minute}})
+
+	serve := func(method, alias, password string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(url.Values{"password": {password}}.Encode())
+		}
+		req := httptest.NewRequest(method, "/"+alias, body)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("alias", alias)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Без cookie вместо редиректа отдается форма
+	rr := serve(http.MethodGet, "docs", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	require.Contains(t, rr.Body.String(), `name="password"`)
+	require.Empty(t, rr.Header().Get("Location"))
+
+	rr = serve(http.MethodPost, "docs", "wrong")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Contains(t, rr.Body.String(), "Wrong password.")
+	require.Empty(t, rr.Result().Cookies())
+
+	// Верный пароль: редирект после формы и cookie для следующих переходов
+	rr = serve(http.MethodPost, "docs", "s3cret")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Equal(t, "https://example.com/docs", rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.True(t, cookies[0].HttpOnly)
+
+	// Постоянный редирект заменяется временным, чтобы браузер не запомнил цель
+	rr = serve(http.MethodGet, "docs", "", cookies[0])
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "https://example.com/docs", rr.Header().Get("Location"))
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	// После лимита неверных попыток пароль не проверяется до конца окна
+	serve(http.MethodPost, "docs", "wrong")
+	rr = serve(http.MethodPost, "docs", "s3cret")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	// Ссылка без пароля не принимает POST
+	rr = serve(http.MethodPost, "open", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestRedirectPreview(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	storageMock := mocks.NewPostgresStorageInterface(t)
+	storageMock.On("GetLink", mock.Anything, "", "docs").
+		Return(postgres.Link{Alias: "docs", URL: "https://example.com/docs", Passthrough: redirect.PassthroughKeep, CreatedAt: created}, nil)
+	storageMock.On("GetLink", mock.Anything, "", "promo").
+		Return(postgres.Link{Alias: "promo", URL: "https://example.com/promo", Title: `<script>alert(1)</script>`, Interstitial: true}, nil)
+	storageMock.On("GetLink", mock.Anything, "", "mail").
+		Return(postgres.Link{Alias: "mail", URL: "mailto:team@example.com"}, nil)
+	storageMock.On("GetLink", mock.Anything, "", "once").
+		Return(postgres.Link{ID: 5, Alias: "once", URL: "https://example.com/once", MaxClicks: 1}, nil)
+	storageMock.On("ConsumeClick", mock.Anything, int64(5)).Return(0, nil).Once()
+
+	recorder := &clickRecorder{}
+	serve := func(opts redirect.Options, alias, path string) *httptest.ResponseRecorder {
+		opts.Clicks = recorder
+		handler := redirect.New(slogdiscard.NewDiscardLogger(), &storage.Storage{Postgres: storageMock}, opts)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("alias", alias)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Суффикс "+" показывает страницу вместо редиректа
+	rr := serve(redirect.Options{}, "docs+", "/docs+")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get("Location"))
+	require.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	require.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+	body := rr.Body.String()
+	require.Contains(t, body, "<title>docs</title>")
+	require.Contains(t, body, "<strong>example.com</strong>")
+	require.Contains(t, body, "Created 2024-03-01 12:30 UTC")
+	require.Contains(t, body, `<a href="https://example.com/docs" rel="noopener noreferrer nofollow">Continue</a>`)
+
+	// preview=1 не передается в цель при passthrough
+	rr = serve(redirect.Options{}, "docs", "/docs?preview=1&ref=mail")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "https://example.com/docs?ref=mail")
+	require.NotContains(t, rr.Body.String(), "preview=1")
+
+	// Явный предпросмотр не записывается как переход
+	require.Empty(t, recorder.clicks)
+
+	// Без признака предпросмотра обычный редирект
+	rr = serve(redirect.Options{}, "docs", "/docs")
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "https://example.com/docs", rr.Header().Get("Location"))
+	require.Len(t, recorder.clicks, 1)
+
+	// Ссылка с interstitial всегда показывает страницу, заголовок экранируется
+	rr = serve(redirect.Options{}, "promo", "/promo")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "&lt;script&gt;alert(1)&lt;/script&gt;")
+	require.NotContains(t, rr.Body.String(), "<script>")
+
+	// Глобальный interstitial из конфига; для не-http цели ссылки на переход нет
+	rr = serve(redirect.Options{Interstitial: true}, "mail", "/mail")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "mailto:team@example.com")
+	require.NotContains(t, rr.Body.String(), "Continue")
+	// Страница interstitial заменяет редирект и считается переходом
+	require.Len(t, recorder.clicks, 3)
+
+	// Предпросмотр одноразовой ссылки тратит переход, но в статистику не попадает
+	rr = serve(redirect.Options{}, "once+", "/once+")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Len(t, recorder.clicks, 3)
+}
+
+func TestRedirectCustomDomain(t *testing.T) {
+	storageMock := mocks.NewPostgresStorageInterface(t)
+	storageMock.On("GetLink", mock.Anything, "", "x").
+		Return(postgres.Link{Alias: "x", URL: "https://example.com/main"}, nil)
+	storageMock.On("GetLink", mock.Anything, "go.brand-a.com", "x").
+		Return(postgres.Link{Alias: "x", Domain: "go.brand-a.com", URL: "https://brand-a.com/"}, nil)
+	storageMock.On("GetLink", mock.Anything, "unknown.com", "x").
+		Return(postgres.Link{}, storage.ErrURLNotFound)
+
+	tests := []struct {
+		name          string
+		defaultDomain string
+		host          string
+		expectedCode  int
+		expectedURL   string
+	}{
+		{name: "Host ignored without default domain", host: "go.brand-a.com", expectedCode: http.StatusFound, expectedURL: "https://example.com/main"},
+		{name: "Default domain", defaultDomain: "sho.rt", host: "sho.rt", expectedCode: http.StatusFound, expectedURL: "https://example.com/main"},
+		{name: "Default domain with port and case", defaultDomain: "sho.rt", host: "SHO.RT:8080", expectedCode: http.StatusFound, expectedURL: "https://example.com/main"},
+		{name: "Brand domain", defaultDomain: "sho.rt", host: "go.brand-a.com", expectedCode: http.StatusFound, expectedURL: "https://brand-a.com/"},
+		{name: "Unknown host", defaultDomain: "sho.rt", host: "unknown.com", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), &storage.Storage{Postgres: storageMock}, redirect.Options{DefaultDomain: tt.defaultDomain})
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			req.Host = tt.host
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", "x")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			require.Equal(t, tt.expectedURL, rr.Header().Get("Location"))
+		})
+	}
+}
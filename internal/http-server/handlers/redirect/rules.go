@@ -0,0 +1,157 @@
+package redirect
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/storage/postgres"
+)
+
+// Устройства в RedirectRule.Device
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	// DeviceMobile - любой телефон или планшет, включая iOS и Android
+	DeviceMobile  = "mobile"
+	DeviceDesktop = "desktop"
+)
+
+// client лениво вычисляет признаки запроса: страна нужна только правилам со странами,
+// а поиск в базе дороже разбора заголовков
+type client struct {
+	r   *http.Request
+	geo *geoip.Resolver
+
+	countryDone bool
+	country     string
+	countryErr  error
+}
+
+func (c *client) deviceMatches(device string) bool {
+	ua := c.r.UserAgent()
+	// iPadOS по умолчанию представляется как Macintosh и попадает в desktop
+	ios := strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod")
+	android := strings.Contains(ua, "Android")
+	mobile := ios || android || strings.Contains(ua, "Mobi")
+
+	switch device {
+	case DeviceIOS:
+		return ios
+	case DeviceAndroid:
+		return android
+	case DeviceMobile:
+		return mobile
+	case DeviceDesktop:
+		return !mobile
+	}
+	return false
+}
+
+// languageMatches сравнивает языки правила с самым предпочтительным языком клиента
+func (c *client) languageMatches(languages []string) bool {
+	preferred := preferredLanguage(c.r.Header.Get("Accept-Language"))
+	if preferred == "" {
+		return false
+	}
+	for _, lang := range languages {
+		if strings.EqualFold(preferred, lang) ||
+			len(preferred) > len(lang) && preferred[len(lang)] == '-' && strings.EqualFold(preferred[:len(lang)], lang) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) countryMatches(countries []string) bool {
+	if !c.countryDone {
+		c.countryDone = true
+		c.country, c.countryErr = c.geo.Country(c.r)
+	}
+	if c.country == "" {
+		return false
+	}
+	for _, country := range countries {
+		if strings.EqualFold(country, c.country) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) matches(rule postgres.RedirectRule) bool {
+	return (rule.Device == "" || c.deviceMatches(rule.Device)) &&
+		(len(rule.Languages) == 0 || c.languageMatches(rule.Languages)) &&
+		(len(rule.Countries) == 0 || c.countryMatches(rule.Countries))
+}
+
+// matchRule возвращает номер первого подходящего правила или -1, если цель - URL ссылки
+func (c *client) matchRule(rules []postgres.RedirectRule) int {
+	for i, rule := range rules {
+		if c.matches(rule) {
+			return i
+		}
+	}
+	return -1
+}
+
+// preferredLanguage возвращает язык с наибольшим q из Accept-Language. Языки с q=0 и "*" пропускаются.
+func preferredLanguage(header string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q > 0 {
+			langs = append(langs, weighted{tag: tag, q: q})
+		}
+	}
+	if len(langs) == 0 {
+		return ""
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	return langs[0].tag
+}
+
+// varyHeaders - заголовки запроса, от которых зависит выбор цели. Нужны в Vary,
+// чтобы кеши не отдавали редирект для iOS клиентам на Android.
+func varyHeaders(rules []postgres.RedirectRule, geo *geoip.Resolver) []string {
+	var device, language, country bool
+	for _, rule := range rules {
+		device = device || rule.Device != ""
+		language = language || len(rule.Languages) > 0
+		country = country || len(rule.Countries) > 0
+	}
+	var headers []string
+	if device {
+		headers = append(headers, "User-Agent")
+	}
+	if language {
+		headers = append(headers, "Accept-Language")
+	}
+	if country && geo != nil && geo.CountryHeader != "" {
+		headers = append(headers, geo.CountryHeader)
+	}
+	if country && geo != nil && geo.IPHeader != "" {
+		headers = append(headers, geo.IPHeader)
+	}
+	return headers
+}
@@ -0,0 +1,79 @@
+package redirect
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/storage/postgres"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{header: "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", expected: "ru-RU"},
+		{header: "en;q=0.5, de;q=0.9", expected: "de"},
+		{header: "fr;q=0, *;q=0.5, it;q=0.1", expected: "it"},
+		{header: "es, pt", expected: "es"},
+		{header: "de;q=abc", expected: ""},
+		{header: "", expected: ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, preferredLanguage(tt.header), tt.header)
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	appStore := postgres.RedirectRule{Device: DeviceIOS, Target: "https://apps.apple.com/app"}
+	play := postgres.RedirectRule{Device: DeviceAndroid, Target: "https://play.google.com/app"}
+	germanDesktop := postgres.RedirectRule{Device: DeviceDesktop, Languages: []string{"de"}, Target: "https://example.de"}
+	nordics := postgres.RedirectRule{Countries: []string{"SE", "NO"}, Target: "https://example.se"}
+	rules := []postgres.RedirectRule{appStore, play, germanDesktop, nordics}
+
+	tests := []struct {
+		name     string
+		ua       string
+		language string
+		country  string
+		expected int
+	}{
+		{name: "iPhone", ua: iPhoneUA, expected: 0},
+		{name: "Android", ua: androidUA, expected: 1},
+		{name: "German desktop", ua: desktopUA, language: "de-AT,en;q=0.5", expected: 2},
+		{name: "German is not preferred", ua: desktopUA, language: "en,de;q=0.9", expected: -1},
+		{name: "First matching rule wins", ua: iPhoneUA, country: "SE", expected: 0},
+		{name: "Country from trusted header", ua: desktopUA, country: "no", expected: 3},
+		{name: "Default target", ua: desktopUA, country: "US", expected: -1},
+	}
+
+	geo := &geoip.Resolver{CountryHeader: "CF-IPCountry"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/promo", nil)
+			r.Header.Set("User-Agent", tt.ua)
+			r.Header.Set("Accept-Language", tt.language)
+			r.Header.Set("CF-IPCountry", tt.country)
+
+			c := client{r: r, geo: geo}
+			require.Equal(t, tt.expected, c.matchRule(rules))
+		})
+	}
+}
+
+func TestMatchRuleWithoutGeo(t *testing.T) {
+	r := httptest.NewRequest("GET", "/promo", nil)
+	r.Header.Set("CF-IPCountry", "SE")
+
+	c := client{r: r}
+	require.Equal(t, -1, c.matchRule([]postgres.RedirectRule{{Countries: []string{"SE"}, Target: "https://example.se"}}))
+}
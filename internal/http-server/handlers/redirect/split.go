@@ -0,0 +1,79 @@
+package redirect
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/storage/postgres"
+)
+
+// splitCookieMaxAge - сколько клиент остается в своем варианте при StickyCookie
+const splitCookieMaxAge = 30 * 24 * time.Hour
+
+// aliasCookieName не содержит сам алиас: в алиасе могут быть символы, недопустимые в имени cookie
+func aliasCookieName(prefix, alias string) string {
+	h := fnv.New32a()
+	h.Write([]byte(alias))
+	return prefix + strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+// pickVariant выбирает вариант A/B-теста. cookie не nil, если вариант нужно запомнить у клиента.
+// ok=false, если у всех вариантов нулевой вес.
+func (c *client) pickVariant(alias string, split *postgres.Split) (variant postgres.Variant, cookie *http.Cookie, ok bool) {
+	total := 0
+	for _, v := range split.Variants {
+		total += max(v.Weight, 0)
+	}
+	if total == 0 {
+		return postgres.Variant{}, nil, false
+	}
+
+	switch split.Sticky {
+	case postgres.StickyCookie:
+		name := aliasCookieName("ab_", alias)
+		if saved, err := c.r.Cookie(name); err == nil {
+			// Вариант из cookie мог быть удален из теста или выключен нулевым весом
+			for _, v := range split.Variants {
+				if v.Name == saved.Value && v.Weight > 0 {
+					return v, nil, true
+				}
+			}
+		}
+		variant = weightedVariant(split.Variants, rand.IntN(total))
+		return variant, &http.Cookie{
+			Name:     name,
+			Value:    variant.Name,
+			Path:     "/",
+			MaxAge:   int(splitCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		}, true
+	case postgres.StickyHash:
+		h := fnv.New64a()
+		h.Write([]byte(alias))
+		h.Write([]byte{0})
+		if ip, ok := c.geo.ClientIP(c.r); ok {
+			h.Write(ip.AsSlice())
+		}
+		h.Write([]byte{0})
+		h.Write([]byte(c.r.UserAgent()))
+		return weightedVariant(split.Variants, int(h.Sum64()%uint64(total))), nil, true
+	}
+	return weightedVariant(split.Variants, rand.IntN(total)), nil, true
+}
+
+// weightedVariant возвращает вариант, в чей диапазон весов попадает point из [0, сумма весов)
+func weightedVariant(variants []postgres.Variant, point int) postgres.Variant {
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < v.Weight {
+			return v
+		}
+		point -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,93 @@
+package redirect
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestWeightedVariant(t *testing.T) {
+	variants := []postgres.Variant{
+		{Name: "a", Weight: 3},
+		{Name: "off", Weight: 0},
+		{Name: "b", Weight: 1},
+	}
+	counts := map[string]int{}
+	for point := range 4 {
+		counts[weightedVariant(variants, point).Name]++
+	}
+	require.Equal(t, map[string]int{"a": 3, "b": 1}, counts)
+}
+
+func TestPickVariant(t *testing.T) {
+	variants := []postgres.Variant{
+		{Name: "control", URL: "https://example.com/a", Weight: 50},
+		{Name: "new", URL: "https://example.com/b", Weight: 50},
+		{Name: "paused", URL: "https://example.com/c", Weight: 0},
+	}
+
+	t.Run("Cookie keeps assigned variant", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/promo", nil)
+		r.Header.Set("Cookie", aliasCookieName("ab_", "promo")+"=new")
+		c := client{r: r}
+
+		for range 20 {
+			v, cookie, ok := c.pickVariant("promo", &postgres.Split{Sticky: postgres.StickyCookie, Variants: variants})
+			require.True(t, ok)
+			require.Equal(t, "new", v.Name)
+			require.Nil(t, cookie)
+		}
+	})
+
+	t.Run("Cookie with paused variant is reassigned", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/promo", nil)
+		r.Header.Set("Cookie", aliasCookieName("ab_", "promo")+"=paused")
+		c := client{r: r}
+
+		v, cookie, ok := c.pickVariant("promo", &postgres.Split{Sticky: postgres.StickyCookie, Variants: variants})
+		require.True(t, ok)
+		require.NotEqual(t, "paused", v.Name)
+		require.NotNil(t, cookie)
+		require.Equal(t, aliasCookieName("ab_", "promo"), cookie.Name)
+		require.Equal(t, v.Name, cookie.Value)
+		require.True(t, cookie.HttpOnly)
+	})
+
+	t.Run("Hash is stable for a client", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/promo", nil)
+		r.RemoteAddr = "203.0.113.7:4000"
+		r.Header.Set("User-Agent", "test-agent")
+		c := client{r: r}
+
+		first, cookie, ok := c.pickVariant("promo", &postgres.Split{Sticky: postgres.StickyHash, Variants: variants})
+		require.True(t, ok)
+		require.Nil(t, cookie)
+		for range 20 {
+			v, _, _ := c.pickVariant("promo", &postgres.Split{Sticky: postgres.StickyHash, Variants: variants})
+			require.Equal(t, first.Name, v.Name)
+		}
+	})
+
+	t.Run("Hash spreads clients across variants", func(t *testing.T) {
+		seen := map[string]bool{}
+		for i := range 64 {
+			r := httptest.NewRequest("GET", "/promo", nil)
+			r.RemoteAddr = fmt.Sprintf("203.0.113.%d:4000", i)
+			r.Header.Set("User-Agent", fmt.Sprintf("agent-%d", i))
+			c := client{r: r}
+			v, _, _ := c.pickVariant("promo", &postgres.Split{Sticky: postgres.StickyHash, Variants: variants})
+			seen[v.Name] = true
+		}
+		require.Equal(t, map[string]bool{"control": true, "new": true}, seen)
+	})
+
+	t.Run("All weights zero", func(t *testing.T) {
+		c := client{r: httptest.NewRequest("GET", "/promo", nil)}
+		_, _, ok := c.pickVariant("promo", &postgres.Split{Variants: []postgres.Variant{{Name: "a", URL: "https://a.com"}}})
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,39 @@
+package redirect
+
+import (
+	"net/url"
+	"strings"
+	"url-shortener/internal/storage/postgres"
+)
+
+// utmTarget добавляет utm-метки ссылки к сохраненному URL. Метки, которые
+// уже явно указаны в сохраненном URL, не заменяются.
+func utmTarget(stored string, utm postgres.UTM) (string, error) {
+	if utm.IsEmpty() {
+		return stored, nil
+	}
+	u, err := url.Parse(stored)
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = mergeQuery(u.RawQuery, utmQuery(utm), PassthroughKeep)
+	return u.String(), nil
+}
+
+// utmQuery кодирует непустые метки в стандартном порядке
+func utmQuery(utm postgres.UTM) string {
+	params := []struct{ key, value string }{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_content", utm.Content},
+		{"utm_term", utm.Term},
+	}
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value != "" {
+			pairs = append(pairs, p.key+"="+url.QueryEscape(p.value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,198 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/links"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	ModeAtomic     = "atomic"
+	ModeBestEffort = "best_effort"
+)
+
+type Request struct {
+	Items []links.Request `json:"items"`
+	// Mode: atomic - все или ничего, best_effort - сохранить все, что возможно
+	Mode string `json:"mode,omitempty"`
+}
+
+type ItemResult struct {
+	resp.Response
+	// Code - машиночитаемая причина ошибки элемента, те же коды, что в problem+json
+	Code     string `json:"code,omitempty"`
+	Index    int    `json:"index"`
+	Id       int64  `json:"id,omitempty"`
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Results []ItemResult `json:"results,omitempty"`
+}
+
+func New(log *slog.Logger, service service.ServiceInterface, shortURLs shorturl.Builder, maxItems int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.batch.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+
+		if req.Mode == "" {
+			req.Mode = ModeBestEffort
+		}
+		if req.Mode != ModeAtomic && req.Mode != ModeBestEffort {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, fmt.Sprintf("mode must be %s or %s", ModeAtomic, ModeBestEffort))
+			return
+		}
+		if len(req.Items) == 0 {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "items must not be empty")
+			return
+		}
+		if len(req.Items) > maxItems {
+			resp.WriteError(w, r, http.StatusRequestEntityTooLarge, resp.CodeTooManyItems, fmt.Sprintf("too many items, max %d", maxItems))
+			return
+		}
+
+		log.Info("batch request decoded", slog.Int("items", len(req.Items)), slog.String("mode", req.Mode))
+
+		results := make([]ItemResult, len(req.Items))
+		pending := make([]postgres.Link, 0, len(req.Items))
+		// positions[i] - индекс в req.Items для pending[i]
+		positions := make([]int, 0, len(req.Items))
+		templates := &templateCache{templates: service, loaded: make(map[[2]string]templateResult)}
+		invalid := 0
+		now := time.Now()
+
+		for i, item := range req.Items {
+			results[i].Index = i
+			if err := item.Validate(now); err != nil {
+				results[i].Response = resp.Error(err.Error())
+				results[i].Code = resp.CodeValidationFailed
+				invalid++
+				continue
+			}
+			if err := item.HashPassword(); err != nil {
+				log.Error("failed to hash password", slogger.Err(err))
+				resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to hash password")
+				return
+			}
+			if err := item.ResolveUTM(r.Context(), templates); err != nil {
+				detail, ok := links.ClientError(err)
+				if !ok {
+					log.Error("failed to get utm template", slogger.Err(err))
+					resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to get utm template")
+					return
+				}
+				results[i].Response = resp.Error(detail)
+				results[i].Code = resp.CodeValidationFailed
+				invalid++
+				continue
+			}
+			// Случайный алиас подбирает сервис, занятый он генерирует заново
+			results[i].Alias = item.Alias
+			pending = append(pending, item.Link(item.Alias))
+			positions = append(positions, i)
+		}
+
+		if invalid > 0 && req.Mode == ModeAtomic {
+			for _, pos := range positions {
+				results[pos].Response = resp.Error(storage.ErrBatchAborted.Error())
+				results[pos].Code = resp.CodeBatchRolledBack
+			}
+			log.Info("atomic batch rejected", slog.Int("invalid", invalid))
+			resp.WriteErrorExt(w, r, http.StatusBadRequest, resp.CodeBatchRejected, "batch contains invalid items", map[string]any{"results": results})
+			return
+		}
+
+		if len(pending) > 0 {
+			saved, err := service.SaveURLs(r.Context(), pending, req.Mode == ModeAtomic)
+			if detail, ok := links.ClientError(err); ok {
+				log.Info("batch rejected", slogger.Err(err))
+				resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, detail)
+				return
+			}
+			if err != nil {
+				log.Error("failed to save urls", slogger.Err(err))
+				resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to add urls")
+				return
+			}
+
+			for j, res := range saved {
+				pos := positions[j]
+				switch {
+				case res.Err == nil:
+					results[pos].Response = resp.OK()
+					results[pos].Id = res.ID
+					results[pos].Alias = res.Alias
+					results[pos].ShortURL = shortURLs.URL(r, pending[j].Domain, res.Alias)
+				case errors.Is(res.Err, storage.ErrURLExists):
+					results[pos].Response = resp.Error("url or alias already exists")
+					results[pos].Code = resp.CodeAlreadyExists
+					invalid++
+				case errors.Is(res.Err, storage.ErrDomainNotFound):
+					results[pos].Response = resp.Error("unknown domain")
+					results[pos].Code = resp.CodeValidationFailed
+					invalid++
+				case errors.Is(res.Err, storage.ErrBatchAborted):
+					results[pos].Response = resp.Error(res.Err.Error())
+					results[pos].Code = resp.CodeBatchRolledBack
+					invalid++
+				default:
+					results[pos].Response = resp.Error(res.Err.Error())
+					results[pos].Code = resp.CodeInternal
+					invalid++
+				}
+			}
+		}
+
+		log.Info("batch processed", slog.Int("items", len(req.Items)), slog.Int("failed", invalid))
+
+		if invalid > 0 && req.Mode == ModeAtomic {
+			resp.WriteErrorExt(w, r, http.StatusConflict, resp.CodeBatchRolledBack, "batch rolled back", map[string]any{"results": results})
+			return
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Results: results})
+	}
+}
+
+type templateResult struct {
+	template postgres.UTMTemplate
+	err      error
+}
+
+// templateCache запрашивает каждый шаблон utm-меток один раз на пачку
+type templateCache struct {
+	templates links.UTMTemplates
+	loaded    map[[2]string]templateResult
+}
+
+func (c *templateCache) GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error) {
+	key := [2]string{owner, name}
+	if res, ok := c.loaded[key]; ok {
+		return res.template, res.err
+	}
+	template, err := c.templates.GetUTMTemplate(ctx, owner, name)
+	c.loaded[key] = templateResult{template: template, err: err}
+	return template, err
+}
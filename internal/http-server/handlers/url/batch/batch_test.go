@@ -0,0 +1,201 @@
+package batch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/batch"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestBatchHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputBody     string
+		mockBehavior  func(s *mocks.ServiceInterface)
+		expectedCode  int
+		expectedErr   string
+		expectedItems []string
+	}{
+		{
+			name:      "Best effort with partial conflict",
+			inputBody: `{"items": [{"url": "https://a.com", "alias": "a"}, {"url": "https://b.com", "alias": "b"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, []postgres.Link{
+					{URL: "https://a.com", Alias: "a"},
+					{URL: "https://b.com", Alias: "b"},
+				}, false).Return([]postgres.SaveResult{{ID: 1, Alias: "a"}, {Alias: "b", Err: storage.ErrURLExists}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"OK", "Error"},
+		},
+		{
+			name:      "Best effort skips invalid items",
+			inputBody: `{"items": [{"url": "not-a-url", "alias": "a"}, {"url": "https://b.com", "alias": "b"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, []postgres.Link{
+					{URL: "https://b.com", Alias: "b"},
+				}, false).Return([]postgres.SaveResult{{ID: 2, Alias: "b"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"Error", "OK"},
+		},
+		{
+			name:      "Alias generated by service",
+			inputBody: `{"items": [{"url": "https://a.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, []postgres.Link{{URL: "https://a.com"}}, false).
+					Return([]postgres.SaveResult{{ID: 1, Alias: "x7Kq2p"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"OK"},
+		},
+		{
+			name: "Utm template is loaded once per batch",
+			inputBody: `{"items": [{"url": "https://a.com", "alias": "a", "owner": "o", "utm_template": "mail"},
+				{"url": "https://b.com", "alias": "b", "owner": "o", "utm_template": "mail"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				utm := postgres.UTM{Source: "newsletter"}
+				s.On("GetUTMTemplate", mock.Anything, "o", "mail").
+					Return(postgres.UTMTemplate{Owner: "o", Name: "mail", UTM: utm}, nil).
+					Once()
+				s.On("SaveURLs", mock.Anything, []postgres.Link{
+					{URL: "https://a.com", Alias: "a", Owner: "o", UTM: utm},
+					{URL: "https://b.com", Alias: "b", Owner: "o", UTM: utm},
+				}, false).Return([]postgres.SaveResult{{ID: 1, Alias: "a"}, {ID: 2, Alias: "b"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"OK", "OK"},
+		},
+		{
+			name:      "Unknown utm template fails only its item",
+			inputBody: `{"items": [{"url": "https://a.com", "alias": "a", "owner": "o", "utm_template": "missing"}, {"url": "https://b.com", "alias": "b"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetUTMTemplate", mock.Anything, "o", "missing").
+					Return(postgres.UTMTemplate{}, storage.ErrUTMTemplateNotFound)
+				s.On("SaveURLs", mock.Anything, []postgres.Link{
+					{URL: "https://b.com", Alias: "b"},
+				}, false).Return([]postgres.SaveResult{{ID: 2, Alias: "b"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedItems: []string{"Error", "OK"},
+		},
+		{
+			name:          "Atomic rejects invalid items without saving",
+			inputBody:     `{"mode": "atomic", "items": [{"url": "", "alias": "a"}, {"url": "https://b.com", "alias": "b"}]}`,
+			mockBehavior:  func(s *mocks.ServiceInterface) {},
+			expectedCode:  http.StatusBadRequest,
+			expectedErr:   response.CodeBatchRejected,
+			expectedItems: []string{"Error", "Error"},
+		},
+		{
+			name:      "Atomic rolled back on conflict",
+			inputBody: `{"mode": "atomic", "items": [{"url": "https://a.com", "alias": "a"}, {"url": "https://b.com", "alias": "b"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, mock.Anything, true).
+					Return([]postgres.SaveResult{{Err: storage.ErrBatchAborted}, {Err: storage.ErrURLExists}}, nil)
+			},
+			expectedCode:  http.StatusConflict,
+			expectedErr:   response.CodeBatchRolledBack,
+			expectedItems: []string{"Error", "Error"},
+		},
+		{
+			name:         "Too many items",
+			inputBody:    `{"items": [{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedErr:  response.CodeTooManyItems,
+		},
+		{
+			name:         "Unknown mode",
+			inputBody:    `{"mode": "sometimes", "items": [{"url": "https://a.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+		{
+			name:      "Brand domains disabled",
+			inputBody: `{"items": [{"url": "https://a.com", "alias": "a", "domain": "go.brand-a.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, mock.Anything, false).Return(nil, links.ErrDomainsDisabled)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name:      "Storage error",
+			inputBody: `{"items": [{"url": "https://a.com", "alias": "a"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveURLs", mock.Anything, mock.Anything, false).Return(nil, errors.New("db error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	shortURLs, err := shorturl.New("https://sho.rt")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			handler := batch.New(slogdiscard.NewDiscardLogger(), serviceMock, shortURLs, 2)
+
+			req, err := http.NewRequest(http.MethodPost, "/url/batch", bytes.NewBufferString(tt.inputBody))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			var results []batch.ItemResult
+			if tt.expectedErr != "" {
+				// Результаты по элементам приходят полем results в problem+json
+				var problem struct {
+					response.Problem
+					Results []batch.ItemResult `json:"results"`
+				}
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				results = problem.Results
+			} else {
+				var resp batch.Response
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+				require.Equal(t, "OK", resp.Status)
+				results = resp.Results
+			}
+
+			if tt.expectedItems != nil {
+				require.Len(t, results, len(tt.expectedItems))
+				for i, status := range tt.expectedItems {
+					require.Equal(t, i, results[i].Index)
+					require.Equal(t, status, results[i].Status)
+					if status == "OK" {
+						require.NotEmpty(t, results[i].Alias)
+						require.Equal(t, "https://sho.rt/"+results[i].Alias, results[i].ShortURL)
+					} else {
+						require.Empty(t, results[i].ShortURL)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package batchdelete
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	OutcomeDeleted     = "deleted"
+	OutcomeWouldDelete = "would_delete"
+	OutcomeNotFound    = "not_found"
+)
+
+// Request выбирает ссылки списком алиасов и/или фильтром.
+// Все заданные условия объединяются через AND. Алиасы ищутся на домене Domain,
+// пустой Domain - основной домен.
+type Request struct {
+	Aliases       []string   `json:"aliases,omitempty"`
+	Domain        string     `json:"domain,omitempty"`
+	Owner         string     `json:"owner,omitempty"`
+	Tag           string     `json:"tag,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	DryRun        bool       `json:"dry_run,omitempty"`
+}
+
+type Result struct {
+	Alias   string `json:"alias"`
+	Outcome string `json:"outcome"`
+}
+
+type Response struct {
+	resp.Response
+	DryRun  bool     `json:"dry_run"`
+	Count   int      `json:"count"`
+	Results []Result `json:"results"`
+}
+
+func (req Request) Filter() postgres.Filter {
+	filter := postgres.Filter{
+		Aliases: req.Aliases,
+		Domain:  domain.Normalize(req.Domain),
+		Owner:   req.Owner,
+		Tag:     req.Tag,
+	}
+	if req.CreatedBefore != nil {
+		filter.CreatedBefore = *req.CreatedBefore
+	}
+	return filter
+}
+
+func New(log *slog.Logger, service service.ServiceInterface, maxAliases int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.batchdelete.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+
+		filter := req.Filter()
+		// Пустой фильтр удалил бы все ссылки, поэтому такой запрос отклоняется
+		if filter.IsEmpty() {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeEmptyFilter, "aliases or filter required")
+			return
+		}
+		if len(req.Aliases) > maxAliases {
+			resp.WriteError(w, r, http.StatusRequestEntityTooLarge, resp.CodeTooManyItems, fmt.Sprintf("too many aliases, max %d", maxAliases))
+			return
+		}
+
+		deleted, err := service.DeleteURLs(r.Context(), filter, req.DryRun)
+		if err != nil {
+			log.Error("failed to delete urls", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		outcome := OutcomeDeleted
+		if req.DryRun {
+			outcome = OutcomeWouldDelete
+		}
+
+		results := make([]Result, 0, len(deleted)+len(req.Aliases))
+		matched := make(map[string]bool, len(deleted))
+		for _, alias := range deleted {
+			matched[alias] = true
+			results = append(results, Result{Alias: alias, Outcome: outcome})
+		}
+		// Алиасы из запроса, которые не попали под удаление, отчитываются отдельно
+		for _, alias := range req.Aliases {
+			if !matched[alias] {
+				matched[alias] = true
+				results = append(results, Result{Alias: alias, Outcome: OutcomeNotFound})
+			}
+		}
+
+		log.Info("bulk delete processed", slog.Int("count", len(deleted)), slog.Bool("dry_run", req.DryRun))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			DryRun:   req.DryRun,
+			Count:    len(deleted),
+			Results:  results,
+		})
+	}
+}
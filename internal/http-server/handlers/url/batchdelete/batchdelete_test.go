@@ -0,0 +1,115 @@
+package batchdelete_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/batchdelete"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestBatchDeleteHandler(t *testing.T) {
+	createdBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		inputBody       string
+		mockBehavior    func(s *mocks.ServiceInterface)
+		expectedCode    int
+		expectedErr     string
+		expectedResults []batchdelete.Result
+	}{
+		{
+			name:      "Aliases with missing one",
+			inputBody: `{"aliases": ["a", "b", "c"]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("DeleteURLs", mock.Anything, postgres.Filter{Aliases: []string{"a", "b", "c"}}, false).
+					Return([]string{"a", "c"}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedResults: []batchdelete.Result{
+				{Alias: "a", Outcome: batchdelete.OutcomeDeleted},
+				{Alias: "c", Outcome: batchdelete.OutcomeDeleted},
+				{Alias: "b", Outcome: batchdelete.OutcomeNotFound},
+			},
+		},
+		{
+			name:      "Dry run by filter",
+			inputBody: `{"owner": "marketing", "tag": "spring", "created_before": "2025-01-01T00:00:00Z", "dry_run": true}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("DeleteURLs", mock.Anything, postgres.Filter{Owner: "marketing", Tag: "spring", CreatedBefore: createdBefore}, true).
+					Return([]string{"x"}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedResults: []batchdelete.Result{
+				{Alias: "x", Outcome: batchdelete.OutcomeWouldDelete},
+			},
+		},
+		{
+			name:         "Empty filter",
+			inputBody:    `{"dry_run": true}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeEmptyFilter,
+		},
+		{
+			name:         "Too many aliases",
+			inputBody:    `{"aliases": ["a", "b", "c", "d"]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedErr:  response.CodeTooManyItems,
+		},
+		{
+			name:      "Storage error",
+			inputBody: `{"tag": "old"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("DeleteURLs", mock.Anything, postgres.Filter{Tag: "old"}, false).
+					Return(nil, errors.New("db error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			handler := batchdelete.New(slogdiscard.NewDiscardLogger(), serviceMock, 3)
+
+			req, err := http.NewRequest(http.MethodPost, "/url/batch/delete", bytes.NewBufferString(tt.inputBody))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				return
+			}
+
+			var resp batchdelete.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "OK", resp.Status)
+			require.Equal(t, tt.expectedResults, resp.Results)
+		})
+	}
+}
@@ -2,18 +2,21 @@ package delete
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
 	slogger "url-shortener/internal/lib/logger/slog"
-	"url-shortener/internal/storage"
+	"url-shortener/internal/lib/tracing"
+	storages "url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 )
 
-func New(ctx context.Context, log *slog.Logger, storage *storage.Storage) http.HandlerFunc {
+func New(log *slog.Logger, storage *storages.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
 		log := log.With(
@@ -21,18 +24,39 @@ func New(ctx context.Context, log *slog.Logger, storage *storage.Storage) http.H
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
+		ctx, span := tracing.Start(r.Context(), op)
+		defer span.End()
+
 		alias := chi.URLParam(r, "alias")
 
 		if alias == "" {
 			log.Info("empty alias")
-			render.JSON(w, r, response.Error("empty alias"))
+			response.WriteError(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "empty alias")
 			return
 		}
 
-		err := storage.DeleteURl(ctx, alias)
+		span.SetAttributes(tracing.String("alias", alias))
+
+		err := storage.DeleteURl(ctx, domain.FromQuery(r), alias)
+		if errors.Is(err, storages.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			response.WriteError(w, r, http.StatusNotFound, response.CodeNotFound, "not found")
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Info("request cancelled", slog.String("alias", alias))
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Warn("storage timeout", slog.String("alias", alias))
+			span.RecordError(err)
+			response.WriteError(w, r, http.StatusGatewayTimeout, response.CodeTimeout, "storage timeout")
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete url", slogger.Err(err), slog.String("alias", alias))
-			render.JSON(w, r, response.Error("internal error"))
+			span.RecordError(err)
+			response.WriteError(w, r, http.StatusInternalServerError, response.CodeInternal, "internal error")
 			return
 		}
 
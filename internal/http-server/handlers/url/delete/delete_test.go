@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	testifymock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
@@ -26,7 +28,8 @@ type TestStorageWrapper struct {
 
 func TestDeleteHandler(t *testing.T) {
 	log := slogdiscard.NewDiscardLogger()
-	ctx := context.Background()
+	// Обработчик передает в хранилище контекст запроса со спаном и таймаутом, поэтому сам ctx не сравнивается
+	anyCtx := testifymock.Anything
 
 	tests := []struct {
 		name          string
@@ -34,12 +37,13 @@ func TestDeleteHandler(t *testing.T) {
 		mockBehavior  func(mock *mocks.PostgresStorageInterface)
 		expectedCode  int
 		expectedError string
+		timeouts      storage.Timeouts
 	}{
 		{
 			name:  "Success",
 			alias: "test-alias",
 			mockBehavior: func(mock *mocks.PostgresStorageInterface) {
-				mock.On("DeleteURl", ctx, "test-alias").
+				mock.On("DeleteURl", anyCtx, "", "test-alias").
 					Return(nil).
 					Once()
 			},
@@ -51,30 +55,45 @@ func TestDeleteHandler(t *testing.T) {
 			mockBehavior: func(mock *mocks.PostgresStorageInterface) {
 				// Нет вызовов к storage при пустом алиасе
 			},
-			expectedCode:  http.StatusOK,
-			expectedError: "empty alias",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidParameter,
 		},
 		{
 			name:  "URL not found",
 			alias: "not-found",
 			mockBehavior: func(mock *mocks.PostgresStorageInterface) {
-				mock.On("DeleteURl", ctx, "not-found").
+				mock.On("DeleteURl", anyCtx, "", "not-found").
 					Return(storage.ErrURLNotFound).
 					Once()
 			},
-			expectedCode:  http.StatusOK,
-			expectedError: "internal error",
+			expectedCode:  http.StatusNotFound,
+			expectedError: response.CodeNotFound,
 		},
 		{
 			name:  "Internal error",
 			alias: "error-case",
 			mockBehavior: func(mock *mocks.PostgresStorageInterface) {
-				mock.On("DeleteURl", ctx, "error-case").
+				mock.On("DeleteURl", anyCtx, "", "error-case").
 					Return(errors.New("some db error")).
 					Once()
 			},
-			expectedCode:  http.StatusOK,
-			expectedError: "internal error",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+		},
+		{
+			name:  "Storage timeout",
+			alias: "slow",
+			mockBehavior: func(mock *mocks.PostgresStorageInterface) {
+				mock.On("DeleteURl", anyCtx, "", "slow").
+					Return(func(ctx context.Context, domain, alias string) error {
+						<-ctx.Done()
+						return ctx.Err()
+					}).
+					Once()
+			},
+			timeouts:      storage.Timeouts{Write: 10 * time.Millisecond},
+			expectedCode:  http.StatusGatewayTimeout,
+			expectedError: response.CodeTimeout,
 		},
 	}
 
@@ -87,7 +106,7 @@ func TestDeleteHandler(t *testing.T) {
 			tt.mockBehavior(storageMock)
 
 			// Создаем хендлер с моком storage
-			handler := delete.New(ctx, log, &storage.Storage{Postgres: storageMock})
+			handler := delete.New(log, &storage.Storage{Postgres: storageMock, Timeouts: tt.timeouts})
 
 			// Создаем тестовый запрос
 			req, err := http.NewRequest(http.MethodDelete, "/"+tt.alias, nil)
@@ -110,18 +129,20 @@ func TestDeleteHandler(t *testing.T) {
 			// Проверяем статус код
 			require.Equal(t, tt.expectedCode, rr.Code)
 
-			// Декодируем ответ
-			var resp response.Response
-			err = json.NewDecoder(rr.Body).Decode(&resp)
-			require.NoError(t, err)
-
 			// Проверяем ответ
 			if tt.expectedError == "" {
+				var resp response.Response
+				err = json.NewDecoder(rr.Body).Decode(&resp)
+				require.NoError(t, err)
 				require.Equal(t, "OK", resp.Status)
 				require.Empty(t, resp.Error)
 			} else {
-				require.Equal(t, "Error", resp.Status)
-				require.Equal(t, tt.expectedError, resp.Error)
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				err = json.NewDecoder(rr.Body).Decode(&problem)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedError, problem.Code)
 			}
 
 			// Проверяем что все ожидания мока выполнены
@@ -0,0 +1,120 @@
+package get
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Link - метаданные ссылки в ответах API и CLI
+type Link struct {
+	Id       int64  `json:"id"`
+	Alias    string `json:"alias"`
+	Domain   string `json:"domain,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	// URL, цели правил и Split не отдаются для ссылок с паролем или лимитом переходов,
+	// см. postgres.Link.HidesTarget
+	URL          string                  `json:"url,omitempty"`
+	Owner        string                  `json:"owner,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`
+	RedirectType int                     `json:"redirect_type,omitempty"`
+	Passthrough  string                  `json:"passthrough,omitempty"`
+	UTM          *postgres.UTM           `json:"utm,omitempty"`
+	Rules        []postgres.RedirectRule `json:"rules,omitempty"`
+	Split        *postgres.Split         `json:"split,omitempty"`
+	// Protected - ссылка открывается только после ввода пароля, сам пароль не отдается
+	Protected bool `json:"protected,omitempty"`
+	// MaxClicks - сколько всего переходов разрешено, 0 - без лимита
+	MaxClicks int `json:"max_clicks,omitempty"`
+	// ClicksLeft - сколько переходов осталось, есть только у ссылок с MaxClicks
+	ClicksLeft   *int       `json:"clicks_left,omitempty"`
+	ActiveFrom   *time.Time `json:"active_from,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+	// State - scheduled, active или expired на момент ответа
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewLink собирает ответ по ссылке, shortURL - ее полный адрес, см. shorturl.Builder
+func NewLink(link postgres.Link, shortURL string) Link {
+	if link.HidesTarget() {
+		link = link.WithoutTargets()
+	}
+	l := Link{
+		Id:           link.ID,
+		Alias:        link.Alias,
+		Domain:       link.Domain,
+		ShortURL:     shortURL,
+		URL:          link.URL,
+		Owner:        link.Owner,
+		Tags:         link.Tags,
+		RedirectType: link.RedirectType,
+		Passthrough:  link.Passthrough,
+		Rules:        link.Rules,
+		Split:        link.Split,
+		Protected:    link.PasswordHash != "",
+		ActiveFrom:   link.ActiveFrom,
+		ExpiresAt:    link.ExpiresAt,
+		Title:        link.Title,
+		Interstitial: link.Interstitial,
+		State:        link.State(time.Now()),
+		CreatedAt:    link.CreatedAt,
+	}
+	if link.MaxClicks > 0 {
+		l.MaxClicks = link.MaxClicks
+		left := max(link.MaxClicks-link.ClicksUsed, 0)
+		l.ClicksLeft = &left
+	}
+	if !link.UTM.IsEmpty() {
+		utm := link.UTM
+		l.UTM = &utm
+	}
+	return l
+}
+
+type Response struct {
+	resp.Response
+	Link
+}
+
+func New(log *slog.Logger, service service.ServiceInterface, shortURLs shorturl.Builder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.get.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("empty alias")
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty alias")
+			return
+		}
+
+		link, err := service.GetLink(r.Context(), domain.FromQuery(r), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", slogger.Err(err), slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Link: NewLink(link, shortURLs.URL(r, link.Domain, link.Alias))})
+	}
+}
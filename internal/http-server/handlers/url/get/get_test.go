@@ -0,0 +1,160 @@
+package get_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestGetHandler(t *testing.T) {
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	launch := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		alias        string
+		query        string
+		mockBehavior func(s *mocks.ServiceInterface)
+		expectedCode int
+		expectedLink get.Link
+		expectedErr  string
+	}{
+		{
+			name:  "Success",
+			alias: "abc",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "abc").Return(postgres.Link{
+					ID: 7, Alias: "abc", URL: "https://a.com", Owner: "o", Tags: []string{"t"}, CreatedAt: created,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedLink: get.Link{Id: 7, Alias: "abc", ShortURL: "https://sho.rt/abc", URL: "https://a.com", Owner: "o", Tags: []string{"t"}, State: postgres.StateActive, CreatedAt: created},
+		},
+		{
+			name:  "Protected link hides targets",
+			alias: "invite",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "invite").Return(postgres.Link{
+					ID: 8, Alias: "invite", URL: "https://a.com", PasswordHash: "$2a$10$hash", MaxClicks: 3, ClicksUsed: 3, CreatedAt: created,
+					Rules: []postgres.RedirectRule{{Device: "ios", Target: "https://apps.apple.com/app/id1"}},
+					Split: &postgres.Split{Variants: []postgres.Variant{{Name: "a", URL: "https://a.com/a", Weight: 1}}},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedLink: get.Link{Id: 8, Alias: "invite", ShortURL: "https://sho.rt/invite", Rules: []postgres.RedirectRule{{Device: "ios"}},
+				Protected: true, MaxClicks: 3, ClicksLeft: new(int), State: postgres.StateActive, CreatedAt: created},
+		},
+		{
+			name:  "Click-limited link hides target",
+			alias: "once",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "once").Return(postgres.Link{
+					ID: 11, Alias: "once", URL: "https://a.com", MaxClicks: 1, CreatedAt: created,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedLink: get.Link{Id: 11, Alias: "once", ShortURL: "https://sho.rt/once", MaxClicks: 1, ClicksLeft: func() *int { n := 1; return &n }(), State: postgres.StateActive, CreatedAt: created},
+		},
+		{
+			name:  "Scheduled link",
+			alias: "launch",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "launch").Return(postgres.Link{
+					ID: 9, Alias: "launch", URL: "https://a.com", ActiveFrom: &launch, CreatedAt: created,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedLink: get.Link{Id: 9, Alias: "launch", ShortURL: "https://sho.rt/launch", URL: "https://a.com", ActiveFrom: &launch, State: postgres.StateScheduled, CreatedAt: created},
+		},
+		{
+			name:  "Brand domain",
+			alias: "abc",
+			query: "?domain=Go.Brand-A.com",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "go.brand-a.com", "abc").Return(postgres.Link{
+					ID: 10, Alias: "abc", Domain: "go.brand-a.com", URL: "https://a.com", CreatedAt: created,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedLink: get.Link{Id: 10, Alias: "abc", Domain: "go.brand-a.com", ShortURL: "https://go.brand-a.com/abc", URL: "https://a.com", State: postgres.StateActive, CreatedAt: created},
+		},
+		{
+			name:  "Not found",
+			alias: "missing",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "missing").Return(postgres.Link{}, storage.ErrURLNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:  "Internal error",
+			alias: "boom",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "boom").Return(postgres.Link{}, errors.New("db error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+		{
+			name:         "Empty alias",
+			alias:        "",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+	}
+
+	shortURLs, err := shorturl.New("https://sho.rt")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			handler := get.New(slogdiscard.NewDiscardLogger(), serviceMock, shortURLs)
+
+			req, err := http.NewRequest(http.MethodGet, "/url/"+tt.alias+tt.query, nil)
+			require.NoError(t, err)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tt.alias)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				return
+			}
+
+			var resp get.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "OK", resp.Status)
+			require.Equal(t, tt.expectedLink, resp.Link)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http-server/handlers/url/get"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+type Response struct {
+	resp.Response
+	Links  []get.Link `json:"links"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+// New отдает страницу ссылок. Параметры запроса: owner, tag, domain, limit, offset.
+func New(log *slog.Logger, service service.ServiceInterface, shortURLs shorturl.Builder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.list.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		query := r.URL.Query()
+		limit, err := intParam(query.Get("limit"), DefaultLimit)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(MaxLimit))
+			return
+		}
+		offset, err := intParam(query.Get("offset"), 0)
+		if err != nil || offset < 0 {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "offset must not be negative")
+			return
+		}
+
+		filter := postgres.Filter{Owner: query.Get("owner"), Tag: query.Get("tag"), Domain: domain.FromQuery(r)}
+		links, err := service.ListURLs(r.Context(), filter, limit, offset)
+		if err != nil {
+			log.Error("failed to list urls", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		views := make([]get.Link, 0, len(links))
+		for _, link := range links {
+			views = append(views, get.NewLink(link, shortURLs.URL(r, link.Domain, link.Alias)))
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Links: views, Limit: limit, Offset: offset})
+	}
+}
+
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,141 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestListHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		mockBehavior  func(s *mocks.ServiceInterface)
+		expectedCode  int
+		expectedCount int
+		expectedShort []string
+		hiddenTarget  string
+		expectedErr   string
+	}{
+		{
+			name:  "Defaults",
+			query: "",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ListURLs", mock.Anything, postgres.Filter{}, list.DefaultLimit, 0).
+					Return([]postgres.Link{{ID: 2, Alias: "b"}, {ID: 1, Alias: "a", Domain: "go.brand-a.com"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 2,
+			expectedShort: []string{"https://sho.rt/b", "https://go.brand-a.com/a"},
+		},
+		{
+			name:  "Filters and paging",
+			query: "?owner=o&tag=t&domain=go.brand-a.com&limit=10&offset=20",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ListURLs", mock.Anything, postgres.Filter{Owner: "o", Tag: "t", Domain: "go.brand-a.com"}, 10, 20).
+					Return([]postgres.Link{}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 0,
+		},
+		{
+			name:  "Protected link hides target",
+			query: "?owner=p",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ListURLs", mock.Anything, postgres.Filter{Owner: "p"}, list.DefaultLimit, 0).
+					Return([]postgres.Link{{ID: 3, Alias: "secret", URL: "https://secret.example.com", PasswordHash: "$2a$10$hash"}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+			hiddenTarget:  "https://secret.example.com",
+		},
+		{
+			name:  "Click-limited link hides target",
+			query: "?owner=l",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ListURLs", mock.Anything, postgres.Filter{Owner: "l"}, list.DefaultLimit, 0).
+					Return([]postgres.Link{{ID: 4, Alias: "once", URL: "https://once.example.com", MaxClicks: 1}}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+			hiddenTarget:  "https://once.example.com",
+		},
+		{
+			name:         "Limit too large",
+			query:        "?limit=5000",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+		{
+			name:         "Negative offset",
+			query:        "?offset=-1",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+		{
+			name:  "Storage error",
+			query: "",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("ListURLs", mock.Anything, postgres.Filter{}, list.DefaultLimit, 0).
+					Return(nil, errors.New("db error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	shortURLs, err := shorturl.New("https://sho.rt")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), serviceMock, shortURLs)
+
+			req, err := http.NewRequest(http.MethodGet, "/url"+tt.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedErr, problem.Code)
+				return
+			}
+
+			if tt.hiddenTarget != "" {
+				require.NotContains(t, rr.Body.String(), tt.hiddenTarget)
+			}
+
+			var resp list.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "OK", resp.Status)
+			require.Len(t, resp.Links, tt.expectedCount)
+			for i, short := range tt.expectedShort {
+				require.Equal(t, short, resp.Links[i].ShortURL)
+			}
+		})
+	}
+}
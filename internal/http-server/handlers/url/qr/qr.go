@@ -0,0 +1,184 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	qrcode "url-shortener/internal/lib/qr"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+
+	// Картинка зависит только от адреса ссылки и параметров, поэтому кешируется надолго
+	cacheControl = "public, max-age=86400"
+	// privateCacheControl - адрес построен по Host запроса, и общий кеш не должен
+	// отдавать такую картинку клиентам, пришедшим на другой хост
+	privateCacheControl = "private, max-age=86400"
+)
+
+// New отдает QR-код короткой ссылки: GET /url/{alias}/qr.
+// Параметры: format (png или svg, можно и расширением /qr.svg), size в пикселях,
+// level (L, M, Q, H), margin в модулях, fg и bg - цвета в hex.
+func New(log *slog.Logger, service service.ServiceInterface, shortURLs shorturl.Builder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.qr.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("empty alias")
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty alias")
+			return
+		}
+
+		format, level, style, err := parseParams(r)
+		if err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, err.Error())
+			return
+		}
+
+		link, err := service.GetLink(r.Context(), domain.FromQuery(r), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", slogger.Err(err), slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		code, err := qrcode.Encode([]byte(shortURLs.URL(r, link.Domain, link.Alias)), level)
+		if err != nil {
+			log.Error("failed to encode qr code", slogger.Err(err), slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to encode qr code")
+			return
+		}
+
+		var body bytes.Buffer
+		contentType := "image/png"
+		if format == FormatSVG {
+			contentType = "image/svg+xml"
+			err = code.SVG(&body, style)
+		} else {
+			err = code.PNG(&body, style)
+		}
+		if err != nil {
+			log.Error("failed to render qr code", slogger.Err(err), slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to render qr code")
+			return
+		}
+
+		sum := sha256.Sum256(body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		if shortURLs.FromRequest() {
+			w.Header().Set("Cache-Control", privateCacheControl)
+		} else {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+		w.Write(body.Bytes())
+	}
+}
+
+func parseParams(r *http.Request) (string, qrcode.Level, qrcode.Style, error) {
+	q := r.URL.Query()
+	style := qrcode.Style{
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	if format == "" {
+		format = FormatPNG
+	}
+	if format != FormatPNG && format != FormatSVG {
+		return "", 0, style, fmt.Errorf("format must be %s or %s", FormatPNG, FormatSVG)
+	}
+
+	level := qrcode.LevelM
+	if v := q.Get("level"); v != "" {
+		var err error
+		if level, err = qrcode.ParseLevel(v); err != nil {
+			return "", 0, style, err
+		}
+	}
+
+	var err error
+	if style.Size, err = intParam(q, "size", DefaultSize, MinSize, MaxSize); err != nil {
+		return "", 0, style, err
+	}
+	if style.Margin, err = intParam(q, "margin", DefaultMargin, 0, MaxMargin); err != nil {
+		return "", 0, style, err
+	}
+	if v := q.Get("fg"); v != "" {
+		if style.Foreground, err = qrcode.ParseColor(v); err != nil {
+			return "", 0, style, err
+		}
+	}
+	if v := q.Get("bg"); v != "" {
+		if style.Background, err = qrcode.ParseColor(v); err != nil {
+			return "", 0, style, err
+		}
+	}
+	return format, level, style, nil
+}
+
+func intParam(q url.Values, name string, def, lo, hi int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, lo, hi)
+	}
+	return n, nil
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package qr_test
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/qr"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestQRHandler(t *testing.T) {
+	found := func(s *mocks.ServiceInterface) {
+		s.On("GetLink", mock.Anything, "", "abc").Return(postgres.Link{ID: 1, Alias: "abc", URL: "https://example.com"}, nil)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		urlFormat    string
+		base         string
+		mockBehavior func(s *mocks.ServiceInterface)
+		expectedCode int
+		expectedType string
+		check        func(t *testing.T, body string)
+		expectedErr  string
+	}{
+		{
+			name:         "Default PNG",
+			mockBehavior: found,
+			expectedCode: http.StatusOK,
+			expectedType: "image/png",
+			check: func(t *testing.T, body string) {
+				img, err := png.Decode(strings.NewReader(body))
+				require.NoError(t, err)
+				// http://example.com/abc - версия 2, 33 модуля с отступом, по 7 пикселей
+				require.Equal(t, 231, img.Bounds().Dx())
+			},
+		},
+		{
+			name:         "SVG with style",
+			query:        "?format=svg&size=512&level=h&margin=2&fg=%23336699&bg=ffffff00",
+			mockBehavior: found,
+			expectedCode: http.StatusOK,
+			expectedType: "image/svg+xml",
+			check: func(t *testing.T, body string) {
+				require.Contains(t, body, `width="512" height="512"`)
+				require.Contains(t, body, `<path fill="#336699" d="M2 2h7v1h-7z`)
+				require.NotContains(t, body, "<rect")
+			},
+		},
+		{
+			name:         "Format from extension",
+			urlFormat:    "svg",
+			mockBehavior: found,
+			expectedCode: http.StatusOK,
+			expectedType: "image/svg+xml",
+		},
+		{
+			name:         "Public base url",
+			base:         "https://sho.rt",
+			mockBehavior: found,
+			expectedCode: http.StatusOK,
+			expectedType: "image/png",
+		},
+		{
+			name:         "Unknown format",
+			query:        "?format=gif",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "format must be png or svg",
+		},
+		{
+			name:         "Size out of range",
+			query:        "?size=10000",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "size must be an integer between 64 and 2048",
+		},
+		{
+			name:         "Unknown level",
+			query:        "?level=X",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "unknown error correction level",
+		},
+		{
+			name:         "Invalid color",
+			query:        "?fg=red",
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "invalid color",
+		},
+		{
+			name: "Not found",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "abc").Return(postgres.Link{}, storage.ErrURLNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  "not found",
+		},
+		{
+			name: "Storage error",
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("GetLink", mock.Anything, "", "abc").Return(postgres.Link{}, errors.New("db down"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+			shortURLs, err := shorturl.New(tt.base)
+			require.NoError(t, err)
+			handler := qr.New(slogdiscard.NewDiscardLogger(), serviceMock, shortURLs)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/url/abc/qr"+tt.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", "abc")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			if tt.urlFormat != "" {
+				ctx = context.WithValue(ctx, middleware.URLFormatCtxKey, tt.urlFormat)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(ctx))
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedErr != "" {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				require.Contains(t, rr.Body.String(), tt.expectedErr)
+				return
+			}
+			require.Equal(t, tt.expectedType, rr.Header().Get("Content-Type"))
+			// Адрес по Host запроса нельзя класть в общий кеш
+			expectedCache := "private, max-age=86400"
+			if tt.base != "" {
+				expectedCache = "public, max-age=86400"
+			}
+			require.Equal(t, expectedCache, rr.Header().Get("Cache-Control"))
+			require.NotEmpty(t, rr.Header().Get("ETag"))
+			if tt.check != nil {
+				tt.check(t, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestQRHandlerNotModified(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("GetLink", mock.Anything, "", "abc").Return(postgres.Link{ID: 1, Alias: "abc", URL: "https://example.com"}, nil)
+	handler := qr.New(slogdiscard.NewDiscardLogger(), serviceMock, shorturl.Builder{})
+
+	serve := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/url/abc/qr?format=svg", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("alias", "abc")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+
+	rr = serve(`"other", ` + etag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+	require.Equal(t, etag, rr.Header().Get("ETag"))
+}
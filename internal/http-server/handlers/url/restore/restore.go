@@ -0,0 +1,53 @@
+package restore
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func New(log *slog.Logger, storages *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.restore.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("empty alias")
+			response.WriteError(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "empty alias")
+			return
+		}
+
+		err := storages.RestoreURL(r.Context(), domain.FromQuery(r), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("deleted url not found", slog.String("alias", alias))
+			response.WriteError(w, r, http.StatusNotFound, response.CodeNotFound, "deleted url not found")
+			return
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Info("url taken by another link", slog.String("alias", alias))
+			response.WriteError(w, r, http.StatusConflict, response.CodeAlreadyExists, "url already exists")
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore url", slogger.Err(err), slog.String("alias", alias))
+			response.WriteError(w, r, http.StatusInternalServerError, response.CodeInternal, "internal error")
+			return
+		}
+
+		log.Info("url restored", slog.String("alias", alias))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,119 @@
+package restore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/redirect/mocks"
+	"url-shortener/internal/http-server/handlers/url/restore"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	tests := []struct {
+		name          string
+		alias         string
+		mockBehavior  func(m *mocks.PostgresStorageInterface)
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:  "Success",
+			alias: "test-alias",
+			mockBehavior: func(m *mocks.PostgresStorageInterface) {
+				m.On("RestoreURL", mock.Anything, "", "test-alias").
+					Return(nil).
+					Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Empty alias",
+			alias:         "",
+			mockBehavior:  func(m *mocks.PostgresStorageInterface) {},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "empty alias",
+		},
+		{
+			name:  "Not deleted or missing",
+			alias: "not-found",
+			mockBehavior: func(m *mocks.PostgresStorageInterface) {
+				m.On("RestoreURL", mock.Anything, "", "not-found").
+					Return(storage.ErrURLNotFound).
+					Once()
+			},
+			expectedCode:  http.StatusNotFound,
+			expectedError: "deleted url not found",
+		},
+		{
+			name:  "Url shortened again",
+			alias: "test-alias",
+			mockBehavior: func(m *mocks.PostgresStorageInterface) {
+				m.On("RestoreURL", mock.Anything, "", "test-alias").
+					Return(storage.ErrURLExists).
+					Once()
+			},
+			expectedCode:  http.StatusConflict,
+			expectedError: "url already exists",
+		},
+		{
+			name:  "Internal error",
+			alias: "error-case",
+			mockBehavior: func(m *mocks.PostgresStorageInterface) {
+				m.On("RestoreURL", mock.Anything, "", "error-case").
+					Return(errors.New("some db error")).
+					Once()
+			},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := mocks.NewPostgresStorageInterface(t)
+			tt.mockBehavior(storageMock)
+
+			handler := restore.New(log, &storage.Storage{Postgres: storageMock})
+
+			req, err := http.NewRequest(http.MethodPost, "/"+tt.alias+"/restore", nil)
+			require.NoError(t, err)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tt.alias)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "test-request-id"))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedError == "" {
+				var resp response.Response
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+				require.Equal(t, "OK", resp.Status)
+			} else {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedCode, problem.Status)
+				require.Equal(t, tt.expectedError, problem.Detail)
+				require.Equal(t, "test-request-id", problem.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/links"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// MaxRules - предельное число правил у одной ссылки, см. links.MaxRules
+const MaxRules = links.MaxRules
+
+// Rule - правило в запросах API, проверяется links.ValidateRule
+type Rule struct {
+	Device    string   `json:"device,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	Target    string   `json:"target"`
+}
+
+type ReplaceRequest struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Response - правила ссылки по порядку проверки. Default - цель, если ни одно правило не подошло.
+type Response struct {
+	resp.Response
+	Rules   []postgres.RedirectRule `json:"rules"`
+	Default string                  `json:"default,omitempty"`
+}
+
+// normalize приводит коды к виду, в котором их сравнивает редирект и проверяет валидатор
+func (rule *Rule) normalize() {
+	rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+	for i, country := range rule.Countries {
+		rule.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+	for i, lang := range rule.Languages {
+		rule.Languages[i] = strings.TrimSpace(lang)
+	}
+}
+
+func (rule Rule) redirectRule() postgres.RedirectRule {
+	return postgres.RedirectRule{
+		Device:    rule.Device,
+		Languages: rule.Languages,
+		Countries: rule.Countries,
+		Target:    rule.Target,
+	}
+}
+
+// Get отдает правила ссылки: GET /url/{alias}/rules
+func Get(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.rules.Get"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		alias := chi.URLParam(r, "alias")
+		link, err := service.GetLink(r.Context(), domain.FromQuery(r), alias)
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+		if link.HidesTarget() {
+			link = link.WithoutTargets()
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Rules: nonNil(link.Rules), Default: link.URL})
+	}
+}
+
+// Replace заменяет все правила ссылки: PUT /url/{alias}/rules. Пустой список удаляет правила.
+func Replace(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.rules.Replace"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var req ReplaceRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+		if len(req.Rules) > MaxRules {
+			resp.WriteError(w, r, http.StatusRequestEntityTooLarge, resp.CodeTooManyItems, fmt.Sprintf("too many rules, max %d", MaxRules))
+			return
+		}
+
+		rules := make([]postgres.RedirectRule, 0, len(req.Rules))
+		for i, rule := range req.Rules {
+			rule.normalize()
+			if err := links.ValidateRule(rule.redirectRule()); err != nil {
+				resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, fmt.Sprintf("rule %d: %s", i, err))
+				return
+			}
+			rules = append(rules, rule.redirectRule())
+		}
+
+		alias := chi.URLParam(r, "alias")
+		saved, err := service.SetRedirectRules(r.Context(), domain.FromQuery(r), alias, rules)
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+
+		log.Info("redirect rules replaced", slog.String("alias", alias), slog.Int("count", len(saved)))
+
+		render.JSON(w, r, Response{Response: resp.OK(), Rules: nonNil(saved)})
+	}
+}
+
+// Add добавляет правило в конец списка: POST /url/{alias}/rules
+func Add(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.rules.Add"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var rule Rule
+		if err := render.DecodeJSON(r.Body, &rule); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+		rule.normalize()
+		if err := links.ValidateRule(rule.redirectRule()); err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, err.Error())
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		hidden, err := targetsHidden(r, service, alias)
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+		saved, err := service.AddRedirectRule(r.Context(), domain.FromQuery(r), alias, rule.redirectRule(), MaxRules)
+		if errors.Is(err, storage.ErrTooManyRules) {
+			resp.WriteError(w, r, http.StatusConflict, resp.CodeTooManyItems, fmt.Sprintf("link already has %d rules", MaxRules))
+			return
+		}
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+
+		log.Info("redirect rule added", slog.String("alias", alias), slog.Int("count", len(saved)))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{Response: resp.OK(), Rules: nonNil(withoutTargets(saved, hidden))})
+	}
+}
+
+// Delete удаляет правило по номеру с нуля: DELETE /url/{alias}/rules/{index}
+func Delete(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.rules.Delete"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		index, err := strconv.Atoi(chi.URLParam(r, "index"))
+		if err != nil || index < 0 {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "rule index must be a non-negative integer")
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		hidden, err := targetsHidden(r, service, alias)
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+		saved, err := service.DeleteRedirectRule(r.Context(), domain.FromQuery(r), alias, index)
+		if errors.Is(err, storage.ErrRuleNotFound) {
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "rule not found")
+			return
+		}
+		if writeStorageError(w, r, log, alias, err) {
+			return
+		}
+
+		log.Info("redirect rule deleted", slog.String("alias", alias), slog.Int("index", index))
+
+		render.JSON(w, r, Response{Response: resp.OK(), Rules: nonNil(withoutTargets(saved, hidden))})
+	}
+}
+
+// writeStorageError отвечает на ошибку хранилища, общую для всех операций с правилами
+func writeStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, alias string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, storage.ErrURLNotFound):
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "url not found")
+	default:
+		log.Error("failed to access redirect rules", slogger.Err(err), slog.String("alias", alias))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+	}
+	return true
+}
+
+// targetsHidden проверяет до изменения правил, скрыта ли цель ссылки: Add и Delete
+// отвечают всеми правилами, и без проверки отдавали бы цели чужих правил
+func targetsHidden(r *http.Request, service service.ServiceInterface, alias string) (bool, error) {
+	link, err := service.GetLink(r.Context(), domain.FromQuery(r), alias)
+	return link.HidesTarget(), err
+}
+
+func withoutTargets(rules []postgres.RedirectRule, hidden bool) []postgres.RedirectRule {
+	if !hidden {
+		return rules
+	}
+	return postgres.Link{Rules: rules}.WithoutTargets().Rules
+}
+
+func nonNil(rules []postgres.RedirectRule) []postgres.RedirectRule {
+	if rules == nil {
+		return []postgres.RedirectRule{}
+	}
+	return rules
+}
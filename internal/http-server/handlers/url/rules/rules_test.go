@@ -0,0 +1,297 @@
+package rules_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/rules"
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+var iosRule = postgres.RedirectRule{Device: "ios", Target: "https://apps.apple.com/app/id1"}
+
+func serve(t *testing.T, handler http.HandlerFunc, method, body string, params map[string]string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/url/app/rules", bytes.NewBufferString(body))
+	require.NoError(t, err)
+
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func requireProblem(t *testing.T, rr *httptest.ResponseRecorder, code int, errCode string) response.Problem {
+	require.Equal(t, code, rr.Code)
+	require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+	var problem response.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	require.Equal(t, errCode, problem.Code)
+	return problem
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(s *mocks.ServiceInterface)
+		expectedCode   int
+		expectedErr    string
+		expectedDetail string
+	}{
+		{
+			name: "Codes are normalized",
+			body: `{"rules": [{"device": "iOS", "target": "https://apps.apple.com/app/id1"},
+				{"countries": ["de", " at"], "languages": ["de"], "target": "https://example.de"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				expected := []postgres.RedirectRule{
+					iosRule,
+					{Countries: []string{"DE", "AT"}, Languages: []string{"de"}, Target: "https://example.de"},
+				}
+				s.On("SetRedirectRules", mock.Anything, "", "app", expected).Return(expected, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Empty list removes rules",
+			body: `{"rules": []}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SetRedirectRules", mock.Anything, "", "app", []postgres.RedirectRule{}).Return(nil, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:           "Rule without conditions",
+			body:           `{"rules": [{"target": "https://example.com"}]}`,
+			mockBehavior:   func(s *mocks.ServiceInterface) {},
+			expectedCode:   http.StatusBadRequest,
+			expectedErr:    response.CodeValidationFailed,
+			expectedDetail: "rule 0: rule has no conditions",
+		},
+		{
+			name:           "Unknown country",
+			body:           `{"rules": [{"device": "ios", "target": "https://a.com"}, {"countries": ["XX"], "target": "https://b.com"}]}`,
+			mockBehavior:   func(s *mocks.ServiceInterface) {},
+			expectedCode:   http.StatusBadRequest,
+			expectedErr:    response.CodeValidationFailed,
+			expectedDetail: "rule 1: field Countries[0] is not valid",
+		},
+		{
+			name:         "Unknown device",
+			body:         `{"rules": [{"device": "tv", "target": "https://a.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name:         "Too many rules",
+			body:         `{"rules": [` + strings.Repeat(`{"device": "ios", "target": "https://a.com"},`, rules.MaxRules) + `{"device": "ios", "target": "https://a.com"}]}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedErr:  response.CodeTooManyItems,
+		},
+		{
+			name: "Link not found",
+			body: `{"rules": []}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SetRedirectRules", mock.Anything, "", "app", mock.Anything).
+					Return(nil, fmt.Errorf("postgres.storage.SetRedirectRules: %w", storage.ErrURLNotFound))
+			},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			rr := serve(t, rules.Replace(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodPut, tt.body, map[string]string{"alias": "app"})
+
+			if tt.expectedCode != http.StatusOK {
+				problem := requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				if tt.expectedDetail != "" {
+					require.Equal(t, tt.expectedDetail, problem.Detail)
+				}
+				return
+			}
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			var resp rules.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.NotNil(t, resp.Rules)
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		link         postgres.Link
+		err          error
+		expectedCode int
+		expectedErr  string
+		expected     []postgres.RedirectRule
+	}{
+		{name: "Success", body: `{"device": "ios", "target": "https://apps.apple.com/app/id1"}`, expectedCode: http.StatusCreated, expected: []postgres.RedirectRule{iosRule}},
+		{
+			name:         "Protected link hides targets",
+			body:         `{"device": "ios", "target": "https://apps.apple.com/app/id1"}`,
+			link:         postgres.Link{PasswordHash: "$2a$10$hash"},
+			expectedCode: http.StatusCreated,
+			expected:     []postgres.RedirectRule{{Device: iosRule.Device}},
+		},
+		{
+			name:         "Limit reached",
+			body:         `{"device": "ios", "target": "https://apps.apple.com/app/id1"}`,
+			err:          storage.ErrTooManyRules,
+			expectedCode: http.StatusConflict,
+			expectedErr:  response.CodeTooManyItems,
+		},
+		{
+			name:         "Storage error",
+			body:         `{"device": "ios", "target": "https://apps.apple.com/app/id1"}`,
+			err:          errors.New("db error"),
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+		{
+			name:         "Invalid target",
+			body:         `{"device": "ios", "target": "not a url"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			if tt.expectedCode != http.StatusBadRequest {
+				serviceMock.On("GetLink", mock.Anything, "", "app").Return(tt.link, nil)
+				serviceMock.On("AddRedirectRule", mock.Anything, "", "app", iosRule, rules.MaxRules).
+					Return([]postgres.RedirectRule{iosRule}, tt.err)
+			}
+
+			rr := serve(t, rules.Add(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodPost, tt.body, map[string]string{"alias": "app"})
+
+			if tt.expectedErr != "" {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+			require.Equal(t, tt.expectedCode, rr.Code)
+			var resp rules.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, tt.expected, resp.Rules)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		index        string
+		err          error
+		expectedCode int
+		expectedErr  string
+	}{
+		{name: "Success", index: "0", expectedCode: http.StatusOK},
+		{
+			name:         "Rule not found",
+			index:        "3",
+			err:          storage.ErrRuleNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:         "Link not found",
+			index:        "0",
+			err:          storage.ErrURLNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{name: "Negative index", index: "-1", expectedCode: http.StatusBadRequest, expectedErr: response.CodeInvalidParameter},
+		{name: "Not a number", index: "first", expectedCode: http.StatusBadRequest, expectedErr: response.CodeInvalidParameter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			if tt.expectedCode != http.StatusBadRequest {
+				serviceMock.On("GetLink", mock.Anything, "", "app").Return(postgres.Link{}, nil)
+				serviceMock.On("DeleteRedirectRule", mock.Anything, "", "app", mock.AnythingOfType("int")).
+					Return([]postgres.RedirectRule{}, tt.err)
+			}
+
+			rr := serve(t, rules.Delete(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodDelete, "", map[string]string{"alias": "app", "index": tt.index})
+
+			if tt.expectedErr != "" {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+			require.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("GetLink", mock.Anything, "", "app").
+		Return(postgres.Link{Alias: "app", URL: "https://example.com", Rules: []postgres.RedirectRule{iosRule}}, nil)
+
+	rr := serve(t, rules.Get(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodGet, "", map[string]string{"alias": "app"})
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp rules.Response
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "https://example.com", resp.Default)
+	require.Equal(t, []postgres.RedirectRule{iosRule}, resp.Rules)
+}
+
+func TestGetProtected(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("GetLink", mock.Anything, "", "app").
+		Return(postgres.Link{Alias: "app", URL: "https://example.com", PasswordHash: "$2a$10$hash", Rules: []postgres.RedirectRule{iosRule}}, nil)
+
+	rr := serve(t, rules.Get(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodGet, "", map[string]string{"alias": "app"})
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotContains(t, rr.Body.String(), "example.com")
+	require.NotContains(t, rr.Body.String(), iosRule.Target)
+	var resp rules.Response
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Empty(t, resp.Default)
+	require.Equal(t, []postgres.RedirectRule{{Device: iosRule.Device}}, resp.Rules)
+}
+
+func TestGetClickLimited(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("GetLink", mock.Anything, "", "app").
+		Return(postgres.Link{Alias: "app", URL: "https://example.com", MaxClicks: 1, Rules: []postgres.RedirectRule{iosRule}}, nil)
+
+	rr := serve(t, rules.Get(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodGet, "", map[string]string{"alias": "app"})
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotContains(t, rr.Body.String(), "example.com")
+	require.NotContains(t, rr.Body.String(), iosRule.Target)
+}
@@ -5,112 +5,110 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"url-shortener/internal/lib/api/random"
 	"url-shortener/internal/lib/api/response"
 	resp "url-shortener/internal/lib/api/response"
 	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/links"
 	"url-shortener/internal/service"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 )
 
-const aliasLength = 6
-
 type Handlers struct {
-	service service.ServiceInterface
+	service   service.ServiceInterface
+	shortURLs shorturl.Builder
 }
 
-func NewHandlers(service service.ServiceInterface) *Handlers {
+func NewHandlers(service service.ServiceInterface, shortURLs shorturl.Builder) *Handlers {
 	return &Handlers{
-		service: service,
+		service:   service,
+		shortURLs: shortURLs,
 	}
 }
 
 type HandlersInterface interface {
 	New(ctx context.Context, urlToSave string, alias string) (int64, error)
-	GetURL(ctx context.Context, alias string) (string, error)
-	DeleteURl(ctx context.Context, alias string) error
+	GetURL(ctx context.Context, domain, alias string) (string, error)
+	DeleteURl(ctx context.Context, domain, alias string) error
 }
 
-func (h *Handlers) New(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+func (h *Handlers) New(log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.url.save.New"
-		log = log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		ctx, span := tracing.Start(r.Context(), op)
+		defer span.End()
 
-		var req Request
+		var req links.Request
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
 			log.Error("failed to decode request body", slogger.Err(err))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("failed to decode request body"))
+			response.WriteError(w, r, http.StatusBadRequest, response.CodeInvalidBody, "failed to decode request body")
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("invalid request", slogger.Err(err))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.ValidationError(err.(validator.ValidationErrors)))
-			return
-		}
-
-		res, err := h.service.URLExists(ctx, req.URL)
-		if err != nil {
-			log.Error("failed to check url existence", slogger.Err(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("failed to check url existence"))
+		link, err := h.service.CreateLink(ctx, req)
+		if isStorageTimeout(w, r, log, span, err) {
 			return
 		}
-		if res {
-			log.Info("url already exists", slog.Any("url", req.URL))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url already exists"))
+		if detail, ok := links.ClientError(err); ok {
+			log.Info("invalid request", slogger.Err(err))
+			response.WriteError(w, r, http.StatusBadRequest, response.CodeValidationFailed, detail)
 			return
 		}
-
-		alias := req.Alias
-		if alias == "" {
-			alias = random.NewRandomString(aliasLength)
-		}
-
-		id, err := h.service.SaveURL(ctx, req.URL, alias)
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Info("url already exists", slog.String("url", req.URL))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url already exists"))
+			response.WriteError(w, r, http.StatusConflict, response.CodeAlreadyExists, "url already exists")
 			return
 		}
 		if err != nil {
 			log.Error("failed to add url", slogger.Err(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("failed to add url"))
+			span.RecordError(err)
+			response.WriteError(w, r, http.StatusInternalServerError, response.CodeInternal, "failed to add url")
 			return
 		}
 
-		log.Info("url added", slog.Int64("id", id))
+		log.Info("url added", slog.Int64("id", link.ID))
+		span.SetAttributes(tracing.String("alias", link.Alias))
 		w.WriteHeader(http.StatusOK)
-		responseOK(w, r, id, alias)
+		responseOK(w, r, link.ID, link.Alias, h.shortURLs.URL(r, link.Domain, link.Alias))
+	}
+}
+
+// isStorageTimeout отвечает на запрос, прерванный клиентом или таймаутом БД
+func isStorageTimeout(w http.ResponseWriter, r *http.Request, log *slog.Logger, span *tracing.Span, err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Info("request cancelled")
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Warn("storage timeout")
+		span.RecordError(err)
+		response.WriteError(w, r, http.StatusGatewayTimeout, response.CodeTimeout, "storage timeout")
+		return true
 	}
+	return false
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, id int64, alias string) {
+func responseOK(w http.ResponseWriter, r *http.Request, id int64, alias, shortURL string) {
 	render.JSON(w, r, Response{
 		Response: resp.OK(),
 		Id:       id,
 		Alias:    alias,
+		ShortURL: shortURL,
 	})
 }
 
-type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
-}
-
 type Response struct {
 	resp.Response
 	Id    int64  `json:"id"`
 	Alias string `json:"alias,omitempty"`
+	// ShortURL - готовый адрес ссылки от public_base_url или хоста запроса
+	ShortURL string `json:"short_url,omitempty"`
 }
@@ -9,96 +9,186 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
 )
 
 func TestHandlers_New(t *testing.T) {
-	type mockBehavior func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error)
-
 	const testAlias = "test_alias"
 	const testURL = "https://google.com"
 
 	tests := []struct {
 		name          string
 		inputBody     string
-		mockBehavior  mockBehavior
+		mockBehavior  func(s *mocks.ServiceInterface)
 		expectedCode  int
 		checkResponse func(t *testing.T, response save.Response)
+		checkProblem  func(t *testing.T, problem response.Problem)
 	}{
 		{
 			name:      "Success with alias",
 			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, testURL, testAlias),
-			mockBehavior: func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error) {
-				s.On("URLExists", mock.Anything, url).Return(exists, nil)
-				s.On("SaveURL", mock.Anything, url, alias).Return(int64(1), saveErr)
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, links.Request{URL: testURL, Alias: testAlias}).
+					Return(postgres.Link{ID: 1, URL: testURL, Alias: testAlias}, nil)
 			},
 			expectedCode: http.StatusOK,
 			checkResponse: func(t *testing.T, resp save.Response) {
 				require.Equal(t, int64(1), resp.Id)
 				require.Equal(t, testAlias, resp.Alias)
+				require.Equal(t, "https://sho.rt/"+testAlias, resp.ShortURL)
 				require.Equal(t, "OK", resp.Status)
 			},
 		},
 		{
-			name:         "Invalid JSON",
-			inputBody:    `{"url": "https://google.com", "alias": "test",}`,
-			mockBehavior: func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error) {},
-			expectedCode: http.StatusBadRequest,
+			name:      "Request fields are passed to the service",
+			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s", "owner": "marketing", "tags": ["spring"], "password": "s3cret", "max_clicks": 1}`, testURL, testAlias),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, links.Request{URL: testURL, Alias: testAlias, Owner: "marketing", Tags: []string{"spring"}, Password: "s3cret", MaxClicks: 1}).
+					Return(postgres.Link{ID: 2, URL: testURL, Alias: testAlias}, nil)
+			},
+			expectedCode: http.StatusOK,
 			checkResponse: func(t *testing.T, resp save.Response) {
-				require.Equal(t, "Error", resp.Status)
-				require.Contains(t, resp.Error, "failed to decode")
+				require.Equal(t, int64(2), resp.Id)
 			},
 		},
 		{
-			name:         "Empty URL",
-			inputBody:    `{"url": "", "alias": "test"}`,
-			mockBehavior: func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error) {},
-			expectedCode: http.StatusBadRequest,
+			name:      "Generated alias",
+			inputBody: fmt.Sprintf(`{"url": "%s"}`, testURL),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, links.Request{URL: testURL}).
+					Return(postgres.Link{ID: 3, URL: testURL, Alias: "abc123"}, nil)
+			},
+			expectedCode: http.StatusOK,
 			checkResponse: func(t *testing.T, resp save.Response) {
-				require.Equal(t, "Error", resp.Status)
-				require.Contains(t, resp.Error, "required field")
+				require.Equal(t, int64(3), resp.Id)
+				require.Equal(t, "abc123", resp.Alias)
+				require.Equal(t, "https://sho.rt/abc123", resp.ShortURL)
+			},
+		},
+		{
+			name:      "Success on brand domain",
+			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s", "domain": "Go.Brand-A.com"}`, testURL, testAlias),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, links.Request{URL: testURL, Alias: testAlias, Domain: "Go.Brand-A.com"}).
+					Return(postgres.Link{ID: 11, URL: testURL, Alias: testAlias, Domain: "go.brand-a.com"}, nil)
+			},
+			expectedCode: http.StatusOK,
+			checkResponse: func(t *testing.T, resp save.Response) {
+				require.Equal(t, int64(11), resp.Id)
+				require.Equal(t, "https://go.brand-a.com/"+testAlias, resp.ShortURL)
+			},
+		},
+		{
+			name:      "Invalid request",
+			inputBody: fmt.Sprintf(`{"url": "%s", "expires_at": "2001-01-01T00:00:00Z"}`, testURL),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, &links.ValidationError{Err: links.ErrExpiresInPast})
+			},
+			expectedCode: http.StatusBadRequest,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeValidationFailed, problem.Code)
+				require.Equal(t, links.ErrExpiresInPast.Error(), problem.Detail)
+			},
+		},
+		{
+			name:      "Unknown utm template",
+			inputBody: fmt.Sprintf(`{"url": "%s", "owner": "marketing", "utm_template": "missing"}`, testURL),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, fmt.Errorf("service.CreateLink: %w", storage.ErrUTMTemplateNotFound))
+			},
+			expectedCode: http.StatusBadRequest,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeValidationFailed, problem.Code)
+				require.Equal(t, "utm template not found", problem.Detail)
+			},
+		},
+		{
+			name:      "Unknown domain",
+			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s", "domain": "go.unknown.com"}`, testURL, testAlias),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, fmt.Errorf("service.CreateLink: %w", storage.ErrDomainNotFound))
+			},
+			expectedCode: http.StatusBadRequest,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeValidationFailed, problem.Code)
+				require.Equal(t, "unknown domain", problem.Detail)
+			},
+		},
+		{
+			name:         "Invalid JSON",
+			inputBody:    `{"url": "https://google.com", "alias": "test",}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeInvalidBody, problem.Code)
+				require.Contains(t, problem.Detail, "failed to decode")
 			},
 		},
 		{
 			name:      "URL already exists",
 			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, testURL, testAlias),
-			mockBehavior: func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error) {
-				s.On("URLExists", mock.Anything, url).Return(true, nil)
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, fmt.Errorf("service.CreateLink: %w", storage.ErrURLExists))
 			},
-			expectedCode: http.StatusBadRequest,
-			checkResponse: func(t *testing.T, resp save.Response) {
-				require.Equal(t, "Error", resp.Status)
-				require.Equal(t, "url already exists", resp.Error)
+			expectedCode: http.StatusConflict,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeAlreadyExists, problem.Code)
+				require.Equal(t, "url already exists", problem.Detail)
 			},
 		},
 		{
 			name:      "Save URL error",
 			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, testURL, testAlias),
-			mockBehavior: func(s *mocks.ServiceInterface, url, alias string, exists bool, saveErr error) {
-				s.On("URLExists", mock.Anything, url).Return(exists, nil)
-				s.On("SaveURL", mock.Anything, url, alias).Return(int64(0), errors.New("db error"))
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, errors.New("db error"))
 			},
 			expectedCode: http.StatusInternalServerError,
-			checkResponse: func(t *testing.T, resp save.Response) {
-				require.Equal(t, "Error", resp.Status)
-				require.Equal(t, "failed to add url", resp.Error)
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeInternal, problem.Code)
+				require.Equal(t, "failed to add url", problem.Detail)
+			},
+		},
+		{
+			name:      "Storage timeout",
+			inputBody: fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, testURL, testAlias),
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+					Return(postgres.Link{}, fmt.Errorf("postgres.storage.AliasExists: %w", context.DeadlineExceeded))
+			},
+			expectedCode: http.StatusGatewayTimeout,
+			checkProblem: func(t *testing.T, problem response.Problem) {
+				require.Equal(t, response.CodeTimeout, problem.Code)
 			},
 		},
 	}
 
+	shortURLs, err := shorturl.New("https://sho.rt")
+	require.NoError(t, err)
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			serviceMock := mocks.NewServiceInterface(t)
-			tt.mockBehavior(serviceMock, testURL, testAlias, false, nil)
+			tt.mockBehavior(serviceMock)
 
-			h := save.NewHandlers(serviceMock)
-			handler := h.New(context.Background(), slogdiscard.NewDiscardLogger())
+			h := save.NewHandlers(serviceMock, shortURLs)
+			handler := h.New(slogdiscard.NewDiscardLogger())
 
 			req, err := http.NewRequest(http.MethodPost, "/url", bytes.NewBufferString(tt.inputBody))
 			require.NoError(t, err)
@@ -108,6 +198,16 @@ func TestHandlers_New(t *testing.T) {
 
 			require.Equal(t, tt.expectedCode, rr.Code)
 
+			if tt.checkProblem != nil {
+				require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+				var problem response.Problem
+				err = json.NewDecoder(rr.Body).Decode(&problem)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedCode, problem.Status)
+				tt.checkProblem(t, problem)
+				return
+			}
+
 			var resp save.Response
 			err = json.NewDecoder(rr.Body).Decode(&resp)
 			require.NoError(t, err)
@@ -116,3 +216,37 @@ func TestHandlers_New(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlers_NewCancelledRequest(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	started := make(chan struct{})
+	serviceMock.On("CreateLink", mock.Anything, mock.AnythingOfType("links.Request")).
+		Return(func(ctx context.Context, req links.Request) (postgres.Link, error) {
+			close(started)
+			<-ctx.Done()
+			return postgres.Link{}, ctx.Err()
+		}).
+		Once()
+
+	handler := save.NewHandlers(serviceMock, shorturl.Builder{}).New(slogdiscard.NewDiscardLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBufferString(`{"url": "https://google.com", "alias": "slow"}`)).
+		WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	// Клиент отключается, пока запрос к БД еще выполняется
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not stop after request was cancelled")
+	}
+}
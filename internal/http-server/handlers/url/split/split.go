@@ -0,0 +1,77 @@
+package split
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/links"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Save задает варианты A/B-теста ссылки: PUT /url/{alias}/split.
+// Статистика переходов по вариантам с прежними именами сохраняется.
+func Save(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.split.Save"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		var split postgres.Split
+		if err := render.DecodeJSON(r.Body, &split); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+		if err := links.ValidateSplit(&split); err != nil {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, err.Error())
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		if writeError(w, r, log, alias, service.SetSplit(r.Context(), domain.FromQuery(r), alias, &split)) {
+			return
+		}
+
+		log.Info("split saved", slog.String("alias", alias), slog.Int("variants", len(split.Variants)))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// Delete завершает A/B-тест: DELETE /url/{alias}/split. Ссылка снова ведет на свой URL.
+func Delete(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.split.Delete"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		alias := chi.URLParam(r, "alias")
+		if writeError(w, r, log, alias, service.SetSplit(r.Context(), domain.FromQuery(r), alias, nil)) {
+			return
+		}
+
+		log.Info("split removed", slog.String("alias", alias))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, log *slog.Logger, alias string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, storage.ErrURLNotFound):
+		resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "url not found")
+	default:
+		log.Error("failed to set split", slogger.Err(err), slog.String("alias", alias))
+		resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package split_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/handlers/url/split"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func serve(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/url/promo/split", bytes.NewBufferString(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("alias", "promo")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		err            error
+		expectedCode   int
+		expectedErr    string
+		expectedDetail string
+	}{
+		{
+			name:         "Success",
+			body:         `{"sticky": "cookie", "variants": [{"name": "a", "url": "https://a.com", "weight": 70}, {"name": "b", "url": "https://b.com", "weight": 30}]}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Link not found",
+			body:         `{"variants": [{"name": "a", "url": "https://a.com", "weight": 1}]}`,
+			err:          fmt.Errorf("postgres.storage.SetSplit: %w", storage.ErrURLNotFound),
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:           "Duplicate variant",
+			body:           `{"variants": [{"name": "a", "url": "https://a.com", "weight": 1}, {"name": "a", "url": "https://b.com", "weight": 1}]}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedErr:    response.CodeValidationFailed,
+			expectedDetail: `variant 1: duplicate name "a"`,
+		},
+		{
+			name:         "Unknown sticky mode",
+			body:         `{"sticky": "session", "variants": [{"name": "a", "url": "https://a.com", "weight": 1}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name:         "Invalid body",
+			body:         `{"variants": `,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidBody,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			if tt.expectedCode != http.StatusBadRequest {
+				serviceMock.On("SetSplit", mock.Anything, "", "promo", mock.AnythingOfType("*postgres.Split")).Return(tt.err)
+			}
+
+			rr := serve(split.Save(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodPut, tt.body)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedErr != "" {
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedErr, problem.Code)
+				if tt.expectedDetail != "" {
+					require.Equal(t, tt.expectedDetail, problem.Detail)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("SetSplit", mock.Anything, "", "promo", (*postgres.Split)(nil)).Return(nil)
+
+	rr := serve(split.Delete(slogdiscard.NewDiscardLogger(), serviceMock), http.MethodDelete, "")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Response - переходы по ссылке. Переходы без A/B-теста идут в вариант с пустым именем.
+type Response struct {
+	resp.Response
+	Total    int64                    `json:"total"`
+	Variants []postgres.VariantClicks `json:"variants"`
+}
+
+// New отдает число переходов по вариантам: GET /url/{alias}/stats.
+// Переходы пишутся пачками, поэтому последние секунды могут еще не попасть в ответ.
+func New(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.url.stats.New"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		alias := chi.URLParam(r, "alias")
+		variants, err := service.ClickStats(r.Context(), domain.FromQuery(r), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "url not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get click stats", slogger.Err(err), slog.String("alias", alias))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		var total int64
+		for _, v := range variants {
+			total += v.Clicks
+		}
+		if variants == nil {
+			variants = []postgres.VariantClicks{}
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Total: total, Variants: variants})
+	}
+}
@@ -0,0 +1,69 @@
+package stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		variants      []postgres.VariantClicks
+		err           error
+		expectedCode  int
+		expectedErr   string
+		expectedTotal int64
+	}{
+		{
+			name:          "Success",
+			variants:      []postgres.VariantClicks{{Variant: "a", Clicks: 12}, {Variant: "b", Clicks: 5}},
+			expectedCode:  http.StatusOK,
+			expectedTotal: 17,
+		},
+		{name: "No clicks", expectedCode: http.StatusOK},
+		{name: "Link not found", err: storage.ErrURLNotFound, expectedCode: http.StatusNotFound, expectedErr: response.CodeNotFound},
+		{name: "Storage error", err: errors.New("db error"), expectedCode: http.StatusInternalServerError, expectedErr: response.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			serviceMock.On("ClickStats", mock.Anything, "", "promo").Return(tt.variants, tt.err)
+
+			req := httptest.NewRequest(http.MethodGet, "/url/promo/stats", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", "promo")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rr := httptest.NewRecorder()
+			stats.New(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedErr != "" {
+				var problem response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				require.Equal(t, tt.expectedErr, problem.Code)
+				return
+			}
+
+			var resp stats.Response
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, tt.expectedTotal, resp.Total)
+			require.NotNil(t, resp.Variants)
+		})
+	}
+}
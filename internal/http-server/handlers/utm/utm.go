@@ -0,0 +1,133 @@
+package utm
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	slogger "url-shortener/internal/lib/logger/slog"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Template - шаблон utm-меток в ответах API
+type Template struct {
+	Owner     string       `json:"owner"`
+	Name      string       `json:"name"`
+	UTM       postgres.UTM `json:"utm"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func NewTemplate(t postgres.UTMTemplate) Template {
+	return Template{Owner: t.Owner, Name: t.Name, UTM: t.UTM, UpdatedAt: t.UpdatedAt}
+}
+
+type ListResponse struct {
+	resp.Response
+	Templates []Template `json:"templates"`
+}
+
+type SaveResponse struct {
+	resp.Response
+	Template
+}
+
+// List отдает шаблоны владельца: GET /utm-templates/{owner}
+func List(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.utm.List"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		owner := chi.URLParam(r, "owner")
+		if owner == "" {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty owner")
+			return
+		}
+
+		templates, err := service.ListUTMTemplates(r.Context(), owner)
+		if err != nil {
+			log.Error("failed to list utm templates", slogger.Err(err), slog.String("owner", owner))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		views := make([]Template, 0, len(templates))
+		for _, t := range templates {
+			views = append(views, NewTemplate(t))
+		}
+
+		render.JSON(w, r, ListResponse{Response: resp.OK(), Templates: views})
+	}
+}
+
+// Save создает или заменяет шаблон: PUT /utm-templates/{owner}/{name}, тело - объект utm-меток.
+// Ссылки, уже созданные по шаблону, не меняются.
+func Save(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.utm.Save"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		owner, name := chi.URLParam(r, "owner"), chi.URLParam(r, "name")
+		if owner == "" || name == "" {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty owner or name")
+			return
+		}
+
+		var utm postgres.UTM
+		if err := render.DecodeJSON(r.Body, &utm); err != nil {
+			log.Error("failed to decode request body", slogger.Err(err))
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request body")
+			return
+		}
+		if utm.IsEmpty() {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "template has no utm parameters")
+			return
+		}
+
+		saved, err := service.SaveUTMTemplate(r.Context(), postgres.UTMTemplate{Owner: owner, Name: name, UTM: utm})
+		if err != nil {
+			log.Error("failed to save utm template", slogger.Err(err), slog.String("owner", owner), slog.String("name", name))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		log.Info("utm template saved", slog.String("owner", owner), slog.String("name", name))
+
+		render.JSON(w, r, SaveResponse{Response: resp.OK(), Template: NewTemplate(saved)})
+	}
+}
+
+// Delete удаляет шаблон: DELETE /utm-templates/{owner}/{name}
+func Delete(log *slog.Logger, service service.ServiceInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.utm.Delete"
+		log := log.With(slog.String("op", op), slog.String("request_id", middleware.GetReqID(r.Context())))
+
+		owner, name := chi.URLParam(r, "owner"), chi.URLParam(r, "name")
+		if owner == "" || name == "" {
+			resp.WriteError(w, r, http.StatusBadRequest, resp.CodeInvalidParameter, "empty owner or name")
+			return
+		}
+
+		err := service.DeleteUTMTemplate(r.Context(), owner, name)
+		if errors.Is(err, storage.ErrUTMTemplateNotFound) {
+			resp.WriteError(w, r, http.StatusNotFound, resp.CodeNotFound, "utm template not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete utm template", slogger.Err(err), slog.String("owner", owner), slog.String("name", name))
+			resp.WriteError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal error")
+			return
+		}
+
+		log.Info("utm template deleted", slog.String("owner", owner), slog.String("name", name))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
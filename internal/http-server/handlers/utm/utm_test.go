@@ -0,0 +1,177 @@
+package utm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/handlers/utm"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func newRequest(t *testing.T, method, owner, name, body string) *http.Request {
+	req, err := http.NewRequest(method, "/utm-templates/"+owner+"/"+name, bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("owner", owner)
+	rctx.URLParams.Add("name", name)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func requireProblem(t *testing.T, rr *httptest.ResponseRecorder, code int, errCode string) {
+	require.Equal(t, code, rr.Code)
+	require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+	var problem response.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	require.Equal(t, errCode, problem.Code)
+}
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name         string
+		owner        string
+		body         string
+		mockBehavior func(s *mocks.ServiceInterface)
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name:  "Success",
+			owner: "team",
+			body:  `{"source":"newsletter","medium":"email"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveUTMTemplate", mock.Anything, postgres.UTMTemplate{
+					Owner: "team", Name: "mail", UTM: postgres.UTM{Source: "newsletter", Medium: "email"},
+				}).Return(postgres.UTMTemplate{
+					Owner: "team", Name: "mail", UTM: postgres.UTM{Source: "newsletter", Medium: "email"},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Empty owner",
+			body:         `{"source":"newsletter"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidParameter,
+		},
+		{
+			name:         "Invalid body",
+			owner:        "team",
+			body:         `{`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidBody,
+		},
+		{
+			name:         "No parameters",
+			owner:        "team",
+			body:         `{}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeValidationFailed,
+		},
+		{
+			name:  "Storage error",
+			owner: "team",
+			body:  `{"source":"newsletter"}`,
+			mockBehavior: func(s *mocks.ServiceInterface) {
+				s.On("SaveUTMTemplate", mock.Anything, mock.Anything).
+					Return(postgres.UTMTemplate{}, errors.New("db error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			tt.mockBehavior(serviceMock)
+
+			rr := httptest.NewRecorder()
+			utm.Save(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, newRequest(t, http.MethodPut, tt.owner, "mail", tt.body))
+
+			if tt.expectedCode != http.StatusOK {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			var resp utm.SaveResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "mail", resp.Name)
+			require.Equal(t, "newsletter", resp.UTM.Source)
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	serviceMock := mocks.NewServiceInterface(t)
+	serviceMock.On("ListUTMTemplates", mock.Anything, "team").Return([]postgres.UTMTemplate{
+		{Owner: "team", Name: "ads", UTM: postgres.UTM{Source: "google", Medium: "cpc"}},
+		{Owner: "team", Name: "mail", UTM: postgres.UTM{Source: "newsletter"}},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	utm.List(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, newRequest(t, http.MethodGet, "team", "", ""))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp utm.ListResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Templates, 2)
+	require.Equal(t, "cpc", resp.Templates[0].UTM.Medium)
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedErr  string
+	}{
+		{name: "Success", expectedCode: http.StatusOK},
+		{
+			name:         "Not found",
+			err:          fmt.Errorf("op: %w", storage.ErrUTMTemplateNotFound),
+			expectedCode: http.StatusNotFound,
+			expectedErr:  response.CodeNotFound,
+		},
+		{
+			name:         "Storage error",
+			err:          errors.New("db error"),
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMock := mocks.NewServiceInterface(t)
+			serviceMock.On("DeleteUTMTemplate", mock.Anything, "team", "mail").Return(tt.err)
+
+			rr := httptest.NewRecorder()
+			utm.Delete(slogdiscard.NewDiscardLogger(), serviceMock).ServeHTTP(rr, newRequest(t, http.MethodDelete, "team", "mail", ""))
+
+			if tt.expectedCode != http.StatusOK {
+				requireProblem(t, rr, tt.expectedCode, tt.expectedErr)
+				return
+			}
+			require.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+	"url-shortener/internal/lib/tracing"
 
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -23,6 +24,7 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("trace_id", tracing.TraceIDFromContext(r.Context())),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
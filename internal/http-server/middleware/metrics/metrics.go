@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute - метка для запросов, не попавших ни в один маршрут, чтобы не плодить серии по путям
+const unmatchedRoute = "unmatched"
+
+// New считает запросы и их длительность по шаблону маршрута chi и статусу ответа
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			defer func() {
+				route := unmatchedRoute
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				labels := []string{r.Method, route, strconv.Itoa(status)}
+
+				metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+				metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
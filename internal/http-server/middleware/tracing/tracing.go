@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"net/http"
+	"url-shortener/internal/lib/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// New открывает серверный спан на каждый запрос. Родитель берется из входящего traceparent,
+// request_id от middleware.RequestID пишется в атрибуты, а traceparent спана
+// возвращается в ответе, чтобы клиент мог найти трейс.
+// Должен стоять после middleware.RequestID.
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			opts := []tracing.StartOption{
+				tracing.WithKind(tracing.KindServer),
+				tracing.WithAttributes(
+					tracing.String("http.request.method", r.Method),
+					tracing.String("url.path", r.URL.Path),
+					tracing.String("request_id", middleware.GetReqID(r.Context())),
+				),
+			}
+			if parent, ok := tracing.Extract(r.Header); ok {
+				opts = append(opts, tracing.WithRemoteParent(parent))
+			}
+
+			ctx, span := tracing.Start(r.Context(), r.Method, opts...)
+			defer span.End()
+
+			tracing.Inject(ctx, w.Header())
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+					span.SetName(r.Method + " " + rctx.RoutePattern())
+					span.SetAttributes(tracing.String("http.route", rctx.RoutePattern()))
+				}
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				span.SetAttributes(tracing.Int("http.response.status_code", status))
+				if status >= http.StatusInternalServerError {
+					span.SetStatus(tracing.StatusError, http.StatusText(status))
+				}
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
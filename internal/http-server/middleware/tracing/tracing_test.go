@@ -0,0 +1,55 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mwtracing "url-shortener/internal/http-server/middleware/tracing"
+	"url-shortener/internal/lib/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	rec := &tracing.Recorder{}
+	tracing.SetTracer(tracing.NewTracer(rec))
+	defer tracing.SetTracer(tracing.NewTracer(nil))
+
+	var handlerSpan tracing.SpanContext
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(mwtracing.New())
+	router.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "handlers.url.redirect.New")
+		handlerSpan = span.SpanContext()
+		span.End()
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/gh", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+	server := spans[1]
+
+	require.Equal(t, "GET /{alias}", server.Name)
+	require.Equal(t, tracing.KindServer, server.Kind)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID.String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+	require.Contains(t, server.Attributes, tracing.String("request_id", "req-42"))
+	require.Contains(t, server.Attributes, tracing.String("http.route", "/{alias}"))
+	require.Contains(t, server.Attributes, tracing.Int("http.response.status_code", http.StatusFound))
+
+	require.Equal(t, server.SpanContext.SpanID, spans[0].Parent)
+	require.Equal(t, server.SpanContext.TraceID, handlerSpan.TraceID)
+	require.Equal(t, tracing.FormatTraceparent(server.SpanContext), rr.Header().Get("traceparent"))
+}
@@ -0,0 +1,117 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ProblemContentType - тип ответа с ошибкой по RFC 9457
+const ProblemContentType = "application/problem+json"
+
+// Стабильные машиночитаемые коды ошибок. Клиенты опираются на code, а не на текст detail.
+const (
+	CodeInvalidBody      = "invalid_body"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeNotFound         = "not_found"
+	CodeGone             = "gone"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeAlreadyExists    = "already_exists"
+	CodeTooManyItems     = "too_many_items"
+	CodeEmptyFilter      = "empty_filter"
+	CodeBatchRejected    = "batch_rejected"
+	CodeBatchRolledBack  = "batch_rolled_back"
+	CodeImportConflict   = "import_conflict"
+	CodeDomainInUse      = "domain_in_use"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal_error"
+)
+
+// Problem - тело ошибки по RFC 9457. Extensions добавляются в объект верхнего уровня.
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Code       string         `json:"code"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	body, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+	ext, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	// {"type":...} + {"results":...} -> {"type":...,"results":...}
+	return append(append(body[:len(body)-1], ','), ext[1:]...), nil
+}
+
+var legacyErrors atomic.Bool
+
+// SetLegacyErrors включает старый формат {"status":"Error","error":"..."} для клиентов,
+// которые еще не перешли на problem+json. HTTP-статусы в обоих режимах одинаковые.
+func SetLegacyErrors(enabled bool) {
+	legacyErrors.Store(enabled)
+}
+
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	WriteErrorExt(w, r, status, code, detail, nil)
+}
+
+// WriteErrorExt пишет ошибку с дополнительными полями, например результатами по элементам пачки
+func WriteErrorExt(w http.ResponseWriter, r *http.Request, status int, code, detail string, ext map[string]any) {
+	if legacyErrors.Load() {
+		body := make(map[string]any, len(ext)+2)
+		for k, v := range ext {
+			body[k] = v
+		}
+		body["status"] = StatusError
+		body["error"] = detail
+		render.Status(r, status)
+		render.JSON(w, r, body)
+		return
+	}
+
+	p := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     detail,
+		Instance:   r.URL.Path,
+		Code:       code,
+		RequestID:  middleware.GetReqID(r.Context()),
+		Extensions: ext,
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(true)
+	if err := enc.Encode(p); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// NotFound и MethodNotAllowed заменяют текстовые ответы chi для неизвестных маршрутов
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusNotFound, CodeNotFound, "route not found")
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
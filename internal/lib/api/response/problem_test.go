@@ -0,0 +1,82 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name         string
+		legacy       bool
+		ext          map[string]any
+		expectedType string
+		expectedBody map[string]any
+	}{
+		{
+			name:         "Problem",
+			expectedType: ProblemContentType,
+			expectedBody: map[string]any{
+				"type":       "about:blank",
+				"title":      "Conflict",
+				"status":     float64(http.StatusConflict),
+				"detail":     "url already exists",
+				"instance":   "/url",
+				"code":       CodeAlreadyExists,
+				"request_id": "test-request-id",
+			},
+		},
+		{
+			name:         "Problem with extensions",
+			ext:          map[string]any{"results": []int{1}},
+			expectedType: ProblemContentType,
+			expectedBody: map[string]any{
+				"type":       "about:blank",
+				"title":      "Conflict",
+				"status":     float64(http.StatusConflict),
+				"detail":     "url already exists",
+				"instance":   "/url",
+				"code":       CodeAlreadyExists,
+				"request_id": "test-request-id",
+				"results":    []any{float64(1)},
+			},
+		},
+		{
+			name:         "Legacy",
+			legacy:       true,
+			ext:          map[string]any{"results": []int{1}},
+			expectedType: "application/json",
+			expectedBody: map[string]any{
+				"status":  StatusError,
+				"error":   "url already exists",
+				"results": []any{float64(1)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLegacyErrors(tt.legacy)
+			t.Cleanup(func() { SetLegacyErrors(false) })
+
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "test-request-id"))
+			rr := httptest.NewRecorder()
+
+			WriteErrorExt(rr, req, http.StatusConflict, CodeAlreadyExists, "url already exists", tt.ext)
+
+			require.Equal(t, http.StatusConflict, rr.Code)
+			require.Contains(t, rr.Header().Get("Content-Type"), tt.expectedType)
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tt.expectedBody, body)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Package domain приводит имена доменов к виду, в котором они хранятся в таблице domains
+package domain
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Normalize - нижний регистр и без точки в конце: Go.Brand.com. и go.brand.com - один домен
+func Normalize(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// FromHost возвращает домен из заголовка Host без порта
+func FromHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return Normalize(host)
+}
+
+// FromQuery возвращает домен из параметра domain. Пустая строка - основной домен.
+func FromQuery(r *http.Request) string {
+	return Normalize(r.URL.Query().Get("domain"))
+}
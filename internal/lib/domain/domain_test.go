@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "go.brand-a.com", expected: "go.brand-a.com"},
+		{host: "Go.Brand-A.com:8080", expected: "go.brand-a.com"},
+		{host: "go.brand-a.com.", expected: "go.brand-a.com"},
+		{host: "localhost:8082", expected: "localhost"},
+		{host: "[::1]:8082", expected: "::1"},
+		{host: "", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			require.Equal(t, tt.expected, FromHost(tt.host))
+		})
+	}
+}
@@ -0,0 +1,367 @@
+// Package geoip читает базы в формате MaxMind DB (GeoLite2-Country, GeoIP2-City и т.п.)
+// и определяет страну клиента. Поддерживается только то, что нужно для поиска страны:
+// дерево поиска и декодер секции данных, без проверки метаданных о языках и описаниях.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+var (
+	ErrInvalidDatabase = errors.New("invalid maxmind database")
+	errNotFound        = errors.New("address not found")
+)
+
+// metadataMarker предшествует метаданным в конце файла
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator - 16 нулевых байт между деревом и секцией данных
+const dataSectionSeparator = 16
+
+// Reader - база, целиком загруженная в память. Безопасен для конкурентного чтения.
+type Reader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start - узел, с которого начинается поиск IPv4 в дереве IPv6 (::/96)
+	ipv4Start uint
+}
+
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buf)
+}
+
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start == -1 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	meta := buf[start+len(metadataMarker):]
+	raw, _, err := decoder{buf: meta}.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	fields, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &Reader{
+		buf:        buf,
+		nodeCount:  uintField(fields, "node_count"),
+		recordSize: uintField(fields, "record_size"),
+		ipVersion:  uintField(fields, "ip_version"),
+	}
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", ErrInvalidDatabase, r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start) {
+		return nil, fmt.Errorf("%w: search tree is truncated", ErrInvalidDatabase)
+	}
+	r.data = buf[treeSize+dataSectionSeparator : start]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup возвращает запись для адреса. ok=false, если адреса нет в базе.
+func (r *Reader) Lookup(addr netip.Addr) (record any, ok bool, err error) {
+	offset, err := r.find(addr)
+	if errors.Is(err, errNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	record, _, err = decoder{buf: r.data}.decode(offset)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	return record, true, nil
+}
+
+// Country возвращает ISO-код страны (country.iso_code) или пустую строку
+func (r *Reader) Country(addr netip.Addr) (string, error) {
+	record, ok, err := r.Lookup(addr)
+	if err != nil || !ok {
+		return "", err
+	}
+	fields, _ := record.(map[string]any)
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := fields[key].(map[string]any)
+		if code, _ := country["iso_code"].(string); code != "" {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+// find проходит дерево по битам адреса и возвращает смещение записи в секции данных
+func (r *Reader) find(addr netip.Addr) (uint, error) {
+	addr = addr.Unmap()
+	var ip []byte
+	node := uint(0)
+	switch {
+	case addr.Is4() && r.ipVersion == 6:
+		ip, node = addr.AsSlice(), r.ipv4Start
+	case addr.Is4() || r.ipVersion == 6:
+		ip = addr.AsSlice()
+	default:
+		// IPv6 в базе только с IPv4
+		return 0, errNotFound
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return 0, errNotFound
+	}
+	if node < r.nodeCount {
+		return 0, fmt.Errorf("%w: search tree is too deep", ErrInvalidDatabase)
+	}
+	offset := node - r.nodeCount - dataSectionSeparator
+	if offset >= uint(len(r.data)) {
+		return 0, fmt.Errorf("%w: record points outside data section", ErrInvalidDatabase)
+	}
+	return offset, nil
+}
+
+// record читает левую (bit=0) или правую (bit=1) запись узла
+func (r *Reader) record(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		// Старшие 4 бита обеих записей лежат в среднем байте узла
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+func uintField(fields map[string]any, key string) uint {
+	v, _ := fields[key].(uint64)
+	return uint(v)
+}
+
+// Типы полей секции данных
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+// maxDepth ограничивает вложенность, чтобы испорченный файл не уводил в бесконечную рекурсию
+const maxDepth = 32
+
+// decoder читает значения секции данных. Указатели отсчитываются от начала buf.
+// Целые числа возвращаются как uint64 или int64, uint128 - как []byte.
+type decoder struct {
+	buf   []byte
+	depth int
+}
+
+func (d decoder) decode(offset uint) (any, uint, error) {
+	if d.depth > maxDepth {
+		return nil, 0, errors.New("data is nested too deep")
+	}
+	typ, size, offset, err := d.header(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		value, _, err := decoder{buf: d.buf, depth: d.depth + 1}.decode(size)
+		return value, offset, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, min(size, 1024))
+		inner := decoder{buf: d.buf, depth: d.depth + 1}
+		for range size {
+			key, next, err := inner.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			value, next, err := inner.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 1024))
+		inner := decoder{buf: d.buf, depth: d.depth + 1}
+		for range size {
+			value, next, err := inner.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errors.New("value is truncated")
+	}
+	b := d.buf[offset:end]
+	switch typ {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes, typeUint128:
+		return bytes.Clone(b), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errors.New("invalid integer size")
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errors.New("invalid int32 size")
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		// Короткие int32 дополняются нулями слева, знак берется из полного значения
+		return int64(int32(v)), end, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
+
+// header разбирает управляющий байт: тип, размер (для указателя - адрес) и начало значения
+func (d decoder) header(offset uint) (typ, size, next uint, err error) {
+	byteAt := func(i uint) (uint, error) {
+		if i >= uint(len(d.buf)) {
+			return 0, errors.New("value is truncated")
+		}
+		return uint(d.buf[i]), nil
+	}
+	readN := func(n uint) (uint, error) {
+		var v uint
+		for i := range n {
+			c, err := byteAt(offset + i)
+			if err != nil {
+				return 0, err
+			}
+			v = v<<8 | c
+		}
+		offset += n
+		return v, nil
+	}
+
+	ctrl, err := byteAt(offset)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	offset++
+	typ = ctrl >> 5
+
+	if typ == typePointer {
+		n := (ctrl >> 3) & 0x3
+		v, err := readN(n + 1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		switch n {
+		case 0:
+			size = (ctrl&0x7)<<8 | v
+		case 1:
+			size = ((ctrl&0x7)<<16 | v) + 2048
+		case 2:
+			size = ((ctrl&0x7)<<24 | v) + 526336
+		default:
+			size = v
+		}
+		return typ, size, offset, nil
+	}
+
+	if typ == typeExtended {
+		ext, err := readN(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		typ = 7 + ext
+	}
+
+	// Размеры от 29 и больше продолжаются в следующих 1-3 байтах
+	size = ctrl & 0x1f
+	if size >= 29 {
+		n := size - 28
+		v, err := readN(n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		size = []uint{29, 285, 65821}[n-1] + v
+	}
+	return typ, size, offset, nil
+}
@@ -0,0 +1,205 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testDB собирает минимальную базу MaxMind DB: сети с кодом страны и метаданные.
+// Записи второй и последующих сетей ссылаются на ключи первой через указатели.
+func testDB(t *testing.T, ipVersion, recordSize int, networks map[string]string) []byte {
+	t.Helper()
+
+	// nodes[i] - левый и правый потомок: 0 - пусто, >0 - узел, <0 - -(смещение данных + 1)
+	nodes := [][2]int{{0, 0}}
+	var data bytes.Buffer
+	keyOffsets := map[string]int{}
+
+	str := func(s string) {
+		if off, ok := keyOffsets[s]; ok {
+			data.Write([]byte{typePointer<<5 | byte(off>>8), byte(off)})
+			return
+		}
+		keyOffsets[s] = data.Len()
+		data.WriteByte(typeString<<5 | byte(len(s)))
+		data.WriteString(s)
+	}
+
+	for cidr, country := range networks {
+		prefix := netip.MustParsePrefix(cidr)
+		addr, bits := prefix.Addr().AsSlice(), prefix.Bits()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			addr, bits = append(make([]byte, 12), addr...), bits+96
+		}
+
+		offset := data.Len()
+		data.WriteByte(typeMap<<5 | 1)
+		str("country")
+		data.WriteByte(typeMap<<5 | 1)
+		str("iso_code")
+		str(country)
+
+		node := 0
+		for i := 0; i < bits; i++ {
+			bit := int(addr[i/8]>>(7-i%8)) & 1
+			if i == bits-1 {
+				nodes[node][bit] = -(offset + 1)
+				break
+			}
+			if nodes[node][bit] <= 0 {
+				nodes = append(nodes, [2]int{0, 0})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	value := func(v int) uint32 {
+		switch {
+		case v == 0:
+			return uint32(nodeCount)
+		case v < 0:
+			return uint32(nodeCount + dataSectionSeparator - v - 1)
+		}
+		return uint32(v)
+	}
+
+	var db bytes.Buffer
+	for _, n := range nodes {
+		left, right := value(n[0]), value(n[1])
+		switch recordSize {
+		case 24:
+			db.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			db.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(left>>20)&0xF0 | byte(right>>24)&0x0F,
+				byte(right >> 16), byte(right >> 8), byte(right)})
+		case 32:
+			db.Write(binary.BigEndian.AppendUint32(nil, left))
+			db.Write(binary.BigEndian.AppendUint32(nil, right))
+		}
+	}
+	db.Write(make([]byte, dataSectionSeparator))
+	db.Write(data.Bytes())
+
+	db.Write(metadataMarker)
+	db.WriteByte(typeMap<<5 | 3)
+	for _, field := range []struct {
+		key   string
+		typ   byte
+		value uint32
+	}{
+		{"node_count", typeUint32, uint32(nodeCount)},
+		{"record_size", typeUint16, uint32(recordSize)},
+		{"ip_version", typeUint16, uint32(ipVersion)},
+	} {
+		db.WriteByte(typeString<<5 | byte(len(field.key)))
+		db.WriteString(field.key)
+		db.WriteByte(field.typ<<5 | 4)
+		db.Write(binary.BigEndian.AppendUint32(nil, field.value))
+	}
+	return db.Bytes()
+}
+
+func TestReaderCountry(t *testing.T) {
+	networks := map[string]string{
+		"81.2.69.0/24":     "GB",
+		"89.160.20.128/25": "SE",
+		"2001:db8::/32":    "DE",
+	}
+
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			nets := networks
+			if ipVersion == 4 {
+				nets = map[string]string{"81.2.69.0/24": "GB", "89.160.20.128/25": "SE"}
+			}
+			reader, err := FromBytes(testDB(t, ipVersion, recordSize, nets))
+			require.NoError(t, err)
+
+			cases := []struct {
+				addr    string
+				country string
+			}{
+				{"81.2.69.142", "GB"},
+				{"89.160.20.200", "SE"},
+				{"89.160.20.1", ""},
+				{"10.0.0.1", ""},
+				{"::ffff:81.2.69.1", "GB"},
+			}
+			if ipVersion == 6 {
+				cases = append(cases, struct{ addr, country string }{"2001:db8::1", "DE"})
+			} else {
+				cases = append(cases, struct{ addr, country string }{"2001:db8::1", ""})
+			}
+
+			for _, tc := range cases {
+				country, err := reader.Country(netip.MustParseAddr(tc.addr))
+				require.NoError(t, err)
+				require.Equal(t, tc.country, country, "ipv%d record %d: %s", ipVersion, recordSize, tc.addr)
+			}
+		}
+	}
+}
+
+func TestFromBytesInvalid(t *testing.T) {
+	_, err := FromBytes([]byte("not a database"))
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+
+	db := testDB(t, 4, 24, map[string]string{"81.2.69.0/24": "GB"})
+	// Дерево обрезано: метаданные обещают больше узлов, чем есть в файле
+	truncated := append(bytes.Clone(db[:10]), db[bytes.LastIndex(db, metadataMarker):]...)
+	_, err = FromBytes(truncated)
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+}
+
+func TestResolverCountry(t *testing.T) {
+	reader, err := FromBytes(testDB(t, 6, 28, map[string]string{"81.2.69.0/24": "GB", "2001:db8::/32": "DE"}))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		resolver   *Resolver
+		remoteAddr string
+		headers    map[string]string
+		country    string
+	}{
+		{name: "Remote address", resolver: &Resolver{DB: reader}, remoteAddr: "81.2.69.1:5555", country: "GB"},
+		{name: "IPv6 remote address", resolver: &Resolver{DB: reader}, remoteAddr: "[2001:db8::5]:443", country: "DE"},
+		{
+			name:       "Trusted country header wins",
+			resolver:   &Resolver{DB: reader, CountryHeader: "CF-IPCountry"},
+			remoteAddr: "81.2.69.1:5555",
+			headers:    map[string]string{"CF-IPCountry": "se"},
+			country:    "SE",
+		},
+		{
+			name:       "Client ip from header",
+			resolver:   &Resolver{DB: reader, IPHeader: "X-Forwarded-For"},
+			remoteAddr: "10.0.0.1:5555",
+			headers:    map[string]string{"X-Forwarded-For": "81.2.69.7, 10.0.0.2"},
+			country:    "GB",
+		},
+		{name: "Unknown address", resolver: &Resolver{DB: reader}, remoteAddr: "10.0.0.1:5555"},
+		{name: "No database", resolver: &Resolver{CountryHeader: "CF-IPCountry"}, remoteAddr: "81.2.69.1:5555"},
+		{name: "Nil resolver", remoteAddr: "81.2.69.1:5555"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			country, err := tc.resolver.Country(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.country, country)
+		})
+	}
+}
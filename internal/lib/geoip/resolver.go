@@ -0,0 +1,55 @@
+package geoip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver определяет страну клиента. Доверенный заголовок от CDN или балансировщика
+// (например CF-IPCountry) имеет приоритет, без него страна ищется в базе по IP клиента.
+// IP берется из IPHeader (первый адрес списка), если он задан, иначе из RemoteAddr.
+type Resolver struct {
+	DB            *Reader
+	CountryHeader string
+	IPHeader      string
+}
+
+// Country возвращает ISO-код страны в верхнем регистре или пустую строку, если страну определить нельзя
+func (g *Resolver) Country(r *http.Request) (string, error) {
+	if g == nil {
+		return "", nil
+	}
+	if g.CountryHeader != "" {
+		if country := strings.TrimSpace(r.Header.Get(g.CountryHeader)); country != "" {
+			return strings.ToUpper(country), nil
+		}
+	}
+	if g.DB == nil {
+		return "", nil
+	}
+	addr, ok := g.ClientIP(r)
+	if !ok {
+		return "", nil
+	}
+	country, err := g.DB.Country(addr)
+	return strings.ToUpper(country), err
+}
+
+// ClientIP возвращает IP клиента с учетом IPHeader. Для nil берется RemoteAddr.
+func (g *Resolver) ClientIP(r *http.Request) (netip.Addr, bool) {
+	if g != nil && g.IPHeader != "" {
+		if value := r.Header.Get(g.IPHeader); value != "" {
+			first, _, _ := strings.Cut(value, ",")
+			addr, err := netip.ParseAddr(strings.TrimSpace(first))
+			return addr, err == nil
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr, err == nil
+}
@@ -49,13 +49,13 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 	fields := make(map[string]interface{}, r.NumAttrs())
 
 	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = value(a.Value)
 
 		return true
 	})
 
 	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = value(a.Value)
 	}
 
 	var b []byte
@@ -85,10 +85,23 @@ func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &PrettyHandler{
 		Handler: h.Handler,
 		l:       h.l,
-		attrs:   attrs,
+		attrs:   append(append([]slog.Attr(nil), h.attrs...), attrs...),
 	}
 }
 
+// value раскрывает группы в вложенные объекты, иначе они печатаются пустыми
+func value(v slog.Value) any {
+	v = v.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	group := make(map[string]any, len(v.Group()))
+	for _, a := range v.Group() {
+		group[a.Key] = value(a.Value)
+	}
+	return group
+}
+
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
 	return &PrettyHandler{
 		Handler: h.Handler.WithGroup(name),
@@ -0,0 +1,135 @@
+// Package slogredact скрывает чувствительные данные в логах: значения атрибутов
+// с ключами вроде password или api_key и секреты в query-параметрах URL.
+package slogredact
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// sensitive - подстроки имен атрибутов и query-параметров, значения которых нельзя писать в лог
+var sensitive = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey", "api-key",
+	"authorization", "cookie", "session", "signature", "credential",
+}
+
+type Handler struct {
+	next slog.Handler
+}
+
+func New(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = Attr(a)
+	}
+	return New(h.next.WithAttrs(redacted))
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return New(h.next.WithGroup(name))
+}
+
+// Attr возвращает атрибут со скрытыми секретами. Группы и LogValuer обходятся рекурсивно.
+func Attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if IsSensitive(a.Key) && !isEmpty(a.Value) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = Attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindString:
+		return slog.String(a.Key, URL(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case *url.URL:
+			if v != nil {
+				return slog.String(a.Key, URL(v.String()))
+			}
+		case url.URL:
+			return slog.String(a.Key, URL(v.String()))
+		}
+	}
+	return a
+}
+
+// IsSensitive сообщает, содержит ли имя атрибута или параметра секрет
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitive {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL скрывает пароль в userinfo и значения чувствительных query-параметров.
+// Строки, которые не похожи на URL с query или userinfo, возвращаются без изменений.
+func URL(raw string) string {
+	if !strings.Contains(raw, "://") || (!strings.Contains(raw, "?") && !strings.Contains(raw, "@")) {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+		changed = true
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			key, _, found := strings.Cut(param, "=")
+			name, err := url.QueryUnescape(key)
+			if err != nil {
+				name = key
+			}
+			if found && IsSensitive(name) {
+				params[i] = key + "=" + Redacted
+				changed = true
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	if !changed {
+		return raw
+	}
+	return u.String()
+}
+
+func isEmpty(v slog.Value) bool {
+	return v.Kind() == slog.KindString && v.String() == ""
+}
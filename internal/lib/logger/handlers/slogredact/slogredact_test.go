@@ -0,0 +1,75 @@
+package slogredact
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURL(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "No query", in: "https://example.com/path", want: "https://example.com/path"},
+		{name: "Safe query", in: "https://example.com/?utm_source=mail&page=2", want: "https://example.com/?utm_source=mail&page=2"},
+		{
+			name: "Token in query",
+			in:   "https://example.com/cb?access_token=abc123&page=2",
+			want: "https://example.com/cb?access_token=" + Redacted + "&page=2",
+		},
+		{
+			name: "Escaped key and repeated params",
+			in:   "https://example.com/?api%5Fkey=k1&x=1&API_KEY=k2",
+			want: "https://example.com/?api%5Fkey=" + Redacted + "&x=1&API_KEY=" + Redacted,
+		},
+		{name: "Password in userinfo", in: "postgres://user:pa55@db:5432/app", want: "postgres://user:REDACTED@db:5432/app"},
+		{name: "Not a url", in: "what?token=1", want: "what?token=1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, URL(tc.in))
+		})
+	}
+}
+
+type request struct {
+	URL string
+}
+
+func (r request) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("url", r.URL))
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(New(slog.NewJSONHandler(&buf, nil))).With(slog.String("api_key", "k-123"))
+
+	target, _ := url.Parse("https://example.com/?token=t1")
+	log.WithGroup("req").Info("request",
+		slog.String("Authorization", "Bearer xyz"),
+		slog.String("password", ""),
+		slog.String("url", "https://example.com/?sid=1&secret=s"),
+		slog.Any("target", target),
+		slog.Any("request", request{URL: "https://example.com/?token=t2"}),
+		slog.String("signal", "terminated"),
+	)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	require.Equal(t, Redacted, got["api_key"])
+	req := got["req"].(map[string]any)
+	require.Equal(t, Redacted, req["Authorization"])
+	require.Equal(t, "", req["password"])
+	require.Equal(t, "https://example.com/?sid=1&secret="+Redacted, req["url"])
+	require.Equal(t, "https://example.com/?token="+Redacted, req["target"])
+	require.Equal(t, map[string]any{"url": "https://example.com/?token=" + Redacted}, req["request"])
+	require.Equal(t, "terminated", req["signal"])
+}
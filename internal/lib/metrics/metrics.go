@@ -0,0 +1,363 @@
+// Package metrics - минимальная реализация метрик в текстовом формате Prometheus
+// (https://prometheus.io/docs/instrumenting/exposition_formats/) без внешних зависимостей.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets - границы гистограммы по умолчанию, как в client_golang
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// labelSep разделяет значения меток в ключе серии
+const labelSep = "\xff"
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry хранит метрики и отдает их в текстовом формате
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (reg *Registry) register(c collector) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.collectors = append(reg.collectors, c)
+}
+
+// WriteTo пишет все метрики в порядке регистрации
+func (reg *Registry) WriteTo(w io.Writer) (int64, error) {
+	reg.mu.Lock()
+	collectors := append([]collector(nil), reg.collectors...)
+	reg.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler отдает метрики для scrape-запросов Prometheus
+func (reg *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		reg.WriteTo(w)
+	}
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + d.name + " " + escapeHelp(d.help) + "\n")
+	w.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+}
+
+// Counter - монотонно растущее значение
+type Counter struct {
+	bits atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add увеличивает счетчик. Отрицательные значения игнорируются.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	addFloat(&c.bits, v)
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec - набор счетчиков с одинаковыми именами меток
+type CounterVec struct {
+	desc
+	mu     sync.RWMutex
+	series map[string]*Counter
+}
+
+func (reg *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{
+		desc:   desc{name: name, help: help, typ: typeCounter, labels: labels},
+		series: make(map[string]*Counter),
+	}
+	reg.register(cv)
+	return cv
+}
+
+// NewCounter регистрирует счетчик без меток
+func (reg *Registry) NewCounter(name, help string) *Counter {
+	return reg.NewCounterVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues возвращает счетчик для значений меток в порядке их объявления
+func (cv *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := seriesKey(cv.labels, values)
+
+	cv.mu.RLock()
+	c, ok := cv.series[key]
+	cv.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	if c, ok = cv.series[key]; !ok {
+		c = &Counter{}
+		cv.series[key] = c
+	}
+	return c
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	cv.writeHeader(w)
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+	for _, key := range sortedKeys(cv.series) {
+		writeSample(w, cv.name, cv.labels, splitKey(key), cv.series[key].Value())
+	}
+}
+
+// Histogram считает наблюдения по корзинам
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// HistogramVec - набор гистограмм с одинаковыми корзинами и именами меток
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[string]*Histogram
+}
+
+func (reg *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	hv := &HistogramVec{
+		desc:    desc{name: name, help: help, typ: typeHistogram, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*Histogram),
+	}
+	reg.register(hv)
+	return hv
+}
+
+func (hv *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := seriesKey(hv.labels, values)
+
+	hv.mu.RLock()
+	h, ok := hv.series[key]
+	hv.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	if h, ok = hv.series[key]; !ok {
+		h = &Histogram{buckets: hv.buckets, counts: make([]uint64, len(hv.buckets))}
+		hv.series[key] = h
+	}
+	return h
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	hv.writeHeader(w)
+	hv.mu.RLock()
+	defer hv.mu.RUnlock()
+
+	bucketLabels := append(append([]string(nil), hv.labels...), "le")
+	for _, key := range sortedKeys(hv.series) {
+		values := splitKey(key)
+		h := hv.series[key]
+
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		for i, upper := range hv.buckets {
+			writeSample(w, hv.name+"_bucket", bucketLabels, append(values, formatFloat(upper)), float64(counts[i]))
+		}
+		writeSample(w, hv.name+"_bucket", bucketLabels, append(values, "+Inf"), float64(count))
+		writeSample(w, hv.name+"_sum", hv.labels, values, sum)
+		writeSample(w, hv.name+"_count", hv.labels, values, float64(count))
+	}
+}
+
+// Sample - одно значение метрики, которую считает функция при каждом scrape
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+type funcCollector struct {
+	desc
+	fn func() []Sample
+}
+
+// NewGaugeFunc регистрирует gauge, значение которого вычисляется при каждом scrape
+func (reg *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	reg.NewFunc(name, help, typeGauge, nil, func() []Sample {
+		return []Sample{{Value: fn()}}
+	})
+}
+
+// NewCounterFunc регистрирует счетчик, который ведется вне реестра, например статистика пула
+func (reg *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	reg.NewFunc(name, help, typeCounter, nil, func() []Sample {
+		return []Sample{{Value: fn()}}
+	})
+}
+
+// NewGaugeVecFunc регистрирует gauge с метками, серии которого вычисляются при каждом scrape
+func (reg *Registry) NewGaugeVecFunc(name, help string, labels []string, fn func() []Sample) {
+	reg.NewFunc(name, help, typeGauge, labels, fn)
+}
+
+func (reg *Registry) NewFunc(name, help, typ string, labels []string, fn func() []Sample) {
+	reg.register(&funcCollector{desc: desc{name: name, help: help, typ: typ, labels: labels}, fn: fn})
+}
+
+func (fc *funcCollector) write(w *bufio.Writer) {
+	fc.writeHeader(w)
+	for _, s := range fc.fn() {
+		writeSample(w, fc.name, fc.labels, s.LabelValues, s.Value)
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			w.WriteString(label + `="` + escapeLabel(value) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpReplacer.Replace(s) }
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
+
+func seriesKey(labels, values []string) string {
+	if len(values) != len(labels) {
+		panic("metrics: expected " + strconv.Itoa(len(labels)) + " label values, got " + strconv.Itoa(len(values)))
+	}
+	return strings.Join(values, labelSep)
+}
+
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, labelSep)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
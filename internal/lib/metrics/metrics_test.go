@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryExposition(t *testing.T) {
+	reg := NewRegistry()
+
+	requests := reg.NewCounterVec("http_requests_total", "Total requests.", "route", "status")
+	requests.WithLabelValues("/url", "200").Inc()
+	requests.WithLabelValues("/url", "200").Add(2)
+	requests.WithLabelValues(`/{alias}`, "404").Inc()
+	requests.WithLabelValues("/url", "500").Add(-1)
+
+	attempts := reg.NewCounter("alias_attempts_total", "Alias generation attempts.")
+	attempts.Inc()
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.5, 0.1}, "route")
+	latency.WithLabelValues("/url").Observe(0.05)
+	latency.WithLabelValues("/url").Observe(0.3)
+	latency.WithLabelValues("/url").Observe(2)
+
+	reg.NewGaugeFunc("pool_idle", "Idle connections.", func() float64 { return 3 })
+	reg.NewGaugeVecFunc("cache_hit_ratio", "Hit ratio.", []string{"cache"}, func() []Sample {
+		return []Sample{{LabelValues: []string{`say "hi"`}, Value: 0.75}}
+	})
+
+	var sb strings.Builder
+	_, err := reg.WriteTo(&sb)
+	require.NoError(t, err)
+
+	require.Equal(t, `# HELP http_requests_total Total requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/url",status="200"} 3
+http_requests_total{route="/url",status="500"} 0
+http_requests_total{route="/{alias}",status="404"} 1
+# HELP alias_attempts_total Alias generation attempts.
+# TYPE alias_attempts_total counter
+alias_attempts_total 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/url",le="0.1"} 1
+latency_seconds_bucket{route="/url",le="0.5"} 2
+latency_seconds_bucket{route="/url",le="+Inf"} 3
+latency_seconds_sum{route="/url"} 2.35
+latency_seconds_count{route="/url"} 3
+# HELP pool_idle Idle connections.
+# TYPE pool_idle gauge
+pool_idle 3
+# HELP cache_hit_ratio Hit ratio.
+# TYPE cache_hit_ratio gauge
+cache_hit_ratio{cache="say \"hi\""} 0.75
+`, sb.String())
+}
+
+func TestWithLabelValuesArity(t *testing.T) {
+	reg := NewRegistry()
+	cv := reg.NewCounterVec("x_total", "x", "a", "b")
+	require.Panics(t, func() { cv.WithLabelValues("only-one") })
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("up_total", "up").Inc()
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"))
+	require.Contains(t, rr.Body.String(), "up_total 1\n")
+}
@@ -0,0 +1,525 @@
+// Package qr кодирует данные в QR-код (ISO/IEC 18004) в байтовом режиме
+// и рисует его в PNG или SVG без внешних зависимостей.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level - уровень коррекции ошибок: какая доля кода может быть повреждена
+type Level int
+
+const (
+	LevelL Level = iota // ~7%
+	LevelM              // ~15%
+	LevelQ              // ~25%
+	LevelH              // ~30%
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+var ErrTooLong = errors.New("data too long for a qr code")
+
+// formatBits - биты уровня в служебной информации о формате
+var formatBits = [...]int{LevelL: 1, LevelM: 0, LevelQ: 3, LevelH: 2}
+
+// eccPerBlock и blocks - число байт коррекции в блоке и число блоков по уровням и версиям
+var eccPerBlock = [4][maxVersion + 1]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var blocks = [4][maxVersion + 1]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// ParseLevel разбирает уровень коррекции: L, M, Q или H в любом регистре
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	}
+	return 0, fmt.Errorf("unknown error correction level %q, expected L, M, Q or H", s)
+}
+
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// Code - матрица модулей QR-кода без отступа вокруг
+type Code struct {
+	Size     int
+	Version  int
+	modules  []bool
+	function []bool
+}
+
+// Dark сообщает, закрашен ли модуль в столбце x и строке y
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y*c.Size+x]
+}
+
+// Encode выбирает наименьшую версию, в которую помещаются данные, и лучшую маску
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < LevelL || level > LevelH {
+		return nil, fmt.Errorf("unknown error correction level %d", level)
+	}
+	version := 0
+	for v := minVersion; v <= maxVersion; v++ {
+		if 4+countBits(v)+len(data)*8 <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	c := &Code{Size: version*4 + 17, Version: version}
+	c.modules = make([]bool, c.Size*c.Size)
+	c.function = make([]bool, c.Size*c.Size)
+	c.drawFunctionPatterns(level)
+	c.drawCodewords(addECC(encodeBytes(data, version, level), version, level))
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(level, mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		// маска - XOR, повторное применение возвращает матрицу к исходной
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(level, best)
+	return c, nil
+}
+
+// countBits - длина поля с числом байт для байтового режима
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// rawModules - число модулей под данные и коррекцию после служебных узоров
+func rawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawModules(version)/8 - eccPerBlock[level][version]*blocks[level][version]
+}
+
+// encodeBytes собирает поток данных: режим, длину, байты, терминатор и заполнители
+func encodeBytes(data []byte, version int, level Level) []byte {
+	capacity := dataCodewords(version, level)
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(len(data), countBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	bb.append(0, min(4, capacity*8-bb.len))
+	bb.append(0, (8-bb.len%8)%8)
+
+	out := bb.data
+	for pad := byte(0xEC); len(out) < capacity; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+// addECC делит данные на блоки, считает для каждого коды Рида-Соломона и перемежает байты блоков
+func addECC(data []byte, version int, level Level) []byte {
+	numBlocks := blocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	raw := rawModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsDivisor(eccLen)
+	parts := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < numShort {
+			// выравнивание коротких блоков по длине, при перемежении пропускается
+			block = append(block, 0)
+		}
+		parts[i] = append(block, ecc...)
+	}
+
+	out := make([]byte, 0, raw)
+	for i := range parts[0] {
+		for j, block := range parts {
+			if i != shortLen-eccLen || j >= numShort {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+	c.function[y*c.Size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns(level Level) {
+	for i := range c.Size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	pos := alignmentPositions(c.Version)
+	last := len(pos) - 1
+	for i, x := range pos {
+		for j, y := range pos {
+			// на местах поисковых узоров выравнивающих нет
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// формат рисуется заранее, чтобы занять его модули до размещения данных
+	c.drawFormat(level, 0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions - координаты центров выравнивающих узоров по каждой оси
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	pos := make([]int, count)
+	pos[0] = 6
+	for i, p := count-1, version*4+10; i > 0; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// formatInfo - 15 бит уровня и маски с кодом БЧХ
+func formatInfo(level Level, mask int) int {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInfo - 18 бит версии с кодом БЧХ, используется с 7-й версии
+func versionInfo(version int) int {
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+func (c *Code) drawFormat(level Level, mask int) {
+	bits := formatInfo(level, mask)
+	for i := range 6 {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	// темный модуль всегда закрашен
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionInfo(c.Version)
+	for i := range 18 {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords раскладывает байты зигзагом по парам столбцов снизу вверх и обратно
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// вертикальный синхронизирующий узор пропускается целиком
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y*c.Size+x] || i >= len(data)*8 {
+					continue
+				}
+				c.set(x, y, bit(int(data[i>>3]), 7-i&7))
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.function[y*c.Size+x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// penalty оценивает маску по четырем правилам стандарта, меньше - лучше
+func (c *Code) penalty() int {
+	const (
+		n1 = 3
+		n2 = 3
+		n3 = 40
+		n4 = 10
+	)
+	p := 0
+	line := make([]bool, c.Size)
+	for _, vertical := range []bool{false, true} {
+		for i := range c.Size {
+			for j := range c.Size {
+				if vertical {
+					line[j] = c.Dark(i, j)
+				} else {
+					line[j] = c.Dark(j, i)
+				}
+			}
+			run := 1
+			for j := 1; j <= c.Size; j++ {
+				if j < c.Size && line[j] == line[j-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					p += n1 + run - 5
+				}
+				run = 1
+			}
+			p += n3 * finderLike(line)
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			d := c.Dark(x, y)
+			if d {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size && d == c.Dark(x+1, y) && d == c.Dark(x, y+1) && d == c.Dark(x+1, y+1) {
+				p += n2
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return p + k*n4
+}
+
+// finderLike считает в строке узоры 1:1:3:1:1 со светлым полем в 4 модуля с одной из сторон.
+// За краем матрицы модули считаются светлыми, как в отступе.
+func finderLike(line []bool) int {
+	pattern := []bool{true, false, true, true, true, false, true}
+	lightAt := func(i int) bool {
+		return i < 0 || i >= len(line) || !line[i]
+	}
+	n := 0
+	for i := 0; i+len(pattern) <= len(line); i++ {
+		match := true
+		for j, d := range pattern {
+			if line[i+j] != d {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		before, after := true, true
+		for j := 1; j <= 4; j++ {
+			before = before && lightAt(i-j)
+			after = after && lightAt(i+len(pattern)-1+j)
+		}
+		if before || after {
+			n++
+		}
+	}
+	return n
+}
+
+// rsDivisor - порождающий многочлен кода Рида-Соломона степени degree над GF(256)
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul умножает в GF(256) по модулю x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+type bitBuffer struct {
+	data []byte
+	len  int
+}
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if b.len%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		if value>>i&1 == 1 {
+			b.data[b.len/8] |= 0x80 >> (b.len % 8)
+		}
+		b.len++
+	}
+}
+
+func bit(x, i int) bool {
+	return x>>i&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,250 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// Пример 1-M "HELLO WORLD" из стандарта
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	require.Equal(t, want, rsRemainder(data, rsDivisor(10)))
+}
+
+func TestFormatAndVersionInfo(t *testing.T) {
+	require.Equal(t, 0b111011111000100, formatInfo(LevelL, 0))
+	require.Equal(t, 0b101010000010010, formatInfo(LevelM, 0))
+	require.Equal(t, 0b011010101011111, formatInfo(LevelQ, 0))
+	require.Equal(t, 0b001011010001001, formatInfo(LevelH, 0))
+	require.Equal(t, 0b100000011001110, formatInfo(LevelM, 5))
+
+	require.Equal(t, 0b000111110010010100, versionInfo(7))
+	require.Equal(t, 0b101000110001101001, versionInfo(40))
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	require.Nil(t, alignmentPositions(1))
+	require.Equal(t, []int{6, 18}, alignmentPositions(2))
+	require.Equal(t, []int{6, 22, 38}, alignmentPositions(7))
+	require.Equal(t, []int{6, 34, 60, 86, 112, 138}, alignmentPositions(32))
+	require.Equal(t, []int{6, 30, 58, 86, 114, 142, 170}, alignmentPositions(40))
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		bytes   int
+	}{
+		{1, LevelL, 17},
+		{1, LevelM, 14},
+		{1, LevelQ, 11},
+		{1, LevelH, 7},
+		{2, LevelM, 26},
+		{10, LevelL, 271},
+		{10, LevelH, 119},
+		{40, LevelL, 2953},
+		{40, LevelM, 2331},
+		{40, LevelQ, 1663},
+		{40, LevelH, 1273},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			c, err := Encode(bytes.Repeat([]byte("a"), tt.bytes), tt.level)
+			require.NoError(t, err)
+			require.Equal(t, tt.version, c.Version)
+
+			c, err = Encode(bytes.Repeat([]byte("a"), tt.bytes+1), tt.level)
+			if tt.version == maxVersion {
+				require.ErrorIs(t, err, ErrTooLong)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.version+1, c.Version)
+		})
+	}
+}
+
+// TestEncodeDecode читает код обратно: формат, маску, блоки и коды коррекции
+func TestEncodeDecode(t *testing.T) {
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for _, n := range []int{1, 20, 33, 150, 400, 1200} {
+			data := []byte(strings.Repeat("https://sho.rt/", n)[:n])
+			c, err := Encode(data, level)
+			if err != nil {
+				require.ErrorIs(t, err, ErrTooLong)
+				continue
+			}
+			require.Equal(t, c.Version*4+17, c.Size)
+
+			free := 0
+			for _, f := range c.function {
+				if !f {
+					free++
+				}
+			}
+			require.Equal(t, rawModules(c.Version), free)
+
+			// обе копии формата совпадают и содержат выбранный уровень
+			var first, second int
+			for i := range 15 {
+				if c.Dark(formatPosition(c.Size, i, false)) {
+					first |= 1 << i
+				}
+				if c.Dark(formatPosition(c.Size, i, true)) {
+					second |= 1 << i
+				}
+			}
+			require.Equal(t, first, second)
+			mask := -1
+			for m := range 8 {
+				if formatInfo(level, m) == first {
+					mask = m
+				}
+			}
+			require.NotEqual(t, -1, mask)
+
+			c.applyMask(mask)
+			codewords := c.readCodewords()
+			c.applyMask(mask)
+
+			require.Equal(t, data, decodeBlocks(t, codewords, c.Version, level))
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, err := Encode([]byte("https://sho.rt/abc"), LevelM)
+	require.NoError(t, err)
+	fg, err := ParseColor("#123")
+	require.NoError(t, err)
+	bg, err := ParseColor("ffffff00")
+	require.NoError(t, err)
+	style := Style{Size: 300, Margin: 4, Foreground: fg, Background: bg}
+
+	var buf bytes.Buffer
+	require.NoError(t, c.PNG(&buf, style))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	// версия 2 с отступом - 33 модуля, по 9 пикселей на модуль
+	require.Equal(t, 297, img.Bounds().Dx())
+	require.Equal(t, color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}, color.NRGBAModel.Convert(img.At(40, 40)))
+	_, _, _, a := img.At(0, 0).RGBA()
+	require.Zero(t, a)
+
+	buf.Reset()
+	require.NoError(t, c.SVG(&buf, style))
+	svg := buf.String()
+	require.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 33 33"`))
+	require.Contains(t, svg, `<path fill="#112233" d="M4 4h7v1h-7z`)
+	require.NotContains(t, svg, "<rect")
+
+	_, err = ParseColor("blue")
+	require.Error(t, err)
+}
+
+func formatPosition(size, i int, second bool) (int, int) {
+	if second {
+		if i < 8 {
+			return size - 1 - i, 8
+		}
+		return 8, size - 15 + i
+	}
+	switch {
+	case i < 6:
+		return 8, i
+	case i == 6:
+		return 8, 7
+	case i == 7:
+		return 8, 8
+	case i == 8:
+		return 7, 8
+	default:
+		return 14 - i, 8
+	}
+}
+
+func (c *Code) readCodewords() []byte {
+	var bb bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				if x := right - j; !c.function[y*c.Size+x] {
+					v := 0
+					if c.Dark(x, y) {
+						v = 1
+					}
+					bb.append(v, 1)
+				}
+			}
+		}
+	}
+	return bb.data[:rawModules(c.Version)/8]
+}
+
+// decodeBlocks разбирает перемежение, сверяет коды коррекции каждого блока и возвращает данные
+func decodeBlocks(t *testing.T, codewords []byte, version int, level Level) []byte {
+	numBlocks := blocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	numShort := numBlocks - len(codewords)%numBlocks
+	shortData := len(codewords)/numBlocks - eccLen
+
+	parts := make([][]byte, numBlocks)
+	k := 0
+	for i := range shortData + 1 {
+		for j := range parts {
+			if i == shortData && j < numShort {
+				continue
+			}
+			parts[j] = append(parts[j], codewords[k])
+			k++
+		}
+	}
+	var data []byte
+	for range eccLen {
+		for j := range parts {
+			parts[j] = append(parts[j], codewords[k])
+			k++
+		}
+	}
+	divisor := rsDivisor(eccLen)
+	for _, p := range parts {
+		n := len(p) - eccLen
+		require.Equal(t, p[n:], rsRemainder(p[:n], divisor))
+		data = append(data, p[:n]...)
+	}
+
+	var bits []int
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, int(b>>i&1))
+		}
+	}
+	read := func(n int) int {
+		v := 0
+		for range n {
+			v = v<<1 | bits[0]
+			bits = bits[1:]
+		}
+		return v
+	}
+	require.Equal(t, 0b0100, read(4))
+	out := make([]byte, read(countBits(version)))
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package qr
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Style - как рисовать код. Size - сторона картинки в пикселях вместе с отступом,
+// Margin - отступ в модулях (стандарт требует не меньше 4).
+type Style struct {
+	Size       int
+	Margin     int
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// ParseColor разбирает цвет в hex: RGB, RRGGBB или RRGGBBAA, решетка в начале необязательна
+func ParseColor(s string) (color.NRGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	if len(h) == 6 {
+		h += "ff"
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != 4 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected hex RGB, RRGGBB or RRGGBBAA", s)
+	}
+	return color.NRGBA{R: b[0], G: b[1], B: b[2], A: b[3]}, nil
+}
+
+// PNG рисует код целым числом пикселей на модуль, чтобы края модулей не размывались.
+// Поэтому картинка может быть немного меньше Size, но не меньше пикселя на модуль.
+func (c *Code) PNG(w io.Writer, s Style) error {
+	total := c.Size + 2*s.Margin
+	scale := max(s.Size/total, 1)
+	side := total * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{
+		s.Background,
+		s.Foreground,
+	})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.Dark(x, y) {
+				continue
+			}
+			px, py := (x+s.Margin)*scale, (y+s.Margin)*scale
+			for dy := range scale {
+				row := img.Pix[(py+dy)*img.Stride+px:]
+				for dx := range scale {
+					row[dx] = 1
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// SVG рисует код одним path в координатах модулей, масштабируется без потери четкости
+func (c *Code) SVG(w io.Writer, s Style) error {
+	total := c.Size + 2*s.Margin
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		s.Size, s.Size, total, total)
+	if s.Background.A != 0 {
+		fmt.Fprintf(bw, `<rect width="%d" height="%d"%s/>`, total, total, svgFill(s.Background))
+	}
+	fmt.Fprintf(bw, `<path%s d="`, svgFill(s.Foreground))
+	for y := range c.Size {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			// соседние темные модули строки рисуются одним прямоугольником
+			run := 1
+			for x+run < c.Size && c.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", x+s.Margin, y+s.Margin, run, run)
+			x += run - 1
+		}
+	}
+	fmt.Fprint(bw, `"/></svg>`)
+	return bw.Flush()
+}
+
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%.3g"`, float64(c.A)/0xff)
+	}
+	return fill
+}
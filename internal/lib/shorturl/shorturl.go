@@ -0,0 +1,57 @@
+// Package shorturl собирает полный адрес короткой ссылки для ответов API
+package shorturl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Builder строит адрес от public_base_url. Ссылка на домене бренда получает хост своего
+// домена со схемой и путем базового адреса. Без базового адреса берется хост запроса.
+type Builder struct {
+	base *url.URL
+}
+
+// New проверяет базовый адрес: абсолютный http(s) без параметров и фрагмента.
+// Пустая строка допустима - тогда адрес строится по запросу.
+func New(base string) (Builder, error) {
+	if base == "" {
+		return Builder{}, nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return Builder{}, fmt.Errorf("public_base_url must be an absolute http(s) url without query, got %q", base)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return Builder{base: u}, nil
+}
+
+// FromRequest сообщает, что базового адреса нет и адрес ссылки зависит от хоста запроса
+func (b Builder) FromRequest() bool {
+	return b.base == nil
+}
+
+// URL возвращает адрес ссылки alias на домене domain (пустой - основной домен).
+// r может быть nil вне HTTP, тогда без базового адреса результат пустой.
+func (b Builder) URL(r *http.Request, domain, alias string) string {
+	u := url.URL{Path: "/" + alias}
+	switch {
+	case b.base != nil:
+		u.Scheme, u.Host, u.Path = b.base.Scheme, b.base.Host, b.base.Path+u.Path
+	case r != nil:
+		u.Scheme, u.Host = "http", r.Host
+		if r.TLS != nil {
+			u.Scheme = "https"
+		}
+	default:
+		return ""
+	}
+	if domain != "" {
+		u.Host = domain
+	}
+	return u.String()
+}
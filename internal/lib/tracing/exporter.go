@@ -0,0 +1,289 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdoutExporter пишет каждый спан строкой JSON. Удобен локально и в тестах без сети.
+type StdoutExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{enc: json.NewEncoder(w)}
+}
+
+type stdoutSpan struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Kind       SpanKind       `json:"kind"`
+	Start      time.Time      `json:"start"`
+	DurationMs float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Status     StatusCode     `json:"status,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+func (e *StdoutExporter) ExportSpan(span SpanData) {
+	out := stdoutSpan{
+		TraceID:    span.SpanContext.TraceID.String(),
+		SpanID:     span.SpanContext.SpanID.String(),
+		Name:       span.Name,
+		Kind:       span.Kind,
+		Start:      span.Start.UTC(),
+		DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+		Status:     span.Status,
+		Error:      span.StatusDescription,
+	}
+	if span.Parent.IsValid() {
+		out.ParentID = span.Parent.String()
+	}
+	if len(span.Attributes) > 0 {
+		out.Attributes = make(map[string]any, len(span.Attributes))
+		for _, a := range span.Attributes {
+			out.Attributes[a.Key] = a.Value
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.enc.Encode(out)
+}
+
+func (e *StdoutExporter) Shutdown(context.Context) error { return nil }
+
+// OTLPExporter отправляет спаны пачками в коллектор по OTLP/HTTP в JSON-кодировке
+type OTLPExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	onError  func(error)
+
+	queue chan SpanData
+	flush chan chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+const (
+	otlpBatchSize     = 256
+	otlpQueueSize     = 4096
+	otlpFlushInterval = 5 * time.Second
+)
+
+// NewOTLPExporter отправляет спаны на endpoint (например http://otel-collector:4318).
+// onError вызывается при ошибке отправки и может быть nil.
+func NewOTLPExporter(endpoint, serviceName string, onError func(error)) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:  serviceName,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		onError:  onError,
+		queue:    make(chan SpanData, otlpQueueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// ExportSpan ставит спан в очередь. Если коллектор не успевает, спан отбрасывается.
+func (e *OTLPExporter) ExportSpan(span SpanData) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+// Shutdown отправляет накопленные спаны и останавливает фоновую отправку
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	var err error
+	e.once.Do(func() {
+		ack := make(chan struct{})
+		select {
+		case e.flush <- ack:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+		select {
+		case <-ack:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	})
+	return err
+}
+
+func (e *OTLPExporter) run() {
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, otlpBatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil && e.onError != nil {
+			e.onError(err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) == otlpBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-e.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(ack)
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(e.service, spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export spans: collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Структуры ниже повторяют JSON-представление ExportTraceServiceRequest из OTLP
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope map[string]string `json:"scope"`
+	Spans []otlpSpan        `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   map[string][]otlpKeyValue `json:"resource"`
+	ScopeSpans []otlpScopeSpans          `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpRequest(service string, spans []SpanData) otlpTraces {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusDescription},
+		}
+		if span.Parent.IsValid() {
+			s.ParentSpanID = span.Parent.String()
+		}
+		out = append(out, s)
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   map[string][]otlpKeyValue{"attributes": otlpAttributes([]Attr{String("service.name", service)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: map[string]string{"name": service}, Spans: out}},
+	}}}
+}
+
+func otlpAttributes(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var value map[string]any
+		switch v := a.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case int64:
+			// int64 в OTLP/JSON кодируется строкой
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: value})
+	}
+	return out
+}
+
+// Recorder хранит спаны в памяти, чтобы тесты могли проверить трейс
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *Recorder) ExportSpan(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *Recorder) Shutdown(context.Context) error { return nil }
+
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
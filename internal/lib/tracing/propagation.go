@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader - заголовок W3C Trace Context (https://www.w3.org/TR/trace-context/)
+const TraceparentHeader = "traceparent"
+
+const flagSampled = 0x01
+
+// Extract читает traceparent из заголовков. Невалидный заголовок игнорируется.
+func Extract(h http.Header) (SpanContext, bool) {
+	return ParseTraceparent(h.Get(TraceparentHeader))
+}
+
+// Inject записывает traceparent текущего спана из ctx
+func Inject(ctx context.Context, h http.Header) {
+	if span := SpanFromContext(ctx); span != nil && span.sc.IsValid() {
+		h.Set(TraceparentHeader, FormatTraceparent(span.sc))
+	}
+}
+
+// ParseTraceparent разбирает значение вида 00-<trace-id>-<parent-id>-<flags>
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+	// Версия 00 содержит ровно четыре поля, у будущих версий могут быть дополнительные
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	if _, err := hex.DecodeString(parts[0]); err != nil {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeHex(parts[1], sc.TraceID[:]) || !decodeHex(parts[2], sc.SpanID[:]) {
+		return SpanContext{}, false
+	}
+	var flags [1]byte
+	if !decodeHex(parts[3], flags[:]) {
+		return SpanContext{}, false
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+	sc.Remote = true
+	return sc, true
+}
+
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// decodeHex принимает только строчные hex-символы нужной длины, как требует спецификация
+func decodeHex(s string, dst []byte) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
@@ -0,0 +1,279 @@
+// Package tracing - минимальный трейсинг в модели OpenTelemetry: спаны с родителями,
+// распространение через W3C traceparent и экспорт в OTLP/HTTP или stdout.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+// SpanContext - то, что передается между сервисами в traceparent
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind совпадает со значениями OTLP
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode совпадает со значениями OTLP
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr      { return Attr{Key: key, Value: value} }
+func Int(key string, value int) Attr     { return Attr{Key: key, Value: int64(value)} }
+func Int64(key string, value int64) Attr { return Attr{Key: key, Value: value} }
+func Bool(key string, value bool) Attr   { return Attr{Key: key, Value: value} }
+
+// Span - одна операция в трейсе. Методы безопасно вызывать у nil.
+type Span struct {
+	tracer *Tracer
+
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	sc         SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attrs      []Attr
+	status     StatusCode
+	statusDesc string
+	ended      bool
+}
+
+// SpanData - снимок завершенного спана для экспортеров
+type SpanData struct {
+	Name              string
+	Kind              SpanKind
+	SpanContext       SpanContext
+	Parent            SpanID
+	Start             time.Time
+	End               time.Time
+	Attributes        []Attr
+	Status            StatusCode
+	StatusDescription string
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError помечает спан ошибочным. nil игнорируется.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+func (s *Span) SetStatus(code StatusCode, description string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.status = code
+	s.statusDesc = description
+	s.mu.Unlock()
+}
+
+// End завершает спан и отдает его экспортеру. Повторные вызовы игнорируются.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	data := SpanData{
+		Name:              s.name,
+		Kind:              s.kind,
+		SpanContext:       s.sc,
+		Parent:            s.parent,
+		Start:             s.start,
+		End:               s.end,
+		Attributes:        append([]Attr(nil), s.attrs...),
+		Status:            s.status,
+		StatusDescription: s.statusDesc,
+	}
+	s.mu.Unlock()
+
+	if s.sc.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+// Exporter получает завершенные спаны. ExportSpan не должен блокировать обработку запроса.
+type Exporter interface {
+	ExportSpan(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer создает спаны и отдает завершенные экспортеру. Без экспортера
+// спаны все равно создаются, чтобы trace_id был в логах и traceparent.
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+type StartOption func(*Span)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(s *Span) { s.kind = kind }
+}
+
+func WithAttributes(attrs ...Attr) StartOption {
+	return func(s *Span) { s.attrs = append(s.attrs, attrs...) }
+}
+
+// WithRemoteParent делает родителем спан из входящего traceparent
+func WithRemoteParent(sc SpanContext) StartOption {
+	return func(s *Span) {
+		if sc.IsValid() {
+			s.sc.TraceID = sc.TraceID
+			s.sc.Sampled = sc.Sampled
+			s.parent = sc.SpanID
+		}
+	}
+}
+
+// Start создает дочерний спан текущего спана из ctx или корневой, если его нет
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	span := &Span{tracer: t, name: name, kind: KindInternal, start: time.Now()}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.sc.TraceID = parent.sc.TraceID
+		span.sc.Sampled = parent.sc.Sampled
+		span.parent = parent.sc.SpanID
+	}
+	for _, opt := range opts {
+		opt(span)
+	}
+	if !span.sc.TraceID.IsValid() {
+		span.sc.TraceID = newTraceID()
+		span.sc.Sampled = true
+	}
+	span.sc.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+var global atomic.Pointer[Tracer]
+
+func init() {
+	global.Store(NewTracer(nil))
+}
+
+// SetTracer задает трейсер, который используют Start и middleware
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, opts...)
+}
+
+type spanKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceIDFromContext возвращает trace_id текущего спана для логов или пустую строку
+func TraceIDFromContext(ctx context.Context) string {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc.TraceID.String()
+	}
+	return ""
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
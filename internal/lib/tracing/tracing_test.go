@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		name        string
+		value       string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{name: "Sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantOK: true, wantSampled: true},
+		{name: "Not sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", wantOK: true},
+		{name: "Future version with extra field", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantOK: true, wantSampled: true},
+		{name: "Version 00 with extra field", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Invalid version ff", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "Zero span id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "Uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "Short trace id", value: "00-4bf92f3577b34da6-00f067aa0ba902b7-01"},
+		{name: "Empty", value: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tc.value)
+			require.Equal(t, tc.wantOK, ok)
+			if !ok {
+				return
+			}
+			require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			require.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			require.Equal(t, tc.wantSampled, sc.Sampled)
+			require.True(t, sc.Remote)
+		})
+	}
+}
+
+func TestFormatTraceparentRoundTrip(t *testing.T) {
+	const value = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(value)
+	require.True(t, ok)
+	require.Equal(t, value, FormatTraceparent(sc))
+}
+
+func TestSpanHierarchy(t *testing.T) {
+	rec := &Recorder{}
+	tracer := NewTracer(rec)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := tracer.Start(context.Background(), "GET /{alias}", WithKind(KindServer), WithRemoteParent(remote))
+	_, child := tracer.Start(ctx, "pgx.query SELECT", WithAttributes(String("db.system", "postgresql")))
+	child.RecordError(errors.New("timeout"))
+	child.End()
+	root.End()
+	root.End()
+
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "pgx.query SELECT", spans[0].Name)
+	require.Equal(t, remote.TraceID, spans[0].SpanContext.TraceID)
+	require.Equal(t, root.SpanContext().SpanID, spans[0].Parent)
+	require.Equal(t, StatusError, spans[0].Status)
+	require.Equal(t, "timeout", spans[0].StatusDescription)
+	require.Equal(t, []Attr{String("db.system", "postgresql")}, spans[0].Attributes)
+
+	require.Equal(t, KindServer, spans[1].Kind)
+	require.Equal(t, remote.SpanID, spans[1].Parent)
+	require.Equal(t, remote.TraceID.String(), TraceIDFromContext(ctx))
+}
+
+func TestNotSampledParentIsNotExported(t *testing.T) {
+	rec := &Recorder{}
+	tracer := NewTracer(rec)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx, span := tracer.Start(context.Background(), "GET", WithRemoteParent(remote))
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	span.End()
+
+	require.Empty(t, rec.Spans())
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewStdoutExporter(&buf))
+
+	ctx, span := tracer.Start(context.Background(), "handlers.url.redirect.New", WithAttributes(String("alias", "gh")))
+	_, child := tracer.Start(ctx, "pgx.query SELECT")
+	child.End()
+	span.End()
+
+	dec := json.NewDecoder(&buf)
+	var first, second map[string]any
+	require.NoError(t, dec.Decode(&first))
+	require.NoError(t, dec.Decode(&second))
+
+	require.Equal(t, "pgx.query SELECT", first["name"])
+	require.Equal(t, span.SpanContext().SpanID.String(), first["parent_id"])
+	require.Equal(t, "handlers.url.redirect.New", second["name"])
+	require.Equal(t, map[string]any{"alias": "gh"}, second["attributes"])
+	require.NotContains(t, second, "parent_id")
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/", "url-shortener", func(err error) { t.Error(err) })
+	tracer := NewTracer(exporter)
+
+	_, span := tracer.Start(context.Background(), "GET /{alias}", WithKind(KindServer), WithAttributes(Int("http.response.status_code", 302)))
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	var req otlpTraces
+	require.NoError(t, json.Unmarshal(<-bodies, &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Equal(t, "service.name", req.ResourceSpans[0].Resource["attributes"][0].Key)
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	require.Equal(t, span.SpanContext().TraceID.String(), spans[0].TraceID)
+	require.Equal(t, KindServer, spans[0].Kind)
+	require.Empty(t, spans[0].ParentSpanID)
+	require.Equal(t, []otlpKeyValue{{Key: "http.response.status_code", Value: map[string]any{"intValue": "302"}}}, spans[0].Attributes)
+}
@@ -0,0 +1,223 @@
+// Package links описывает запрос на создание ссылки и его проверку. Запрос общий
+// для HTTP API, пакетного сохранения, импорта и CLI, поэтому не зависит от HTTP.
+package links
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domain"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AliasLength - длина случайного алиаса
+const AliasLength = 6
+
+type Request struct {
+	URL string `json:"url" validate:"required,url"`
+	// Alias не может заканчиваться на "+": суффикс открывает предпросмотр ссылки
+	Alias string `json:"alias,omitempty" validate:"omitempty,endsnotwith=+"`
+	// Domain - зарегистрированный домен бренда, без него ссылка живет на основном домене.
+	// Алиас уникален в пределах домена.
+	Domain string   `json:"domain,omitempty" validate:"omitempty,fqdn"`
+	Owner  string   `json:"owner,omitempty"`
+	Tags   []string `json:"tags,omitempty" validate:"omitempty,dive,required"`
+	// RedirectType - статус редиректа для ссылки, без него берется redirect.default_type из конфига
+	RedirectType int `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// Passthrough переносит путь после алиаса и параметры запроса в целевой URL.
+	// Значение задает политику для параметров, которые уже есть в URL: keep, replace или append.
+	Passthrough string `json:"passthrough,omitempty" validate:"omitempty,oneof=keep replace append"`
+	// UTM - utm-метки ссылки. Вместо них можно передать UTMTemplate - имя шаблона владельца.
+	UTM         *postgres.UTM `json:"utm,omitempty"`
+	UTMTemplate string        `json:"utm_template,omitempty"`
+	// Split делит переходы между несколькими URL по весам, см. ValidateSplit
+	Split *postgres.Split `json:"split,omitempty"`
+	// Password закрывает ссылку паролем. В базе хранится только bcrypt-хеш, см. HashPassword.
+	Password string `json:"password,omitempty" validate:"omitempty,min=4"`
+	// MaxClicks - сколько раз можно перейти по ссылке, после этого редирект отвечает 410
+	MaxClicks int `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	// ActiveFrom и ExpiresAt задают окно, в котором ссылка редиректит, см. ValidateSchedule
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// Title показывается на странице предпросмотра. Interstitial показывает ее вместо редиректа.
+	Title        string `json:"title,omitempty" validate:"omitempty,max=200"`
+	Interstitial bool   `json:"interstitial,omitempty"`
+
+	passwordHash string
+}
+
+var (
+	ErrUTMConflict         = errors.New("utm and utm_template are mutually exclusive")
+	ErrUTMTemplateOwner    = errors.New("utm_template requires owner")
+	ErrPasswordTooLong     = errors.New("password must be at most 72 bytes")
+	ErrExpiresInPast       = errors.New("expires_at must be in the future")
+	ErrExpiresBeforeActive = errors.New("expires_at must be after active_from")
+	// ErrDomainsDisabled - без redirect.default_domain редирект не смотрит на Host,
+	// поэтому ссылка на домене бренда была бы недоступна
+	ErrDomainsDisabled = errors.New("brand domains require redirect.default_domain")
+)
+
+// ValidateSchedule проверяет окно активности ссылки на момент now
+func (req Request) ValidateSchedule(now time.Time) error {
+	if req.ExpiresAt == nil {
+		return nil
+	}
+	if !req.ExpiresAt.After(now) {
+		return ErrExpiresInPast
+	}
+	if req.ActiveFrom != nil && !req.ExpiresAt.After(*req.ActiveFrom) {
+		return ErrExpiresBeforeActive
+	}
+	return nil
+}
+
+// maxPasswordBytes - bcrypt учитывает только первые 72 байта пароля
+const maxPasswordBytes = 72
+
+// HashPassword заменяет Password его bcrypt-хешем для Link
+func (req *Request) HashPassword() error {
+	if req.Password == "" {
+		return nil
+	}
+	if len(req.Password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	req.passwordHash = string(hash)
+	req.Password = ""
+	return nil
+}
+
+// UTMTemplates - источник шаблонов utm-меток
+type UTMTemplates interface {
+	GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error)
+}
+
+// ResolveUTM подставляет в req.UTM метки из шаблона UTMTemplate. Метки копируются
+// в ссылку, поэтому последующее изменение шаблона на нее не влияет.
+func (req *Request) ResolveUTM(ctx context.Context, templates UTMTemplates) error {
+	if req.UTMTemplate == "" {
+		return nil
+	}
+	if req.UTM != nil {
+		return ErrUTMConflict
+	}
+	if req.Owner == "" {
+		return ErrUTMTemplateOwner
+	}
+	template, err := templates.GetUTMTemplate(ctx, req.Owner, req.UTMTemplate)
+	if err != nil {
+		return err
+	}
+	req.UTM = &template.UTM
+	req.UTMTemplate = ""
+	return nil
+}
+
+// LogValue раскрывает запрос в группу, чтобы slogredact мог скрыть секреты в URL
+func (req Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("url", req.URL),
+		slog.String("alias", req.Alias),
+		slog.String("domain", req.Domain),
+		slog.String("owner", req.Owner),
+		slog.Any("tags", req.Tags),
+		slog.Int("redirect_type", req.RedirectType),
+		slog.String("passthrough", req.Passthrough),
+		slog.Any("utm", req.UTM),
+		slog.String("utm_template", req.UTMTemplate),
+		slog.Any("split", req.Split),
+		slog.Bool("password", req.Password != "" || req.passwordHash != ""),
+		slog.Int("max_clicks", req.MaxClicks),
+		slog.Any("active_from", req.ActiveFrom),
+		slog.Any("expires_at", req.ExpiresAt),
+		slog.String("title", req.Title),
+		slog.Bool("interstitial", req.Interstitial),
+	)
+}
+
+// Link собирает ссылку для сохранения с уже выбранным алиасом
+func (req Request) Link(alias string) postgres.Link {
+	var utm postgres.UTM
+	if req.UTM != nil {
+		utm = *req.UTM
+	}
+	return postgres.Link{
+		URL:          req.URL,
+		Alias:        alias,
+		Domain:       domain.Normalize(req.Domain),
+		Owner:        req.Owner,
+		Tags:         req.Tags,
+		RedirectType: req.RedirectType,
+		Passthrough:  req.Passthrough,
+		UTM:          utm,
+		Split:        req.Split,
+		PasswordHash: req.passwordHash,
+		MaxClicks:    req.MaxClicks,
+		ActiveFrom:   req.ActiveFrom,
+		ExpiresAt:    req.ExpiresAt,
+		Title:        req.Title,
+		Interstitial: req.Interstitial,
+	}
+}
+
+// ValidationError - запрос не прошел проверку. Текст ошибки пригоден для ответа клиенту.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// validate кеширует разбор тегов структур, он безопасен для параллельного использования
+var validate = validator.New()
+
+// Validate проверяет поля запроса, варианты A/B-теста и окно активности на момент now.
+// Ошибка всегда *ValidationError.
+func (req Request) Validate(now time.Time) error {
+	if err := validate.Struct(req); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			err = errors.New(response.ValidationError(errs).Error)
+		}
+		return &ValidationError{Err: err}
+	}
+	if err := ValidateSplit(req.Split); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := req.ValidateSchedule(now); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if len(req.Password) > maxPasswordBytes {
+		return &ValidationError{Err: ErrPasswordTooLong}
+	}
+	return nil
+}
+
+// ClientError возвращает текст ошибки для клиента, если ошибка создания ссылки в самом запросе:
+// запрос не прошел проверку, шаблон utm-меток не подходит или домен недоступен
+func ClientError(err error) (string, bool) {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Error(), true
+	}
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		return "unknown domain", true
+	}
+	for _, target := range []error{storage.ErrUTMTemplateNotFound, ErrUTMConflict, ErrUTMTemplateOwner, ErrPasswordTooLong, ErrDomainsDisabled} {
+		if errors.Is(err, target) {
+			return target.Error(), true
+		}
+	}
+	return "", false
+}
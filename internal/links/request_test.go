@@ -0,0 +1,82 @@
+package links
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+	muchLater := now.Add(2 * time.Hour)
+	const url = "https://google.com"
+
+	tests := []struct {
+		name     string
+		req      Request
+		expected string
+	}{
+		{name: "Valid", req: Request{URL: url, Alias: "docs", Domain: "go.brand-a.com", Password: "s3cret", MaxClicks: 1, ActiveFrom: &later, ExpiresAt: &muchLater}},
+		{name: "Empty URL", req: Request{}, expected: "required field"},
+		{name: "Alias with preview suffix", req: Request{URL: url, Alias: "docs+"}, expected: "Alias"},
+		{name: "Domain with scheme", req: Request{URL: url, Domain: "https://go.brand-a.com"}, expected: "Domain"},
+		{name: "Unsupported redirect type", req: Request{URL: url, RedirectType: 303}, expected: "RedirectType"},
+		{name: "Unknown passthrough policy", req: Request{URL: url, Passthrough: "merge"}, expected: "Passthrough"},
+		{name: "Negative max clicks", req: Request{URL: url, MaxClicks: -1}, expected: "MaxClicks"},
+		{
+			name:     "Split without weights",
+			req:      Request{URL: url, Split: &postgres.Split{Variants: []postgres.Variant{{Name: "a", URL: "https://a.com"}}}},
+			expected: "split needs at least one variant with positive weight",
+		},
+		{name: "Already expired", req: Request{URL: url, ExpiresAt: &past}, expected: ErrExpiresInPast.Error()},
+		{name: "Expires before activation", req: Request{URL: url, ActiveFrom: &muchLater, ExpiresAt: &later}, expected: ErrExpiresBeforeActive.Error()},
+		{name: "Password too long", req: Request{URL: url, Password: strings.Repeat("я", 40)}, expected: ErrPasswordTooLong.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(now)
+			if tt.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			var invalid *ValidationError
+			require.ErrorAs(t, err, &invalid)
+			require.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestClientError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+		ok       bool
+	}{
+		{name: "Validation", err: &ValidationError{Err: ErrExpiresInPast}, expected: ErrExpiresInPast.Error(), ok: true},
+		{name: "Unknown domain", err: fmt.Errorf("op: %w", storage.ErrDomainNotFound), expected: "unknown domain", ok: true},
+		{name: "Unknown utm template", err: fmt.Errorf("op: %w", storage.ErrUTMTemplateNotFound), expected: storage.ErrUTMTemplateNotFound.Error(), ok: true},
+		{name: "Utm conflict", err: ErrUTMConflict, expected: ErrUTMConflict.Error(), ok: true},
+		{name: "Url exists", err: storage.ErrURLExists},
+		{name: "Storage error", err: errors.New("db down")},
+		{name: "No error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, ok := ClientError(tt.err)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, detail)
+		})
+	}
+}
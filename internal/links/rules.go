@@ -0,0 +1,53 @@
+package links
+
+import (
+	"errors"
+	"fmt"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxRules - предельное число правил у одной ссылки. Правила проверяются на каждом редиректе.
+const MaxRules = 20
+
+var ErrNoConditions = errors.New("rule has no conditions")
+
+// rule - поля postgres.RedirectRule с проверками валидатора
+type rule struct {
+	Device    string   `validate:"omitempty,oneof=ios android mobile desktop"`
+	Languages []string `validate:"max=20,dive,bcp47_language_tag"`
+	Countries []string `validate:"max=50,dive,iso3166_1_alpha2"`
+	Target    string   `validate:"required,url"`
+}
+
+// ValidateRule проверяет правило редиректа. Заполненные условия должны совпасть все,
+// нужно хотя бы одно. Текст ошибки пригоден для ответа клиенту.
+func ValidateRule(r postgres.RedirectRule) error {
+	err := validate.Struct(rule{Device: r.Device, Languages: r.Languages, Countries: r.Countries, Target: r.Target})
+	if err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			return errors.New(response.ValidationError(errs).Error)
+		}
+		return err
+	}
+	if r.Device == "" && len(r.Languages) == 0 && len(r.Countries) == 0 {
+		return ErrNoConditions
+	}
+	return nil
+}
+
+// ValidateRules проверяет все правила ссылки, ошибка указывает номер правила с нуля
+func ValidateRules(rules []postgres.RedirectRule) error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("too many rules, max %d", MaxRules)
+	}
+	for i, r := range rules {
+		if err := ValidateRule(r); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
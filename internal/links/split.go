@@ -0,0 +1,58 @@
+package links
+
+import (
+	"fmt"
+	"regexp"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// MaxVariants - предельное число вариантов A/B-теста у ссылки
+	MaxVariants = 10
+	// maxVariantWeight ограничивает веса, чтобы их сумма не переполнялась
+	maxVariantWeight = 10000
+)
+
+// variantName - имя варианта попадает в cookie и аналитику как есть
+var variantName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidateSplit проверяет варианты A/B-теста. Текст ошибки пригоден для ответа клиенту.
+func ValidateSplit(split *postgres.Split) error {
+	if split == nil {
+		return nil
+	}
+	switch split.Sticky {
+	case postgres.StickyNone, postgres.StickyCookie, postgres.StickyHash:
+	default:
+		return fmt.Errorf("unknown split sticky %q, expected cookie or hash", split.Sticky)
+	}
+	if len(split.Variants) == 0 || len(split.Variants) > MaxVariants {
+		return fmt.Errorf("split must have from 1 to %d variants", MaxVariants)
+	}
+
+	v := validator.New()
+	names := make(map[string]bool, len(split.Variants))
+	total := 0
+	for i, variant := range split.Variants {
+		if !variantName.MatchString(variant.Name) {
+			return fmt.Errorf("variant %d: name must be 1-32 letters, digits, '-' or '_'", i)
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("variant %d: duplicate name %q", i, variant.Name)
+		}
+		names[variant.Name] = true
+		if err := v.Var(variant.URL, "required,url"); err != nil {
+			return fmt.Errorf("variant %d: url is not a valid URL", i)
+		}
+		if variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return fmt.Errorf("variant %d: weight must be between 0 and %d", i, maxVariantWeight)
+		}
+		total += variant.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("split needs at least one variant with positive weight")
+	}
+	return nil
+}
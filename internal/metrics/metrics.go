@@ -0,0 +1,56 @@
+// Package metrics описывает метрики сервиса и отдает их на отдельном listener
+package metrics
+
+import (
+	"url-shortener/internal/lib/metrics"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var Registry = metrics.NewRegistry()
+
+var (
+	HTTPRequests = Registry.NewCounterVec("http_requests_total",
+		"Number of HTTP requests by method, route pattern and status.",
+		"method", "route", "status")
+	HTTPDuration = Registry.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by method, route pattern and status.",
+		metrics.DefBuckets, "method", "route", "status")
+
+	Redirects = Registry.NewCounterVec("url_shortener_redirects_total",
+		"Redirect lookups by result (hit or miss).",
+		"result")
+
+	AliasAttempts = Registry.NewCounter("url_shortener_alias_generation_attempts_total",
+		"Number of generated random aliases.")
+	AliasCollisions = Registry.NewCounter("url_shortener_alias_collisions_total",
+		"Number of generated aliases that were already taken.")
+
+	ClicksRecorded = Registry.NewCounter("url_shortener_clicks_recorded_total",
+		"Number of clicks written to the database.")
+	ClicksDropped = Registry.NewCounter("url_shortener_clicks_dropped_total",
+		"Number of clicks lost because the queue was full or the write failed.")
+)
+
+const (
+	ResultHit  = "hit"
+	ResultMiss = "miss"
+)
+
+// RegisterPool добавляет статистику пула соединений pgx
+func RegisterPool(pool *pgxpool.Pool) {
+	Registry.NewGaugeFunc("pgxpool_acquired_conns", "Connections currently acquired from the pool.",
+		func() float64 { return float64(pool.Stat().AcquiredConns()) })
+	Registry.NewGaugeFunc("pgxpool_idle_conns", "Idle connections in the pool.",
+		func() float64 { return float64(pool.Stat().IdleConns()) })
+	Registry.NewGaugeFunc("pgxpool_total_conns", "Total connections in the pool.",
+		func() float64 { return float64(pool.Stat().TotalConns()) })
+	Registry.NewGaugeFunc("pgxpool_max_conns", "Maximum size of the pool.",
+		func() float64 { return float64(pool.Stat().MaxConns()) })
+	Registry.NewCounterFunc("pgxpool_acquire_total", "Successful acquires from the pool.",
+		func() float64 { return float64(pool.Stat().AcquireCount()) })
+	Registry.NewCounterFunc("pgxpool_empty_acquire_total", "Acquires that had to wait for a connection.",
+		func() float64 { return float64(pool.Stat().EmptyAcquireCount()) })
+	Registry.NewCounterFunc("pgxpool_acquire_wait_seconds_total", "Time spent waiting for a connection when the pool was empty.",
+		func() float64 { return pool.Stat().EmptyAcquireWaitTime().Seconds() })
+}
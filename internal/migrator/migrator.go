@@ -0,0 +1,292 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Таблица версий совместима с golang-migrate, поэтому базы, которые уже
+// мигрировались через entrypoint.sh, продолжают с той же версии.
+const schemaTable = "schema_migrations"
+
+// lockID - ключ pg_advisory_lock, чтобы реплики не применяли миграции одновременно
+const lockID = 7403172301
+
+// undefinedTable - код ошибки Postgres для несуществующей таблицы
+const undefinedTable = "42P01"
+
+var (
+	ErrDirty           = errors.New("database is dirty, fix the failed migration and force the version manually")
+	ErrVersionMismatch = errors.New("schema version mismatch")
+	// ErrSchemaAhead - база уже мигрирована более новой версией сервиса. Во время раскатки
+	// старые реплики продолжают работать, поэтому это не причина снимать их с трафика.
+	ErrSchemaAhead = errors.New("database schema is newer than the binary")
+	ErrNoDown      = errors.New("migration has no down script")
+
+	fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+// Status описывает состояние схемы относительно встроенных миграций
+type Status struct {
+	Version uint
+	Latest  uint
+	Dirty   bool
+	Pending []Migration
+}
+
+// New читает файлы вида 000001_name.up.sql и 000001_name.down.sql из source
+func New(pool *pgxpool.Pool, source fs.FS) (*Migrator, error) {
+	const op = "migrator.New"
+
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read migrations: %w", op, err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		m := fileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid migration version %q: %w", op, entry.Name(), err)
+		}
+		body, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%s failed to read %s: %w", op, entry.Name(), err)
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &Migration{Version: uint(version), Name: m[2]}
+			byVersion[uint(version)] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// Latest - версия последней известной миграции
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы. Версия 0 означает, что миграции не применялись.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	const op = "migrator.Version"
+
+	var version int64
+	var dirty bool
+	err := m.pool.QueryRow(ctx, `SELECT version, dirty FROM `+schemaTable+` LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("%s failed to read schema version: %w", op, err)
+	}
+	return uint(version), dirty, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Version: version, Latest: m.Latest(), Dirty: dirty}
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+	return status, nil
+}
+
+// Check возвращает ErrVersionMismatch, если схема отстает от встроенных миграций, ErrDirty -
+// если она грязная, и ErrSchemaAhead, если база новее бинарника
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	return checkVersion(version, dirty, m.Latest())
+}
+
+func checkVersion(version uint, dirty bool, latest uint) error {
+	switch {
+	case dirty:
+		return fmt.Errorf("version %d: %w", version, ErrDirty)
+	case version < latest:
+		return fmt.Errorf("%w: database at %d, binary expects %d", ErrVersionMismatch, version, latest)
+	case version > latest:
+		return fmt.Errorf("%w: database at %d, binary expects %d", ErrSchemaAhead, version, latest)
+	}
+	return nil
+}
+
+// Up применяет все миграции новее текущей версии и возвращает число примененных
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	const op = "migrator.Up"
+	applied := 0
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig.Version, mig.Version, mig.Up); err != nil {
+				return fmt.Errorf("failed to apply %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", op, err)
+	}
+	return applied, nil
+}
+
+// Down откатывает steps последних примененных миграций и возвращает число откаченных
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	const op = "migrator.Down"
+	reverted := 0
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("%d_%s: %w", mig.Version, mig.Name, ErrNoDown)
+			}
+			var previous uint
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(ctx, conn, mig.Version, previous, mig.Down); err != nil {
+				return fmt.Errorf("failed to revert %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	if err != nil {
+		return reverted, fmt.Errorf("%s: %w", op, err)
+	}
+	return reverted, nil
+}
+
+// withLock выполняет fn на одном соединении под сессионной advisory-блокировкой
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, int64(lockID)); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, int64(lockID))
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+schemaTable+` (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create %s: %w", schemaTable, err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) lockedVersion(ctx context.Context, conn *pgxpool.Conn) (uint, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM `+schemaTable+` LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("version %d: %w", version, ErrDirty)
+	}
+	return uint(version), nil
+}
+
+// apply помечает версию грязной, выполняет SQL и записывает итоговую версию, как это делает golang-migrate
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, version, result uint, sql string) error {
+	if err := setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return setVersion(ctx, conn, result, false)
+}
+
+func setVersion(ctx context.Context, conn *pgxpool.Conn, version uint, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `TRUNCATE `+schemaTable); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO `+schemaTable+` (version, dirty) VALUES ($1, $2)`, int64(version), dirty); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTable
+}
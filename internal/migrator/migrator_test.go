@@ -0,0 +1,57 @@
+package migrator
+
+import (
+	"testing"
+	"testing/fstest"
+	"url-shortener/migrations"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReadsMigrations(t *testing.T) {
+	source := fstest.MapFS{
+		"000002_soft_delete.up.sql":   {Data: []byte("ALTER TABLE url ADD deleted_at TIMESTAMPTZ;")},
+		"000002_soft_delete.down.sql": {Data: []byte("ALTER TABLE url DROP deleted_at;")},
+		"000001_init_db.up.sql":       {Data: []byte("CREATE TABLE url ();")},
+		"000001_init_db.down.sql":     {Data: []byte("DROP TABLE url;")},
+		"000010_later.up.sql":         {Data: []byte("SELECT 1;")},
+		"README.md":                   {Data: []byte("not a migration")},
+	}
+
+	m, err := New(nil, source)
+	require.NoError(t, err)
+
+	require.Equal(t, uint(10), m.Latest())
+	require.Equal(t, []Migration{
+		{Version: 1, Name: "init_db", Up: "CREATE TABLE url ();", Down: "DROP TABLE url;"},
+		{Version: 2, Name: "soft_delete", Up: "ALTER TABLE url ADD deleted_at TIMESTAMPTZ;", Down: "ALTER TABLE url DROP deleted_at;"},
+		{Version: 10, Name: "later", Up: "SELECT 1;"},
+	}, m.migrations)
+}
+
+func TestNewEmpty(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{})
+	require.NoError(t, err)
+	require.Equal(t, uint(0), m.Latest())
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	m, err := New(nil, migrations.FS)
+	require.NoError(t, err)
+	require.NotZero(t, m.Latest())
+
+	for i, mig := range m.migrations {
+		require.Equal(t, uint(i+1), mig.Version, "migration versions must be sequential")
+		require.NotEmpty(t, mig.Up, "%d_%s has no up script", mig.Version, mig.Name)
+		require.NotEmpty(t, mig.Down, "%d_%s has no down script", mig.Version, mig.Name)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	require.NoError(t, checkVersion(5, false, 5))
+	require.ErrorIs(t, checkVersion(4, false, 5), ErrVersionMismatch)
+	// Во время раскатки база может быть новее старой реплики
+	require.ErrorIs(t, checkVersion(6, false, 5), ErrSchemaAhead)
+	require.ErrorIs(t, checkVersion(5, true, 5), ErrDirty)
+	require.ErrorIs(t, checkVersion(6, true, 5), ErrDirty)
+}
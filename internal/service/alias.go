@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"url-shortener/internal/lib/api/random"
+	"url-shortener/internal/links"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+// maxAliasAttempts - сколько раз генерировать алиас заново, если случайный уже занят
+const maxAliasAttempts = 3
+
+// saveWithAlias сохраняет ссылку под алиасом из запроса, а без него - под случайным.
+// Занятый случайный алиас генерируется заново, всего не больше maxAliasAttempts раз.
+func (s *Service) saveWithAlias(ctx context.Context, req links.Request) (postgres.Link, error) {
+	link := req.Link(req.Alias)
+	if req.Alias != "" {
+		id, err := s.storage.SaveURL(ctx, link)
+		if err != nil {
+			return postgres.Link{}, err
+		}
+		link.ID = id
+		return link, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		link.Alias = random.NewRandomString(links.AliasLength)
+		metrics.AliasAttempts.Inc()
+
+		id, err := s.storage.SaveURL(ctx, link)
+		if err == nil {
+			link.ID = id
+			return link, nil
+		}
+		// url уже проверен в CreateLink, поэтому конфликт для случайного алиаса - это занятый алиас
+		if attempt == maxAliasAttempts || !errors.Is(err, storage.ErrURLExists) {
+			return postgres.Link{}, err
+		}
+		metrics.AliasCollisions.Inc()
+	}
+}
+
+// saveBatchWithAliases сохраняет пачку, подбирая случайные алиасы ссылкам без алиаса.
+// Конфликт такой ссылки при свободном url - занятый алиас: для нее алиас генерируется заново,
+// всего не больше maxAliasAttempts раз. В режиме atomic конфликт откатывает всю пачку,
+// поэтому она отправляется заново, если других ошибок в ней нет. Итоговый алиас - в SaveResult.Alias.
+func (s *Service) saveBatchWithAliases(ctx context.Context, batch []postgres.Link, atomic bool) ([]postgres.SaveResult, error) {
+	batch = slices.Clone(batch)
+	generated := make([]bool, len(batch))
+	for i := range batch {
+		if batch[i].Alias == "" {
+			generated[i] = true
+			batch[i].Alias = random.NewRandomString(links.AliasLength)
+			metrics.AliasAttempts.Inc()
+		}
+	}
+
+	results, err := s.storage.SaveURLs(ctx, batch, atomic)
+	for attempt := 2; err == nil && attempt <= maxAliasAttempts; attempt++ {
+		var retry []int
+		retry, err = s.aliasCollisions(ctx, batch, results, generated, atomic)
+		if err != nil || len(retry) == 0 {
+			break
+		}
+		for _, i := range retry {
+			batch[i].Alias = random.NewRandomString(links.AliasLength)
+			metrics.AliasAttempts.Inc()
+			metrics.AliasCollisions.Inc()
+		}
+		if atomic {
+			results, err = s.storage.SaveURLs(ctx, batch, true)
+			continue
+		}
+
+		retried := make([]postgres.Link, len(retry))
+		for j, i := range retry {
+			retried[j] = batch[i]
+		}
+		var saved []postgres.SaveResult
+		if saved, err = s.storage.SaveURLs(ctx, retried, false); err == nil {
+			for j, i := range retry {
+				results[i] = saved[j]
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// aliasCollisions возвращает индексы ссылок со случайным алиасом, которым достался занятый алиас.
+// В режиме atomic пачка не повторяется, если в ней есть другие ошибки.
+func (s *Service) aliasCollisions(ctx context.Context, batch []postgres.Link, results []postgres.SaveResult, generated []bool, atomic bool) ([]int, error) {
+	var collisions []int
+	for i, res := range results {
+		if res.Err == nil || errors.Is(res.Err, storage.ErrBatchAborted) {
+			continue
+		}
+		if !generated[i] || !errors.Is(res.Err, storage.ErrURLExists) {
+			if atomic {
+				return nil, nil
+			}
+			continue
+		}
+		exists, err := s.storage.URLExists(ctx, batch[i].URL, batch[i].Domain)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			if atomic {
+				return nil, nil
+			}
+			continue
+		}
+		collisions = append(collisions, i)
+	}
+	return collisions, nil
+}
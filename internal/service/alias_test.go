@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/links"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/mocks"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestCreateLinkAliasRetry(t *testing.T) {
+	const testURL = "https://google.com"
+	dbErr := errors.New("db down")
+
+	tests := []struct {
+		name  string
+		alias string
+		// saveErrs - ответы SaveURL по порядку, nil - ссылка сохранена
+		saveErrs           []error
+		expectedErr        error
+		expectedAttempts   float64
+		expectedCollisions float64
+	}{
+		{name: "Free generated alias", saveErrs: []error{nil}, expectedAttempts: 1},
+		{
+			name:               "Taken generated alias is retried",
+			saveErrs:           []error{storage.ErrURLExists, nil},
+			expectedAttempts:   2,
+			expectedCollisions: 1,
+		},
+		{
+			name:               "Gives up after max attempts",
+			saveErrs:           []error{storage.ErrURLExists, storage.ErrURLExists, storage.ErrURLExists},
+			expectedErr:        storage.ErrURLExists,
+			expectedAttempts:   3,
+			expectedCollisions: 2,
+		},
+		{name: "Other errors are not retried", saveErrs: []error{dbErr}, expectedErr: dbErr, expectedAttempts: 1},
+		{name: "Custom alias is not retried", alias: "docs", saveErrs: []error{storage.ErrURLExists}, expectedErr: storage.ErrURLExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := mocks.NewStorageInterface(t)
+			storageMock.On("URLExists", mock.Anything, testURL, "").Return(false, nil)
+			var aliases []string
+			for _, saveErr := range tt.saveErrs {
+				storageMock.On("SaveURL", mock.Anything, mock.AnythingOfType("postgres.Link")).
+					Run(func(args mock.Arguments) { aliases = append(aliases, args.Get(1).(postgres.Link).Alias) }).
+					Return(int64(1), saveErr).
+					Once()
+			}
+
+			attempts, collisions := metrics.AliasAttempts.Value(), metrics.AliasCollisions.Value()
+			link, err := service.NewService(storageMock, "sho.rt").CreateLink(context.Background(), links.Request{URL: testURL, Alias: tt.alias})
+
+			require.Equal(t, tt.expectedAttempts, metrics.AliasAttempts.Value()-attempts)
+			require.Equal(t, tt.expectedCollisions, metrics.AliasCollisions.Value()-collisions)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, aliases[len(aliases)-1], link.Alias)
+			require.Len(t, link.Alias, links.AliasLength)
+			if len(aliases) > 1 {
+				require.NotEqual(t, aliases[0], aliases[1])
+			}
+		})
+	}
+}
+
+func TestSaveURLsAliasRetry(t *testing.T) {
+	taken := postgres.SaveResult{Err: storage.ErrURLExists}
+	aborted := postgres.SaveResult{Err: storage.ErrBatchAborted}
+
+	tests := []struct {
+		name   string
+		batch  []postgres.Link
+		atomic bool
+		// saves - ответы SaveURLs по порядку вызовов
+		saves [][]postgres.SaveResult
+		// urlTaken - ответ URLExists для конфликтов со случайным алиасом
+		urlTaken bool
+		// sent - сколько ссылок ушло в каждый вызов SaveURLs
+		sent               []int
+		expected           []error
+		expectedCollisions float64
+	}{
+		{
+			name:     "Custom aliases are saved once",
+			batch:    []postgres.Link{{URL: "https://a.com", Alias: "a"}, {URL: "https://b.com", Alias: "b"}},
+			saves:    [][]postgres.SaveResult{{{ID: 1}, taken}},
+			sent:     []int{2},
+			expected: []error{nil, storage.ErrURLExists},
+		},
+		{
+			name:               "Only colliding items are retried",
+			batch:              []postgres.Link{{URL: "https://a.com", Alias: "a"}, {URL: "https://b.com"}},
+			saves:              [][]postgres.SaveResult{{{ID: 1}, taken}, {{ID: 2}}},
+			sent:               []int{2, 1},
+			expected:           []error{nil, nil},
+			expectedCollisions: 1,
+		},
+		{
+			name:     "Taken url is not retried",
+			batch:    []postgres.Link{{URL: "https://b.com"}},
+			saves:    [][]postgres.SaveResult{{taken}},
+			urlTaken: true,
+			sent:     []int{1},
+			expected: []error{storage.ErrURLExists},
+		},
+		{
+			name:               "Gives up after max attempts",
+			batch:              []postgres.Link{{URL: "https://b.com"}},
+			saves:              [][]postgres.SaveResult{{taken}, {taken}, {taken}},
+			sent:               []int{1, 1, 1},
+			expected:           []error{storage.ErrURLExists},
+			expectedCollisions: 2,
+		},
+		{
+			name:               "Atomic batch is resent whole",
+			batch:              []postgres.Link{{URL: "https://a.com", Alias: "a"}, {URL: "https://b.com"}},
+			atomic:             true,
+			saves:              [][]postgres.SaveResult{{aborted, taken}, {{ID: 1}, {ID: 2}}},
+			sent:               []int{2, 2},
+			expected:           []error{nil, nil},
+			expectedCollisions: 1,
+		},
+		{
+			name:     "Atomic batch with other conflicts is not resent",
+			batch:    []postgres.Link{{URL: "https://a.com", Alias: "a"}, {URL: "https://b.com"}},
+			atomic:   true,
+			saves:    [][]postgres.SaveResult{{taken, taken}},
+			sent:     []int{2},
+			expected: []error{storage.ErrURLExists, storage.ErrURLExists},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := mocks.NewStorageInterface(t)
+			storageMock.On("URLExists", mock.Anything, mock.Anything, "").Return(tt.urlTaken, nil).Maybe()
+			var sent [][]postgres.Link
+			for _, save := range tt.saves {
+				storageMock.On("SaveURLs", mock.Anything, mock.Anything, tt.atomic).
+					Run(func(args mock.Arguments) { sent = append(sent, slices.Clone(args.Get(1).([]postgres.Link))) }).
+					Return(func(_ context.Context, links []postgres.Link, _ bool) []postgres.SaveResult {
+						results := make([]postgres.SaveResult, len(save))
+						for i, res := range save {
+							res.Alias = links[i].Alias
+							results[i] = res
+						}
+						return results
+					}, nil).
+					Once()
+			}
+
+			original := slices.Clone(tt.batch)
+			collisions := metrics.AliasCollisions.Value()
+			results, err := service.NewService(storageMock, "sho.rt").SaveURLs(context.Background(), tt.batch, tt.atomic)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCollisions, metrics.AliasCollisions.Value()-collisions)
+
+			require.Len(t, sent, len(tt.sent))
+			for i, n := range tt.sent {
+				require.Len(t, sent[i], n)
+			}
+			// Исходная пачка не меняется, повторная попытка получает новый алиас
+			require.Equal(t, original, tt.batch)
+			if len(sent) > 1 && tt.batch[len(tt.batch)-1].Alias == "" {
+				require.NotEqual(t, sent[0][len(sent[0])-1].Alias, sent[1][len(sent[1])-1].Alias)
+			}
+
+			require.Len(t, results, len(tt.expected))
+			for i, expectedErr := range tt.expected {
+				require.ErrorIs(t, results[i].Err, expectedErr)
+				if expectedErr == nil {
+					require.NotEmpty(t, results[i].Alias)
+				}
+			}
+		})
+	}
+}
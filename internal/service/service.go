@@ -2,36 +2,220 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"iter"
+	"time"
+	"url-shortener/internal/lib/domain"
+	"url-shortener/internal/links"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
 )
 
 type Service struct {
 	storage storage.StorageInterface
+	// defaultDomain - redirect.default_domain. Пока он пуст, домены брендов не используются.
+	defaultDomain string
 }
 
-func NewService(storage storage.StorageInterface) *Service {
+func NewService(storage storage.StorageInterface, defaultDomain string) *Service {
 	return &Service{
-		storage: storage,
+		storage:       storage,
+		defaultDomain: defaultDomain,
 	}
 }
 
+// checkDomain отклоняет домен бренда, пока не задан redirect.default_domain
+func (s *Service) checkDomain(host string) error {
+	if host != "" && s.defaultDomain == "" {
+		return links.ErrDomainsDisabled
+	}
+	return nil
+}
+
 type ServiceInterface interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error)
-	GetURL(ctx context.Context, alias string) (string, error)
-	DeleteURl(ctx context.Context, alias string) error
-	URLExists(ctx context.Context, url string) (bool, error)
+	CreateLink(ctx context.Context, req links.Request) (postgres.Link, error)
+	SaveURL(ctx context.Context, link postgres.Link) (int64, error)
+	SaveURLs(ctx context.Context, links []postgres.Link, atomic bool) ([]postgres.SaveResult, error)
+	ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error)
+	ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error]
+	GetURL(ctx context.Context, domain, alias string) (string, error)
+	GetLink(ctx context.Context, domain, alias string) (postgres.Link, error)
+	ListURLs(ctx context.Context, filter postgres.Filter, limit, offset int) ([]postgres.Link, error)
+	DeleteURl(ctx context.Context, domain, alias string) error
+	DeleteURLs(ctx context.Context, filter postgres.Filter, dryRun bool) ([]string, error)
+	RestoreURL(ctx context.Context, domain, alias string) error
+	PurgeDeletedURLs(ctx context.Context, retention time.Duration) (int64, error)
+	URLExists(ctx context.Context, url, domain string) (bool, error)
+	SaveUTMTemplate(ctx context.Context, t postgres.UTMTemplate) (postgres.UTMTemplate, error)
+	GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error)
+	ListUTMTemplates(ctx context.Context, owner string) ([]postgres.UTMTemplate, error)
+	DeleteUTMTemplate(ctx context.Context, owner, name string) error
+	SetRedirectRules(ctx context.Context, domain, alias string, rules []postgres.RedirectRule) ([]postgres.RedirectRule, error)
+	AddRedirectRule(ctx context.Context, domain, alias string, rule postgres.RedirectRule, limit int) ([]postgres.RedirectRule, error)
+	DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]postgres.RedirectRule, error)
+	SetSplit(ctx context.Context, domain, alias string, split *postgres.Split) error
+	RecordClicks(ctx context.Context, clicks []postgres.Click) error
+	ClickStats(ctx context.Context, domain, alias string) ([]postgres.VariantClicks, error)
+	ConsumeClick(ctx context.Context, id int64) (int, error)
+	SaveDomain(ctx context.Context, host string) (postgres.Domain, error)
+	ListDomains(ctx context.Context) ([]postgres.Domain, error)
+	DeleteDomain(ctx context.Context, host string) error
+}
+
+// CreateLink проверяет запрос и сохраняет ссылку: подставляет шаблон utm-меток, хеширует пароль
+// и без алиаса подбирает случайный. Ошибки в самом запросе распознает links.ClientError,
+// url, уже сокращенный на этом домене, - storage.ErrURLExists.
+func (s *Service) CreateLink(ctx context.Context, req links.Request) (postgres.Link, error) {
+	const op = "service.CreateLink"
+
+	if err := req.Validate(time.Now()); err != nil {
+		return postgres.Link{}, err
+	}
+	if err := s.checkDomain(req.Domain); err != nil {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := req.HashPassword(); err != nil {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := req.ResolveUTM(ctx, s.storage); err != nil {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	exists, err := s.storage.URLExists(ctx, req.URL, domain.Normalize(req.Domain))
+	if err != nil {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if exists {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	}
+
+	link, err := s.saveWithAlias(ctx, req)
+	if err != nil {
+		return postgres.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+func (s *Service) URLExists(ctx context.Context, url, domain string) (bool, error) {
+	return s.storage.URLExists(ctx, url, domain)
+}
+
+func (s *Service) SaveURL(ctx context.Context, link postgres.Link) (int64, error) {
+	return s.storage.SaveURL(ctx, link)
+}
+
+// SaveURLs сохраняет пачку ссылок, ссылки без алиаса получают случайный - см. saveBatchWithAliases.
+// Если в пачке есть домен бренда, а домены недоступны, не сохраняется ничего.
+func (s *Service) SaveURLs(ctx context.Context, batch []postgres.Link, atomic bool) ([]postgres.SaveResult, error) {
+	for _, link := range batch {
+		if err := s.checkDomain(link.Domain); err != nil {
+			return nil, err
+		}
+	}
+	return s.saveBatchWithAliases(ctx, batch, atomic)
+}
+
+// ImportURLs прерывает импорт на первой строке с доменом бренда, если домены недоступны
+func (s *Service) ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error) {
+	checked := func(yield func(postgres.ImportRow, error) bool) {
+		for row, err := range rows {
+			if err == nil {
+				err = s.checkDomain(row.Link.Domain)
+			}
+			if !yield(row, err) || err != nil {
+				return
+			}
+		}
+	}
+	return s.storage.ImportURLs(ctx, checked, policy)
+}
+
+func (s *Service) ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error] {
+	return s.storage.ExportURLs(ctx, filter)
+}
+
+func (s *Service) GetURL(ctx context.Context, domain, alias string) (string, error) {
+	return s.storage.GetURL(ctx, domain, alias)
+}
+func (s *Service) GetLink(ctx context.Context, domain, alias string) (postgres.Link, error) {
+	return s.storage.GetLink(ctx, domain, alias)
+}
+
+func (s *Service) ListURLs(ctx context.Context, filter postgres.Filter, limit, offset int) ([]postgres.Link, error) {
+	return s.storage.ListURLs(ctx, filter, limit, offset)
+}
+
+func (s *Service) DeleteURl(ctx context.Context, domain, alias string) error {
+	return s.storage.DeleteURl(ctx, domain, alias)
+}
+func (s *Service) DeleteURLs(ctx context.Context, filter postgres.Filter, dryRun bool) ([]string, error) {
+	return s.storage.DeleteURLs(ctx, filter, dryRun)
+}
+
+func (s *Service) RestoreURL(ctx context.Context, domain, alias string) error {
+	return s.storage.RestoreURL(ctx, domain, alias)
+}
+
+// PurgeDeletedURLs удаляет ссылки, которые находятся в корзине дольше retention
+func (s *Service) PurgeDeletedURLs(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.storage.PurgeDeletedURLs(ctx, time.Now().Add(-retention))
 }
 
-func (s *Service) URLExists(ctx context.Context, url string) (bool, error) {
-	return s.storage.URLExists(ctx, url)
+func (s *Service) SaveUTMTemplate(ctx context.Context, t postgres.UTMTemplate) (postgres.UTMTemplate, error) {
+	return s.storage.SaveUTMTemplate(ctx, t)
 }
 
-func (s *Service) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
-	return s.storage.SaveURL(ctx, urlToSave, alias)
+func (s *Service) GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error) {
+	return s.storage.GetUTMTemplate(ctx, owner, name)
 }
-func (s *Service) GetURL(ctx context.Context, alias string) (string, error) {
-	return s.storage.GetURL(ctx, alias)
+
+func (s *Service) ListUTMTemplates(ctx context.Context, owner string) ([]postgres.UTMTemplate, error) {
+	return s.storage.ListUTMTemplates(ctx, owner)
+}
+
+func (s *Service) DeleteUTMTemplate(ctx context.Context, owner, name string) error {
+	return s.storage.DeleteUTMTemplate(ctx, owner, name)
+}
+
+func (s *Service) SetRedirectRules(ctx context.Context, domain, alias string, rules []postgres.RedirectRule) ([]postgres.RedirectRule, error) {
+	return s.storage.SetRedirectRules(ctx, domain, alias, rules)
+}
+
+func (s *Service) AddRedirectRule(ctx context.Context, domain, alias string, rule postgres.RedirectRule, limit int) ([]postgres.RedirectRule, error) {
+	return s.storage.AddRedirectRule(ctx, domain, alias, rule, limit)
 }
-func (s *Service) DeleteURl(ctx context.Context, alias string) error {
-	return s.storage.DeleteURl(ctx, alias)
+
+func (s *Service) DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]postgres.RedirectRule, error) {
+	return s.storage.DeleteRedirectRule(ctx, domain, alias, index)
+}
+
+func (s *Service) SetSplit(ctx context.Context, domain, alias string, split *postgres.Split) error {
+	return s.storage.SetSplit(ctx, domain, alias, split)
+}
+
+func (s *Service) RecordClicks(ctx context.Context, clicks []postgres.Click) error {
+	return s.storage.RecordClicks(ctx, clicks)
+}
+
+func (s *Service) ClickStats(ctx context.Context, domain, alias string) ([]postgres.VariantClicks, error) {
+	return s.storage.ClickStats(ctx, domain, alias)
+}
+
+func (s *Service) ConsumeClick(ctx context.Context, id int64) (int, error) {
+	return s.storage.ConsumeClick(ctx, id)
+}
+
+func (s *Service) SaveDomain(ctx context.Context, host string) (postgres.Domain, error) {
+	if err := s.checkDomain(host); err != nil {
+		return postgres.Domain{}, err
+	}
+	return s.storage.SaveDomain(ctx, host)
+}
+
+func (s *Service) ListDomains(ctx context.Context) ([]postgres.Domain, error) {
+	return s.storage.ListDomains(ctx)
+}
+
+func (s *Service) DeleteDomain(ctx context.Context, host string) error {
+	return s.storage.DeleteDomain(ctx, host)
 }
@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/links"
+	"url-shortener/internal/service"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/mocks"
+	"url-shortener/internal/storage/postgres"
+)
+
+func TestCreateLink(t *testing.T) {
+	const testURL = "https://google.com"
+	utm := postgres.UTM{Source: "newsletter", Medium: "email"}
+
+	tests := []struct {
+		name         string
+		req          links.Request
+		mockBehavior func(s *mocks.StorageInterface)
+		expected     postgres.Link
+		expectedErr  error
+		clientErr    string
+	}{
+		{
+			name: "Success",
+			req:  links.Request{URL: testURL, Alias: "docs", Domain: "Go.Brand-A.com", Owner: "marketing", Tags: []string{"spring"}},
+			mockBehavior: func(s *mocks.StorageInterface) {
+				s.On("URLExists", mock.Anything, testURL, "go.brand-a.com").Return(false, nil)
+				s.On("SaveURL", mock.Anything, postgres.Link{URL: testURL, Alias: "docs", Domain: "go.brand-a.com", Owner: "marketing", Tags: []string{"spring"}}).
+					Return(int64(1), nil)
+			},
+			expected: postgres.Link{ID: 1, URL: testURL, Alias: "docs", Domain: "go.brand-a.com", Owner: "marketing", Tags: []string{"spring"}},
+		},
+		{
+			name: "Utm template is copied into the link",
+			req:  links.Request{URL: testURL, Alias: "docs", Owner: "marketing", UTMTemplate: "mail"},
+			mockBehavior: func(s *mocks.StorageInterface) {
+				s.On("GetUTMTemplate", mock.Anything, "marketing", "mail").
+					Return(postgres.UTMTemplate{Owner: "marketing", Name: "mail", UTM: utm}, nil)
+				s.On("URLExists", mock.Anything, testURL, "").Return(false, nil)
+				s.On("SaveURL", mock.Anything, postgres.Link{URL: testURL, Alias: "docs", Owner: "marketing", UTM: utm}).Return(int64(2), nil)
+			},
+			expected: postgres.Link{ID: 2, URL: testURL, Alias: "docs", Owner: "marketing", UTM: utm},
+		},
+		{
+			name:         "Invalid request",
+			req:          links.Request{URL: testURL, RedirectType: 303},
+			mockBehavior: func(s *mocks.StorageInterface) {},
+			clientErr:    "field RedirectType is not valid",
+		},
+		{
+			name:         "Utm and utm template together",
+			req:          links.Request{URL: testURL, Owner: "marketing", UTM: &utm, UTMTemplate: "mail"},
+			mockBehavior: func(s *mocks.StorageInterface) {},
+			clientErr:    links.ErrUTMConflict.Error(),
+		},
+		{
+			name: "Url already exists",
+			req:  links.Request{URL: testURL, Alias: "docs"},
+			mockBehavior: func(s *mocks.StorageInterface) {
+				s.On("URLExists", mock.Anything, testURL, "").Return(true, nil)
+			},
+			expectedErr: storage.ErrURLExists,
+		},
+		{
+			name: "Unknown domain",
+			req:  links.Request{URL: testURL, Alias: "docs", Domain: "go.unknown.com"},
+			mockBehavior: func(s *mocks.StorageInterface) {
+				s.On("URLExists", mock.Anything, testURL, "go.unknown.com").Return(false, nil)
+				s.On("SaveURL", mock.Anything, mock.AnythingOfType("postgres.Link")).Return(int64(0), storage.ErrDomainNotFound)
+			},
+			clientErr: "unknown domain",
+		},
+		{
+			name: "Storage error",
+			req:  links.Request{URL: testURL, Alias: "docs"},
+			mockBehavior: func(s *mocks.StorageInterface) {
+				s.On("URLExists", mock.Anything, testURL, "").Return(false, context.DeadlineExceeded)
+			},
+			expectedErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := mocks.NewStorageInterface(t)
+			tt.mockBehavior(storageMock)
+
+			link, err := service.NewService(storageMock, "sho.rt").CreateLink(context.Background(), tt.req)
+			switch {
+			case tt.clientErr != "":
+				detail, ok := links.ClientError(err)
+				require.True(t, ok, "unexpected error %v", err)
+				require.Equal(t, tt.clientErr, detail)
+			case tt.expectedErr != nil:
+				require.ErrorIs(t, err, tt.expectedErr)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, link)
+			}
+		})
+	}
+}
+
+func TestCreateLinkHashesPassword(t *testing.T) {
+	storageMock := mocks.NewStorageInterface(t)
+	storageMock.On("URLExists", mock.Anything, "https://google.com", "").Return(false, nil)
+	storageMock.On("SaveURL", mock.Anything, mock.MatchedBy(func(link postgres.Link) bool {
+		return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte("s3cret")) == nil
+	})).Return(int64(7), nil)
+
+	link, err := service.NewService(storageMock, "sho.rt").CreateLink(context.Background(), links.Request{URL: "https://google.com", Password: "s3cret"})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), link.ID)
+	require.Len(t, link.Alias, links.AliasLength)
+	require.NotEmpty(t, link.PasswordHash)
+}
+
+func TestDomainsDisabled(t *testing.T) {
+	ctx := context.Background()
+	// Без redirect.default_domain хранилище не должно вызываться
+	s := service.NewService(mocks.NewStorageInterface(t), "")
+
+	_, err := s.CreateLink(ctx, links.Request{URL: "https://google.com", Domain: "go.brand-a.com"})
+	detail, ok := links.ClientError(err)
+	require.True(t, ok)
+	require.Equal(t, links.ErrDomainsDisabled.Error(), detail)
+
+	_, err = s.SaveDomain(ctx, "go.brand-a.com")
+	require.ErrorIs(t, err, links.ErrDomainsDisabled)
+
+	_, err = s.SaveURLs(ctx, []postgres.Link{{URL: "https://a.com", Alias: "a"}, {URL: "https://b.com", Alias: "b", Domain: "go.brand-a.com"}}, false)
+	require.ErrorIs(t, err, links.ErrDomainsDisabled)
+}
+
+func TestImportURLsDomainsDisabled(t *testing.T) {
+	storageMock := mocks.NewStorageInterface(t)
+	storageMock.On("ImportURLs", mock.Anything, mock.Anything, postgres.ConflictSkip).
+		Return(func(_ context.Context, rows iter.Seq2[postgres.ImportRow, error], _ postgres.ConflictPolicy) (postgres.ImportStats, error) {
+			var stats postgres.ImportStats
+			for _, err := range rows {
+				if err != nil {
+					return stats, err
+				}
+				stats.Created++
+			}
+			return stats, nil
+		})
+
+	rows := func(yield func(postgres.ImportRow, error) bool) {
+		for _, link := range []postgres.Link{{Alias: "a"}, {Alias: "b", Domain: "go.brand-a.com"}, {Alias: "c"}} {
+			if !yield(postgres.ImportRow{Link: link}, nil) {
+				return
+			}
+		}
+	}
+	stats, err := service.NewService(storageMock, "").ImportURLs(context.Background(), rows, postgres.ConflictSkip)
+	require.ErrorIs(t, err, links.ErrDomainsDisabled)
+	require.Equal(t, 1, stats.Created)
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrDomainNotFound = errors.New("domain not found")
+	ErrDomainExists   = errors.New("domain exists")
+	ErrDomainInUse    = errors.New("domain has links")
+)
+
+// foreignKeyViolation - код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// byAlias - условие выборки ссылки по алиасу ($1) и домену ($2). Пустой домен - основной.
+const byAlias = `alias = $1 AND domain IS NOT DISTINCT FROM NULLIF($2, '')`
+
+// Domain - короткий домен бренда. Алиасы уникальны в пределах домена.
+type Domain struct {
+	Host      string
+	CreatedAt time.Time
+}
+
+func scanDomain(row pgx.Row) (Domain, error) {
+	var d Domain
+	err := row.Scan(&d.Host, &d.CreatedAt)
+	return d, err
+}
+
+func (s *StoragePool) SaveDomain(ctx context.Context, host string) (Domain, error) {
+	const op = "postgres.storage.SaveDomain"
+	d, err := scanDomain(s.pool.QueryRow(ctx, `INSERT INTO domains (host) VALUES ($1) RETURNING host, created_at`, host))
+	if isUniqueViolation(err) {
+		return Domain{}, fmt.Errorf("%s: %w", op, ErrDomainExists)
+	}
+	if err != nil {
+		return Domain{}, fmt.Errorf("%s failed to save domain: %w", op, err)
+	}
+	return d, nil
+}
+
+func (s *StoragePool) ListDomains(ctx context.Context) ([]Domain, error) {
+	const op = "postgres.storage.ListDomains"
+	rows, err := s.pool.Query(ctx, `SELECT host, created_at FROM domains ORDER BY host`)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to list domains: %w", op, err)
+	}
+	domains, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Domain, error) {
+		return scanDomain(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read domains: %w", op, err)
+	}
+	return domains, nil
+}
+
+// DeleteDomain удаляет домен без ссылок. Удаленные, но еще не очищенные ссылки тоже занимают домен.
+func (s *StoragePool) DeleteDomain(ctx context.Context, host string) error {
+	const op = "postgres.storage.DeleteDomain"
+	res, err := s.pool.Exec(ctx, `DELETE FROM domains WHERE host = $1`, host)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("%s: %w", op, ErrDomainInUse)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed to delete domain: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrDomainNotFound)
+	}
+	return nil
+}
+
+// unknownDomains возвращает домены ссылок, которых нет в таблице domains
+func unknownDomains(ctx context.Context, tx pgx.Tx, links []Link) (map[string]bool, error) {
+	var hosts []string
+	for _, link := range links {
+		if link.Domain != "" {
+			hosts = append(hosts, link.Domain)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(ctx, `SELECT host FROM domains WHERE host = ANY($1)`, hosts)
+	if err != nil {
+		return nil, err
+	}
+	known, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	unknown := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		unknown[h] = true
+	}
+	for _, h := range known {
+		delete(unknown, h)
+	}
+	return unknown, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
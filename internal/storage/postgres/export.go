@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// exportFetchSize - сколько строк читается из курсора за один FETCH
+const exportFetchSize = 1000
+
+// ExportedLink - ссылка из выгрузки вместе с переходами по ней
+type ExportedLink struct {
+	Link
+	// Clicks - переходы по вариантам A/B-теста, переходы без теста учтены с пустым Variant
+	Clicks []VariantClicks
+}
+
+// exportClicks считает переходы по ссылке курсора, используя idx_clicks_url_variant
+const exportClicks = `COALESCE((SELECT jsonb_agg(jsonb_build_object('variant', variant, 'clicks', n) ORDER BY variant)
+	FROM (SELECT variant, count(*) AS n FROM clicks WHERE url_id = url.id GROUP BY variant) c), '[]')`
+
+// ExportURLs читает ссылки, включая удаленные, со статистикой переходов через серверный
+// курсор порциями по exportFetchSize, поэтому память не растет с размером таблицы.
+// Чтение идет в одной read-only транзакции и видит согласованный снимок.
+func (s *StoragePool) ExportURLs(ctx context.Context, filter Filter) iter.Seq2[ExportedLink, error] {
+	const op = "postgres.storage.ExportURLs"
+
+	return func(yield func(ExportedLink, error) bool) {
+		tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+		if err != nil {
+			yield(ExportedLink{}, fmt.Errorf("%s failed to begin transaction: %w", op, err))
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		where, args := filter.where()
+		_, err = tx.Exec(ctx, `DECLARE export_cursor NO SCROLL CURSOR FOR SELECT `+linkColumns+`, `+exportClicks+` FROM url WHERE `+where+` ORDER BY id`, args...)
+		if err != nil {
+			yield(ExportedLink{}, fmt.Errorf("%s failed to declare cursor: %w", op, err))
+			return
+		}
+
+		fetch := fmt.Sprintf(`FETCH FORWARD %d FROM export_cursor`, exportFetchSize)
+		for {
+			rows, err := tx.Query(ctx, fetch)
+			if err != nil {
+				yield(ExportedLink{}, fmt.Errorf("%s failed to fetch urls: %w", op, err))
+				return
+			}
+
+			fetched := 0
+			for rows.Next() {
+				var link ExportedLink
+				err := rows.Scan(append(link.scanArgs(), &link.Clicks)...)
+				if err != nil {
+					rows.Close()
+					yield(ExportedLink{}, fmt.Errorf("%s failed to scan url: %w", op, err))
+					return
+				}
+				fetched++
+				if !yield(link, nil) {
+					rows.Close()
+					return
+				}
+			}
+			if err := rows.Err(); err != nil {
+				yield(ExportedLink{}, fmt.Errorf("%s failed to fetch urls: %w", op, err))
+				return
+			}
+			if fetched < exportFetchSize {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter задает выборку ссылок для массовых операций.
+// Непустые поля объединяются через AND. Aliases ищутся в домене Domain,
+// без Domain - на основном домене, иначе один алиас задел бы ссылки всех брендов.
+type Filter struct {
+	Aliases       []string
+	Domain        string
+	Owner         string
+	Tag           string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.Aliases) == 0 && f.Domain == "" && f.Owner == "" && f.Tag == "" && f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero()
+}
+
+// where собирает условие WHERE и аргументы запроса. Для пустого фильтра возвращает TRUE.
+func (f Filter) where() (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if len(f.Aliases) > 0 {
+		add("alias = ANY($%d)", f.Aliases)
+		add("domain IS NOT DISTINCT FROM NULLIF($%d, '')", f.Domain)
+	} else if f.Domain != "" {
+		add("domain = $%d", f.Domain)
+	}
+	if f.Owner != "" {
+		add("owner = $%d", f.Owner)
+	}
+	if f.Tag != "" {
+		add("$%d = ANY(tags)", f.Tag)
+	}
+	if !f.CreatedAfter.IsZero() {
+		add("created_at >= $%d", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		add("created_at < $%d", f.CreatedBefore)
+	}
+
+	if len(conds) == 0 {
+		return "TRUE", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterWhere(t *testing.T) {
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		filter        Filter
+		expectedWhere string
+		expectedArgs  []any
+	}{
+		{
+			name:          "Empty",
+			filter:        Filter{},
+			expectedWhere: "TRUE",
+		},
+		{
+			name:          "Aliases only",
+			filter:        Filter{Aliases: []string{"a", "b"}},
+			expectedWhere: "alias = ANY($1) AND domain IS NOT DISTINCT FROM NULLIF($2, '')",
+			expectedArgs:  []any{[]string{"a", "b"}, ""},
+		},
+		{
+			name:          "Aliases on domain",
+			filter:        Filter{Aliases: []string{"a"}, Domain: "go.brand.com"},
+			expectedWhere: "alias = ANY($1) AND domain IS NOT DISTINCT FROM NULLIF($2, '')",
+			expectedArgs:  []any{[]string{"a"}, "go.brand.com"},
+		},
+		{
+			name:          "Domain only",
+			filter:        Filter{Domain: "go.brand.com", Owner: "marketing"},
+			expectedWhere: "domain = $1 AND owner = $2",
+			expectedArgs:  []any{"go.brand.com", "marketing"},
+		},
+		{
+			name:          "Owner, tag and date",
+			filter:        Filter{Owner: "marketing", Tag: "spring", CreatedBefore: before},
+			expectedWhere: "owner = $1 AND $2 = ANY(tags) AND created_at < $3",
+			expectedArgs:  []any{"marketing", "spring", before},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.where()
+			require.Equal(t, tt.expectedWhere, where)
+			require.Equal(t, tt.expectedArgs, args)
+			require.Equal(t, tt.expectedArgs == nil, tt.filter.IsEmpty())
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ConflictPolicy определяет, что делать при импорте ссылки, алиас или url которой уже заняты
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+func (p ConflictPolicy) Valid() bool {
+	switch p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return true
+	}
+	return false
+}
+
+// importChunkSize - сколько строк отправляется в Postgres одним pgx.Batch
+const importChunkSize = 500
+
+// ImportRow - ссылка из внешнего источника вместе с номером строки для отчета
+type ImportRow struct {
+	Line int
+	Link Link
+}
+
+type ImportStats struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+	Conflicts   []ImportRow
+	// UnknownDomains - строки с незарегистрированным доменом, они не сохраняются
+	UnknownDomains []ImportRow
+}
+
+const (
+	importColumns = `url, alias, owner, tags, domain, redirect_type, passthrough, utm, rules, split, password_hash, max_clicks, clicks_used, active_from, expires_at, title, interstitial, created_at, deleted_at`
+	importValues  = `SELECT $1, $2, NULLIF($3, ''), COALESCE($4::text[], '{}'), NULLIF($5, ''), NULLIF($6::smallint, 0), NULLIF($7, ''), NULLIF($8::jsonb, '{}'), $9::jsonb, $10::jsonb,
+		NULLIF($11, ''), NULLIF($12::integer, 0), $13::integer, $14::timestamptz, $15::timestamptz, NULLIF($16, ''), $17::boolean, COALESCE($18::timestamptz, now()), $19::timestamptz`
+
+	importInsertQuery = `INSERT INTO url (` + importColumns + `)
+		` + importValues + `
+		ON CONFLICT DO NOTHING
+		RETURNING id, true`
+	// При перезаписи конфликт по алиасу на том же домене заменяет все поля ссылки,
+	// а конфликт по url с другой живой ссылкой того же домена отсекается заранее,
+	// чтобы не прерывать транзакцию. Удаленная ссылка url не занимает.
+	// Без created_at в файле у перезаписанной ссылки остается прежняя дата создания.
+	// xmax = 0 означает, что строка была вставлена, а не обновлена.
+	importOverwriteQuery = `INSERT INTO url (` + importColumns + `)
+		` + importValues + `
+		WHERE $19::timestamptz IS NOT NULL
+			OR NOT EXISTS (SELECT 1 FROM url WHERE url = $1 AND domain IS NOT DISTINCT FROM NULLIF($5, '') AND alias <> $2 AND deleted_at IS NULL)
+		ON CONFLICT (alias, domain) DO UPDATE
+			SET url = EXCLUDED.url, owner = EXCLUDED.owner, tags = EXCLUDED.tags,
+				redirect_type = EXCLUDED.redirect_type, passthrough = EXCLUDED.passthrough, utm = EXCLUDED.utm,
+				rules = EXCLUDED.rules, split = EXCLUDED.split,
+				password_hash = EXCLUDED.password_hash, max_clicks = EXCLUDED.max_clicks, clicks_used = EXCLUDED.clicks_used,
+				active_from = EXCLUDED.active_from, expires_at = EXCLUDED.expires_at,
+				title = EXCLUDED.title, interstitial = EXCLUDED.interstitial,
+				created_at = COALESCE($18::timestamptz, url.created_at), deleted_at = EXCLUDED.deleted_at
+		RETURNING id, xmax = 0`
+)
+
+// importArgs - параметры importInsertQuery и importOverwriteQuery
+func (link Link) importArgs() []any {
+	// Пустые правила и время создания передаются как NULL
+	var rules any
+	if len(link.Rules) > 0 {
+		rules = link.Rules
+	}
+	var createdAt *time.Time
+	if !link.CreatedAt.IsZero() {
+		createdAt = &link.CreatedAt
+	}
+	return []any{link.URL, link.Alias, link.Owner, link.Tags, link.Domain, link.RedirectType, link.Passthrough, link.UTM, rules, link.Split,
+		link.PasswordHash, link.MaxClicks, link.ClicksUsed, link.ActiveFrom, link.ExpiresAt, link.Title, link.Interstitial, createdAt, link.DeletedAt}
+}
+
+// ImportURLs потоково сохраняет строки из rows в одной транзакции пачками по importChunkSize.
+// Ошибка источника откатывает весь импорт. При ConflictFail первый же конфликт
+// откатывает транзакцию и возвращает ErrURLExists вместе с найденным конфликтом.
+// Строки с незарегистрированным доменом пропускаются и попадают в UnknownDomains.
+func (s *StoragePool) ImportURLs(ctx context.Context, rows iter.Seq2[ImportRow, error], policy ConflictPolicy) (ImportStats, error) {
+	const op = "postgres.storage.ImportURLs"
+	var stats ImportStats
+
+	if !policy.Valid() {
+		return stats, fmt.Errorf("%s: unknown conflict policy %q", op, policy)
+	}
+
+	query := importInsertQuery
+	if policy == ConflictOverwrite {
+		query = importOverwriteQuery
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("%s failed to begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	chunk := make([]ImportRow, 0, importChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		links := make([]Link, len(chunk))
+		for i, row := range chunk {
+			links[i] = row.Link
+		}
+		// Без проверки вставка с неизвестным доменом нарушит внешний ключ и прервет транзакцию
+		unknown, err := unknownDomains(ctx, tx, links)
+		if err != nil {
+			return fmt.Errorf("failed to check domains: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for _, row := range chunk {
+			if unknown[row.Link.Domain] {
+				continue
+			}
+			batch.Queue(query, row.Link.importArgs()...)
+		}
+		br := tx.SendBatch(ctx, batch)
+		defer br.Close()
+
+		for _, row := range chunk {
+			if unknown[row.Link.Domain] {
+				stats.UnknownDomains = append(stats.UnknownDomains, row)
+				continue
+			}
+			var id int64
+			var inserted bool
+			err := br.QueryRow().Scan(&id, &inserted)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				stats.Conflicts = append(stats.Conflicts, row)
+				if policy == ConflictFail {
+					return ErrURLExists
+				}
+				stats.Skipped++
+			case err != nil:
+				return fmt.Errorf("failed to import alias %q: %w", row.Link.Alias, err)
+			case inserted:
+				stats.Created++
+			default:
+				stats.Overwritten++
+			}
+		}
+		chunk = chunk[:0]
+		return br.Close()
+	}
+
+	for row, err := range rows {
+		if err != nil {
+			return stats, fmt.Errorf("%s failed to read rows: %w", op, err)
+		}
+		chunk = append(chunk, row)
+		if len(chunk) == importChunkSize {
+			if err := flush(); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return stats, fmt.Errorf("%s failed to commit transaction: %w", op, err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrClicksExhausted = errors.New("click limit exhausted")
+
+// ConsumeClick списывает один переход со ссылки с max_clicks и возвращает, сколько осталось.
+// Строка блокируется на время UPDATE, а условие перепроверяется после ожидания блокировки,
+// поэтому параллельные редиректы с любых реплик не превысят лимит.
+// Если переходов не осталось или ссылку успели удалить, возвращается ErrClicksExhausted.
+func (s *StoragePool) ConsumeClick(ctx context.Context, id int64) (int, error) {
+	const op = "postgres.storage.ConsumeClick"
+	var left int
+	err := s.pool.QueryRow(ctx, `UPDATE url SET clicks_used = clicks_used + 1
+		WHERE id = $1 AND deleted_at IS NULL AND clicks_used < max_clicks
+		RETURNING max_clicks - clicks_used`, id).Scan(&left)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, ErrClicksExhausted)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to consume click: %w", op, err)
+	}
+	return left, nil
+}
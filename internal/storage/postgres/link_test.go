@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkState(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		link     Link
+		expected string
+	}{
+		{name: "No schedule", expected: StateActive},
+		{name: "Not active yet", link: Link{ActiveFrom: &after}, expected: StateScheduled},
+		{name: "Activated", link: Link{ActiveFrom: &before}, expected: StateActive},
+		{name: "Activates right now", link: Link{ActiveFrom: &now}, expected: StateActive},
+		{name: "Expires later", link: Link{ActiveFrom: &before, ExpiresAt: &after}, expected: StateActive},
+		{name: "Expires right now", link: Link{ExpiresAt: &now}, expected: StateExpired},
+		{name: "Expired", link: Link{ExpiresAt: &before}, expected: StateExpired},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, tt.link.State(now), tt.name)
+	}
+}
+
+func TestLinkWithoutTargets(t *testing.T) {
+	link := Link{
+		Alias:        "secret",
+		URL:          "https://example.com",
+		PasswordHash: "$2a$10$hash",
+		Rules:        []RedirectRule{{Device: "ios", Target: "https://apps.apple.com/app/id1"}},
+		Split:        &Split{Variants: []Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}}},
+	}
+	require.True(t, link.HidesTarget())
+	require.True(t, Link{URL: "https://example.com", MaxClicks: 1}.HidesTarget())
+	require.False(t, Link{URL: "https://example.com"}.HidesTarget())
+
+	hidden := link.WithoutTargets()
+	require.Empty(t, hidden.URL)
+	require.Nil(t, hidden.Split)
+	require.Equal(t, []RedirectRule{{Device: "ios"}}, hidden.Rules)
+	// Правила копируются, исходная ссылка не меняется
+	require.Equal(t, "https://apps.apple.com/app/id1", link.Rules[0].Target)
+}
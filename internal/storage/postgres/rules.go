@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrRuleNotFound = errors.New("redirect rule not found")
+	ErrTooManyRules = errors.New("too many redirect rules")
+)
+
+// RedirectRule - условие, при котором редирект ведет на Target вместо URL ссылки.
+// Пустое поле не проверяется, заполненные поля должны совпасть все.
+// Правила проверяются по порядку, URL ссылки служит целью по умолчанию.
+type RedirectRule struct {
+	// Device - ios, android, mobile или desktop
+	Device string `json:"device,omitempty"`
+	// Languages - языки из Accept-Language: "en" совпадает с en-US, "en-US" только с en-US
+	Languages []string `json:"languages,omitempty"`
+	// Countries - ISO-коды стран в верхнем регистре
+	Countries []string `json:"countries,omitempty"`
+	// Target пустой только в ответах API для ссылок со скрытой целью, см. Link.HidesTarget
+	Target string `json:"target,omitempty"`
+}
+
+// SetRedirectRules заменяет правила ссылки целиком. Пустой список удаляет правила.
+func (s *StoragePool) SetRedirectRules(ctx context.Context, domain, alias string, rules []RedirectRule) ([]RedirectRule, error) {
+	const op = "postgres.storage.SetRedirectRules"
+	if rules == nil {
+		rules = []RedirectRule{}
+	}
+	var saved []RedirectRule
+	err := s.pool.QueryRow(ctx, `
+		UPDATE url SET rules = NULLIF($3::jsonb, '[]') WHERE `+byAlias+` AND deleted_at IS NULL
+		RETURNING COALESCE(rules, '[]')`, alias, domain, rules).Scan(&saved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to set redirect rules: %w", op, err)
+	}
+	return saved, nil
+}
+
+// AddRedirectRule добавляет правило в конец списка, если в нем меньше limit правил
+func (s *StoragePool) AddRedirectRule(ctx context.Context, domain, alias string, rule RedirectRule, limit int) ([]RedirectRule, error) {
+	const op = "postgres.storage.AddRedirectRule"
+	var saved []RedirectRule
+	err := s.pool.QueryRow(ctx, `
+		UPDATE url SET rules = COALESCE(rules, '[]') || jsonb_build_array($3::jsonb)
+		WHERE `+byAlias+` AND deleted_at IS NULL AND jsonb_array_length(COALESCE(rules, '[]')) < $4
+		RETURNING rules`, alias, domain, rule, limit).Scan(&saved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.rulesUpdateError(ctx, op, domain, alias, ErrTooManyRules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to add redirect rule: %w", op, err)
+	}
+	return saved, nil
+}
+
+// DeleteRedirectRule удаляет правило по номеру, следующие правила сдвигаются
+func (s *StoragePool) DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]RedirectRule, error) {
+	const op = "postgres.storage.DeleteRedirectRule"
+	var saved []RedirectRule
+	err := s.pool.QueryRow(ctx, `
+		UPDATE url SET rules = NULLIF(rules - $3::int, '[]')
+		WHERE `+byAlias+` AND deleted_at IS NULL AND $3 >= 0 AND $3 < jsonb_array_length(COALESCE(rules, '[]'))
+		RETURNING COALESCE(rules, '[]')`, alias, domain, index).Scan(&saved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.rulesUpdateError(ctx, op, domain, alias, ErrRuleNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to delete redirect rule: %w", op, err)
+	}
+	return saved, nil
+}
+
+// rulesUpdateError различает отсутствующую ссылку и невыполненное условие на правила
+func (s *StoragePool) rulesUpdateError(ctx context.Context, op, domain, alias string, conditionErr error) error {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM url WHERE `+byAlias+` AND deleted_at IS NULL)`, alias, domain).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s failed to check url existence: %w", op, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+	return fmt.Errorf("%s: %w", op, conditionErr)
+}
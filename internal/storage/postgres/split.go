@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Способы закрепить вариант A/B-теста за клиентом
+const (
+	// StickyNone выбирает вариант заново на каждый переход
+	StickyNone = ""
+	// StickyCookie запоминает вариант в cookie
+	StickyCookie = "cookie"
+	// StickyHash выбирает вариант по хешу IP и User-Agent клиента
+	StickyHash = "hash"
+)
+
+// Split - взвешенные варианты цели ссылки. Доля трафика варианта - его вес,
+// деленный на сумму весов. Хранится в колонке split как JSONB.
+type Split struct {
+	Sticky   string    `json:"sticky,omitempty"`
+	Variants []Variant `json:"variants"`
+}
+
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Click - переход по ссылке для аналитики. Variant пустой, если у ссылки нет A/B-теста.
+type Click struct {
+	LinkID    int64
+	Variant   string
+	ClickedAt time.Time
+}
+
+// VariantClicks - число переходов по одному варианту
+type VariantClicks struct {
+	Variant string `json:"variant"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// SetSplit заменяет варианты ссылки. nil убирает A/B-тест, ссылка снова ведет на url.
+func (s *StoragePool) SetSplit(ctx context.Context, domain, alias string, split *Split) error {
+	const op = "postgres.storage.SetSplit"
+	res, err := s.pool.Exec(ctx, `UPDATE url SET split = $3 WHERE `+byAlias+` AND deleted_at IS NULL`, alias, domain, split)
+	if err != nil {
+		return fmt.Errorf("%s failed to set split: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+	return nil
+}
+
+// RecordClicks записывает пачку переходов через COPY
+func (s *StoragePool) RecordClicks(ctx context.Context, clicks []Click) error {
+	const op = "postgres.storage.RecordClicks"
+	_, err := s.pool.CopyFrom(ctx, pgx.Identifier{"clicks"}, []string{"url_id", "variant", "clicked_at"},
+		pgx.CopyFromSlice(len(clicks), func(i int) ([]any, error) {
+			return []any{clicks[i].LinkID, clicks[i].Variant, clicks[i].ClickedAt}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("%s failed to record clicks: %w", op, err)
+	}
+	return nil
+}
+
+// ClickStats считает переходы по ссылке с разбивкой по вариантам, включая удаленные из теста варианты
+func (s *StoragePool) ClickStats(ctx context.Context, domain, alias string) ([]VariantClicks, error) {
+	const op = "postgres.storage.ClickStats"
+	var id int64
+	err := s.pool.QueryRow(ctx, `SELECT id FROM url WHERE `+byAlias+` AND deleted_at IS NULL`, alias, domain).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to get url: %w", op, err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT variant, count(*) FROM clicks WHERE url_id = $1 GROUP BY variant ORDER BY variant`, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to count clicks: %w", op, err)
+	}
+	stats, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (VariantClicks, error) {
+		var v VariantClicks
+		err := row.Scan(&v.Variant, &v.Clicks)
+		return v, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read clicks: %w", op, err)
+	}
+	return stats, nil
+}
@@ -4,12 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrURLNotFound  = errors.New("url not found")
+	ErrURLExists    = errors.New("url exists")
+	ErrBatchAborted = errors.New("batch aborted")
+	ErrEmptyFilter  = errors.New("empty filter")
+)
+
+// uniqueViolation - код ошибки Postgres при нарушении UNIQUE-ограничения
+const uniqueViolation = "23505"
+
 type StoragePool struct {
 	pool *pgxpool.Pool
 }
@@ -21,6 +33,7 @@ func NewDatabase(ctx context.Context, databaseURL string) (*StoragePool, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database connection string: %w", err)
 	}
+	config.ConnConfig.Tracer = queryTracer{}
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
@@ -45,39 +58,241 @@ func (d *StoragePool) Close() error {
 	return nil
 }
 
+// Link описывает короткую ссылку. ID, CreatedAt и DeletedAt заполняются
+// только при чтении из базы.
+type Link struct {
+	ID    int64
+	URL   string
+	Alias string
+	// Domain - домен бренда, в котором уникален алиас, пустая строка - основной домен
+	Domain string
+	Owner  string
+	Tags   []string
+	// RedirectType - HTTP-статус редиректа (301, 302, 307, 308), 0 - статус по умолчанию из конфига
+	RedirectType int
+	// Passthrough - политика переноса пути и параметров запроса в целевой URL (keep, replace, append),
+	// пустая строка отключает перенос
+	Passthrough string
+	UTM         UTM
+	// Rules - правила выбора цели по устройству, языку и стране, см. RedirectRule
+	Rules []RedirectRule
+	// Split - варианты A/B-теста, nil - ссылка ведет на URL
+	Split *Split
+	// PasswordHash - bcrypt-хеш пароля, пустая строка - ссылка без пароля
+	PasswordHash string
+	// MaxClicks - сколько раз можно перейти по ссылке, 0 - без лимита. ClicksUsed - сколько уже перешли.
+	MaxClicks  int
+	ClicksUsed int
+	// ActiveFrom и ExpiresAt задают окно, в котором ссылка редиректит, nil - без ограничения, см. State
+	ActiveFrom *time.Time
+	ExpiresAt  *time.Time
+	// Title - название для страницы предпросмотра. Interstitial - показывать ее вместо редиректа.
+	Title        string
+	Interstitial bool
+	CreatedAt    time.Time
+	DeletedAt    *time.Time
+}
+
+// Состояния ссылки по ActiveFrom и ExpiresAt
+const (
+	StateScheduled = "scheduled"
+	StateActive    = "active"
+	StateExpired   = "expired"
+)
+
+// State возвращает состояние ссылки на момент now
+func (link Link) State(now time.Time) string {
+	switch {
+	case link.ActiveFrom != nil && now.Before(*link.ActiveFrom):
+		return StateScheduled
+	case link.ExpiresAt != nil && !now.Before(*link.ExpiresAt):
+		return StateExpired
+	}
+	return StateActive
+}
+
+// HidesTarget сообщает, что адреса назначения ссылки нельзя отдавать в API метаданных:
+// иначе по ним можно перейти, не вводя пароль и не тратя переход из MaxClicks
+func (link Link) HidesTarget() bool {
+	return link.PasswordHash != "" || link.MaxClicks > 0
+}
+
+// WithoutTargets возвращает копию ссылки без URL, целей правил и вариантов A/B.
+// Условия правил остаются, чтобы было видно, как настроена ссылка.
+func (link Link) WithoutTargets() Link {
+	link.URL = ""
+	link.Split = nil
+	if link.Rules != nil {
+		rules := make([]RedirectRule, len(link.Rules))
+		for i, rule := range link.Rules {
+			rule.Target = ""
+			rules[i] = rule
+		}
+		link.Rules = rules
+	}
+	return link
+}
+
+// linkColumns - колонки, которые читает scanLink
+const linkColumns = `id, url, alias, COALESCE(domain, ''), COALESCE(owner, ''), tags, COALESCE(redirect_type, 0), COALESCE(passthrough, ''), COALESCE(utm, '{}'), COALESCE(rules, '[]'), split, COALESCE(password_hash, ''), COALESCE(max_clicks, 0), clicks_used, active_from, expires_at, COALESCE(title, ''), interstitial, created_at, deleted_at`
+
+func scanLink(row pgx.Row) (Link, error) {
+	var link Link
+	err := row.Scan(link.scanArgs()...)
+	return link, err
+}
+
+// scanArgs - приемники для колонок linkColumns по порядку
+func (link *Link) scanArgs() []any {
+	return []any{&link.ID, &link.URL, &link.Alias, &link.Domain, &link.Owner, &link.Tags, &link.RedirectType, &link.Passthrough, &link.UTM, &link.Rules, &link.Split, &link.PasswordHash, &link.MaxClicks, &link.ClicksUsed, &link.ActiveFrom, &link.ExpiresAt, &link.Title, &link.Interstitial, &link.CreatedAt, &link.DeletedAt}
+}
+
+// SaveResult - результат сохранения одной ссылки из пакета
+type SaveResult struct {
+	ID int64
+	// Alias - алиас, под которым ссылка сохранялась
+	Alias string
+	Err   error
+}
+
 type PostgresStorageInterface interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error)
-	GetURL(ctx context.Context, alias string) (string, error)
-	DeleteURl(ctx context.Context, alias string) error
-	URLExists(ctx context.Context, url string) (bool, error)
+	SaveURL(ctx context.Context, link Link) (int64, error)
+	SaveURLs(ctx context.Context, links []Link, atomic bool) ([]SaveResult, error)
+	ImportURLs(ctx context.Context, rows iter.Seq2[ImportRow, error], policy ConflictPolicy) (ImportStats, error)
+	ExportURLs(ctx context.Context, filter Filter) iter.Seq2[ExportedLink, error]
+	GetURL(ctx context.Context, domain, alias string) (string, error)
+	GetLink(ctx context.Context, domain, alias string) (Link, error)
+	ListURLs(ctx context.Context, filter Filter, limit, offset int) ([]Link, error)
+	DeleteURl(ctx context.Context, domain, alias string) error
+	DeleteURLs(ctx context.Context, filter Filter, dryRun bool) ([]string, error)
+	RestoreURL(ctx context.Context, domain, alias string) error
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+	URLExists(ctx context.Context, url, domain string) (bool, error)
+	SaveUTMTemplate(ctx context.Context, t UTMTemplate) (UTMTemplate, error)
+	GetUTMTemplate(ctx context.Context, owner, name string) (UTMTemplate, error)
+	ListUTMTemplates(ctx context.Context, owner string) ([]UTMTemplate, error)
+	DeleteUTMTemplate(ctx context.Context, owner, name string) error
+	SetRedirectRules(ctx context.Context, domain, alias string, rules []RedirectRule) ([]RedirectRule, error)
+	AddRedirectRule(ctx context.Context, domain, alias string, rule RedirectRule, limit int) ([]RedirectRule, error)
+	DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]RedirectRule, error)
+	SetSplit(ctx context.Context, domain, alias string, split *Split) error
+	RecordClicks(ctx context.Context, clicks []Click) error
+	ClickStats(ctx context.Context, domain, alias string) ([]VariantClicks, error)
+	ConsumeClick(ctx context.Context, id int64) (int, error)
+	SaveDomain(ctx context.Context, host string) (Domain, error)
+	ListDomains(ctx context.Context) ([]Domain, error)
+	DeleteDomain(ctx context.Context, host string) error
 }
 
-func (d *StoragePool) URLExists(ctx context.Context, url string) (bool, error) {
+// URLExists проверяет, есть ли живая ссылка на url на домене domain, пустой domain - основной домен
+func (d *StoragePool) URLExists(ctx context.Context, url, domain string) (bool, error) {
 	const op = "postgres.storage.AliasExists"
 	var exists bool
-	err := d.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM url WHERE url = $1)`, url).Scan(&exists)
+	err := d.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM url WHERE url = $1 AND domain IS NOT DISTINCT FROM NULLIF($2, '') AND deleted_at IS NULL)`, url, domain).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("%s: failed to check url existence: %w", op, err)
 	}
 	return exists, nil
 
 }
-func (s *StoragePool) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
+
+const insertURLQuery = `INSERT INTO url (url, alias, owner, tags, redirect_type, passthrough, utm, split, password_hash, max_clicks, active_from, expires_at, title, interstitial, domain)
+	VALUES ($1, $2, NULLIF($3, ''), COALESCE($4::text[], '{}'), NULLIF($5::smallint, 0), NULLIF($6, ''), NULLIF($7::jsonb, '{}'), $8::jsonb, NULLIF($9, ''), NULLIF($10::integer, 0), $11, $12, NULLIF($13, ''), $14, NULLIF($15, ''))`
+
+// insertArgs - параметры insertURLQuery
+func (link Link) insertArgs() []any {
+	return []any{link.URL, link.Alias, link.Owner, link.Tags, link.RedirectType, link.Passthrough, link.UTM, link.Split, link.PasswordHash, link.MaxClicks, link.ActiveFrom, link.ExpiresAt, link.Title, link.Interstitial, link.Domain}
+}
+
+func (s *StoragePool) SaveURL(ctx context.Context, link Link) (int64, error) {
 	const op = "postgres.storage.SaveURL"
 	var id int64
-	err := s.pool.QueryRow(ctx, `INSERT INTO url (url, alias) VALUES ($1, $2) RETURNING id`, urlToSave, alias).Scan(&id)
+	err := s.pool.QueryRow(ctx, insertURLQuery+` RETURNING id`, link.insertArgs()...).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, ErrURLExists)
+	}
+	if isForeignKeyViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, ErrDomainNotFound)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("%s failed to save url: %w", op, err)
 	}
 	return id, nil
 }
 
-func (s *StoragePool) GetURL(ctx context.Context, alias string) (string, error) {
+// SaveURLs сохраняет ссылки одной транзакцией через pgx.Batch.
+// Конфликтующие ссылки получают ErrURLExists, ссылки на незарегистрированный домен -
+// ErrDomainNotFound. Если atomic = true, любая такая ошибка откатывает всю транзакцию,
+// а остальные ссылки получают ErrBatchAborted.
+func (s *StoragePool) SaveURLs(ctx context.Context, links []Link, atomic bool) ([]SaveResult, error) {
+	const op = "postgres.storage.SaveURLs"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Неизвестный домен нарушил бы внешний ключ и прервал транзакцию, поэтому такие ссылки отсеиваются заранее
+	unknown, err := unknownDomains(ctx, tx, links)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to check domains: %w", op, err)
+	}
+
+	results := make([]SaveResult, len(links))
+	conflicts := 0
+	batch := &pgx.Batch{}
+	for i, link := range links {
+		results[i].Alias = link.Alias
+		if unknown[link.Domain] {
+			results[i].Err = ErrDomainNotFound
+			conflicts++
+			continue
+		}
+		batch.Queue(insertURLQuery+` ON CONFLICT DO NOTHING RETURNING id`, link.insertArgs()...)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	for i, link := range links {
+		if unknown[link.Domain] {
+			continue
+		}
+		err := br.QueryRow().Scan(&results[i].ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			results[i].Err = ErrURLExists
+			conflicts++
+			continue
+		}
+		if err != nil {
+			br.Close()
+			return nil, fmt.Errorf("%s failed to save url: %w", op, err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return nil, fmt.Errorf("%s failed to close batch: %w", op, err)
+	}
+
+	if atomic && conflicts > 0 {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = SaveResult{Alias: results[i].Alias, Err: ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s failed to commit transaction: %w", op, err)
+	}
+	return results, nil
+}
+
+func (s *StoragePool) GetURL(ctx context.Context, domain, alias string) (string, error) {
 	const op = "postgres.storage.GetURL"
 	var url string
-	err := s.pool.QueryRow(ctx, `SELECT url FROM url WHERE alias = $1`, alias).Scan(&url)
+	err := s.pool.QueryRow(ctx, `SELECT url FROM url WHERE `+byAlias+` AND deleted_at IS NULL`, alias, domain).Scan(&url)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return "", fmt.Errorf("%s url not found: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, ErrURLNotFound)
 	}
 	if err != nil {
 		return "", fmt.Errorf("%s failed to get url: %w", op, err)
@@ -85,15 +300,107 @@ func (s *StoragePool) GetURL(ctx context.Context, alias string) (string, error)
 	return url, nil
 }
 
-func (s *StoragePool) DeleteURl(ctx context.Context, alias string) error {
+// GetLink возвращает неудаленную ссылку со всеми метаданными
+func (s *StoragePool) GetLink(ctx context.Context, domain, alias string) (Link, error) {
+	const op = "postgres.storage.GetLink"
+	link, err := scanLink(s.pool.QueryRow(ctx, `SELECT `+linkColumns+` FROM url WHERE `+byAlias+` AND deleted_at IS NULL`, alias, domain))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Link{}, fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+	if err != nil {
+		return Link{}, fmt.Errorf("%s failed to get url: %w", op, err)
+	}
+	return link, nil
+}
+
+// ListURLs возвращает страницу неудаленных ссылок, новые первыми
+func (s *StoragePool) ListURLs(ctx context.Context, filter Filter, limit, offset int) ([]Link, error) {
+	const op = "postgres.storage.ListURLs"
+	where, args := filter.where()
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`SELECT %s FROM url WHERE deleted_at IS NULL AND %s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		linkColumns, where, len(args)-1, len(args))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to list urls: %w", op, err)
+	}
+	links, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Link, error) {
+		return scanLink(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read urls: %w", op, err)
+	}
+	return links, nil
+}
+
+func (s *StoragePool) DeleteURl(ctx context.Context, domain, alias string) error {
 	const op = "postgres.storage.DeleteURl"
-	res, err := s.pool.Exec(ctx, `DELETE FROM url WHERE alias = $1`, alias)
+	// Ссылка не удаляется физически: алиас остается занятым до очистки
+	res, err := s.pool.Exec(ctx, `UPDATE url SET deleted_at = now() WHERE `+byAlias+` AND deleted_at IS NULL`, alias, domain)
 	if err != nil {
 		return fmt.Errorf("%s failed to delete url: %w", op, err)
 	}
 	if res.RowsAffected() == 0 {
-		return fmt.Errorf("url not found or access denied")
+		return fmt.Errorf("%s: %w", op, ErrURLNotFound)
 	}
 
 	return nil
 }
+
+// DeleteURLs помечает удаленными все ссылки, подходящие под фильтр, одним запросом
+// и возвращает их алиасы. При dryRun ничего не удаляется, возвращаются только алиасы.
+func (s *StoragePool) DeleteURLs(ctx context.Context, filter Filter, dryRun bool) ([]string, error) {
+	const op = "postgres.storage.DeleteURLs"
+	if filter.IsEmpty() {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmptyFilter)
+	}
+
+	where, args := filter.where()
+	query := `UPDATE url SET deleted_at = now() WHERE deleted_at IS NULL AND ` + where + ` RETURNING alias`
+	if dryRun {
+		query = `SELECT alias FROM url WHERE deleted_at IS NULL AND ` + where + ` ORDER BY id`
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to delete urls: %w", op, err)
+	}
+	aliases, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read deleted aliases: %w", op, err)
+	}
+	return aliases, nil
+}
+
+func (s *StoragePool) RestoreURL(ctx context.Context, domain, alias string) error {
+	const op = "postgres.storage.RestoreURL"
+	res, err := s.pool.Exec(ctx, `UPDATE url SET deleted_at = NULL WHERE `+byAlias+` AND deleted_at IS NOT NULL`, alias, domain)
+	// Пока ссылка была удалена, ее url могли сократить заново
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%s: %w", op, ErrURLExists)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed to restore url: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrURLNotFound)
+	}
+
+	return nil
+}
+
+// PurgeDeletedURLs физически удаляет ссылки, удаленные раньше deletedBefore
+func (s *StoragePool) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const op = "postgres.storage.PurgeDeletedURLs"
+	res, err := s.pool.Exec(ctx, `DELETE FROM url WHERE deleted_at IS NOT NULL AND deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to purge deleted urls: %w", op, err)
+	}
+	return res.RowsAffected(), nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"url-shortener/internal/lib/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryTracer создает дочерний спан на каждый запрос и пачку запросов pgx
+type queryTracer struct{}
+
+var (
+	_ pgx.QueryTracer = queryTracer{}
+	_ pgx.BatchTracer = queryTracer{}
+)
+
+// maxStatementLength - сколько символов SQL сохранять в атрибуте спана
+const maxStatementLength = 512
+
+// Спаны хранятся под своими ключами, чтобы End не завершил по ошибке спан обработчика
+type (
+	querySpanKey struct{}
+	batchSpanKey struct{}
+)
+
+type batchSpan struct {
+	span   *tracing.Span
+	failed int
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	// Запросы вне HTTP-запроса (очистка корзины, миграции) не порождают отдельных трейсов
+	if tracing.SpanFromContext(ctx) == nil {
+		return ctx
+	}
+	ctx, span := tracing.Start(ctx, "pgx.query "+operation(data.SQL),
+		tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes(
+			tracing.String("db.system", "postgresql"),
+			tracing.String("db.statement", statement(data.SQL)),
+		),
+	)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(*tracing.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(tracing.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	span.RecordError(data.Err)
+	span.End()
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if tracing.SpanFromContext(ctx) == nil {
+		return ctx
+	}
+	ctx, span := tracing.Start(ctx, "pgx.batch",
+		tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes(
+			tracing.String("db.system", "postgresql"),
+			tracing.Int("db.batch.size", data.Batch.Len()),
+		),
+	)
+	return context.WithValue(ctx, batchSpanKey{}, &batchSpan{span: span})
+}
+
+func (queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if batch, ok := ctx.Value(batchSpanKey{}).(*batchSpan); ok && data.Err != nil {
+		batch.failed++
+	}
+}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	batch, ok := ctx.Value(batchSpanKey{}).(*batchSpan)
+	if !ok {
+		return
+	}
+	batch.span.SetAttributes(tracing.Int("db.batch.failed", batch.failed))
+	batch.span.RecordError(data.Err)
+	batch.span.End()
+}
+
+// operation возвращает первое слово запроса для имени спана: SELECT, INSERT и т.д.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func statement(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxStatementLength {
+		return sql[:maxStatementLength] + "..."
+	}
+	return sql
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUTMTemplateNotFound = errors.New("utm template not found")
+
+// UTM - utm-метки, которые редирект добавляет к целевому URL. Пустые поля не добавляются.
+// У ссылки хранится в колонке utm как JSONB.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Term     string `json:"term,omitempty"`
+}
+
+func (u UTM) IsEmpty() bool {
+	return u == UTM{}
+}
+
+// UTMTemplate - именованный набор utm-меток владельца. При создании ссылки
+// метки из шаблона копируются в ссылку, поэтому изменение шаблона не меняет старые ссылки.
+type UTMTemplate struct {
+	Owner     string
+	Name      string
+	UTM       UTM
+	UpdatedAt time.Time
+}
+
+const utmTemplateColumns = `owner, name, source, medium, campaign, content, term, updated_at`
+
+func scanUTMTemplate(row pgx.Row) (UTMTemplate, error) {
+	var t UTMTemplate
+	err := row.Scan(&t.Owner, &t.Name, &t.UTM.Source, &t.UTM.Medium, &t.UTM.Campaign, &t.UTM.Content, &t.UTM.Term, &t.UpdatedAt)
+	return t, err
+}
+
+// SaveUTMTemplate создает шаблон или заменяет метки существующего
+func (s *StoragePool) SaveUTMTemplate(ctx context.Context, t UTMTemplate) (UTMTemplate, error) {
+	const op = "postgres.storage.SaveUTMTemplate"
+	saved, err := scanUTMTemplate(s.pool.QueryRow(ctx, `
+		INSERT INTO utm_templates (owner, name, source, medium, campaign, content, term)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (owner, name) DO UPDATE
+			SET source = EXCLUDED.source, medium = EXCLUDED.medium, campaign = EXCLUDED.campaign,
+				content = EXCLUDED.content, term = EXCLUDED.term, updated_at = now()
+		RETURNING `+utmTemplateColumns,
+		t.Owner, t.Name, t.UTM.Source, t.UTM.Medium, t.UTM.Campaign, t.UTM.Content, t.UTM.Term))
+	if err != nil {
+		return UTMTemplate{}, fmt.Errorf("%s failed to save utm template: %w", op, err)
+	}
+	return saved, nil
+}
+
+func (s *StoragePool) GetUTMTemplate(ctx context.Context, owner, name string) (UTMTemplate, error) {
+	const op = "postgres.storage.GetUTMTemplate"
+	t, err := scanUTMTemplate(s.pool.QueryRow(ctx,
+		`SELECT `+utmTemplateColumns+` FROM utm_templates WHERE owner = $1 AND name = $2`, owner, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return UTMTemplate{}, fmt.Errorf("%s: %w", op, ErrUTMTemplateNotFound)
+	}
+	if err != nil {
+		return UTMTemplate{}, fmt.Errorf("%s failed to get utm template: %w", op, err)
+	}
+	return t, nil
+}
+
+// ListUTMTemplates возвращает шаблоны владельца по имени
+func (s *StoragePool) ListUTMTemplates(ctx context.Context, owner string) ([]UTMTemplate, error) {
+	const op = "postgres.storage.ListUTMTemplates"
+	rows, err := s.pool.Query(ctx, `SELECT `+utmTemplateColumns+` FROM utm_templates WHERE owner = $1 ORDER BY name`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to list utm templates: %w", op, err)
+	}
+	templates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (UTMTemplate, error) {
+		return scanUTMTemplate(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read utm templates: %w", op, err)
+	}
+	return templates, nil
+}
+
+func (s *StoragePool) DeleteUTMTemplate(ctx context.Context, owner, name string) error {
+	const op = "postgres.storage.DeleteUTMTemplate"
+	res, err := s.pool.Exec(ctx, `DELETE FROM utm_templates WHERE owner = $1 AND name = $2`, owner, name)
+	if err != nil {
+		return fmt.Errorf("%s failed to delete utm template: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUTMTemplateNotFound)
+	}
+	return nil
+}
@@ -2,44 +2,230 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"iter"
+	"time"
 	"url-shortener/internal/storage/postgres"
 )
 
 type Storage struct {
 	Postgres postgres.PostgresStorageInterface
+	Timeouts Timeouts
 }
 
-func NewStorage(pool *postgres.StoragePool) *Storage {
+// Timeouts - предельное время одного обращения к БД по типу операции. Ноль - без ограничения.
+// Импорт и экспорт идут потоком и ограничены только контекстом запроса.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Batch time.Duration
+}
+
+func NewStorage(pool *postgres.StoragePool, timeouts Timeouts) *Storage {
 	return &Storage{
 		Postgres: pool,
+		Timeouts: timeouts,
 	}
 }
 
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 var (
-	ErrURLNotFound = errors.New("url not found")
-	ErrURLExists   = errors.New("url exists")
+	ErrURLNotFound  = postgres.ErrURLNotFound
+	ErrURLExists    = postgres.ErrURLExists
+	ErrBatchAborted = postgres.ErrBatchAborted
+	ErrEmptyFilter  = postgres.ErrEmptyFilter
+
+	ErrUTMTemplateNotFound = postgres.ErrUTMTemplateNotFound
+	ErrRuleNotFound        = postgres.ErrRuleNotFound
+	ErrTooManyRules        = postgres.ErrTooManyRules
+	ErrClicksExhausted     = postgres.ErrClicksExhausted
+	ErrDomainNotFound      = postgres.ErrDomainNotFound
+	ErrDomainExists        = postgres.ErrDomainExists
+	ErrDomainInUse         = postgres.ErrDomainInUse
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=StorageInterface
 type StorageInterface interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error)
-	GetURL(ctx context.Context, alias string) (string, error)
-	DeleteURl(ctx context.Context, alias string) error
-	URLExists(ctx context.Context, url string) (bool, error)
+	SaveURL(ctx context.Context, link postgres.Link) (int64, error)
+	SaveURLs(ctx context.Context, links []postgres.Link, atomic bool) ([]postgres.SaveResult, error)
+	ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error)
+	ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error]
+	GetURL(ctx context.Context, domain, alias string) (string, error)
+	GetLink(ctx context.Context, domain, alias string) (postgres.Link, error)
+	ListURLs(ctx context.Context, filter postgres.Filter, limit, offset int) ([]postgres.Link, error)
+	DeleteURl(ctx context.Context, domain, alias string) error
+	DeleteURLs(ctx context.Context, filter postgres.Filter, dryRun bool) ([]string, error)
+	RestoreURL(ctx context.Context, domain, alias string) error
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+	URLExists(ctx context.Context, url, domain string) (bool, error)
+	SaveUTMTemplate(ctx context.Context, t postgres.UTMTemplate) (postgres.UTMTemplate, error)
+	GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error)
+	ListUTMTemplates(ctx context.Context, owner string) ([]postgres.UTMTemplate, error)
+	DeleteUTMTemplate(ctx context.Context, owner, name string) error
+	SetRedirectRules(ctx context.Context, domain, alias string, rules []postgres.RedirectRule) ([]postgres.RedirectRule, error)
+	AddRedirectRule(ctx context.Context, domain, alias string, rule postgres.RedirectRule, limit int) ([]postgres.RedirectRule, error)
+	DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]postgres.RedirectRule, error)
+	SetSplit(ctx context.Context, domain, alias string, split *postgres.Split) error
+	RecordClicks(ctx context.Context, clicks []postgres.Click) error
+	ClickStats(ctx context.Context, domain, alias string) ([]postgres.VariantClicks, error)
+	ConsumeClick(ctx context.Context, id int64) (int, error)
+	SaveDomain(ctx context.Context, host string) (postgres.Domain, error)
+	ListDomains(ctx context.Context) ([]postgres.Domain, error)
+	DeleteDomain(ctx context.Context, host string) error
+}
+
+func (s *Storage) URLExists(ctx context.Context, url, domain string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.URLExists(ctx, url, domain)
+}
+func (s *Storage) SaveURL(ctx context.Context, link postgres.Link) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.SaveURL(ctx, link)
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, links []postgres.Link, atomic bool) ([]postgres.SaveResult, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Batch)
+	defer cancel()
+	return s.Postgres.SaveURLs(ctx, links, atomic)
+}
+
+func (s *Storage) ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error) {
+	return s.Postgres.ImportURLs(ctx, rows, policy)
+}
+
+func (s *Storage) ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error] {
+	return s.Postgres.ExportURLs(ctx, filter)
+}
+
+func (s *Storage) GetURL(ctx context.Context, domain, alias string) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.GetURL(ctx, domain, alias)
+}
+
+func (s *Storage) GetLink(ctx context.Context, domain, alias string) (postgres.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.GetLink(ctx, domain, alias)
+}
+
+func (s *Storage) ListURLs(ctx context.Context, filter postgres.Filter, limit, offset int) ([]postgres.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.ListURLs(ctx, filter, limit, offset)
+}
+
+func (s *Storage) DeleteURl(ctx context.Context, domain, alias string) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.DeleteURl(ctx, domain, alias)
+}
+
+func (s *Storage) DeleteURLs(ctx context.Context, filter postgres.Filter, dryRun bool) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Batch)
+	defer cancel()
+	return s.Postgres.DeleteURLs(ctx, filter, dryRun)
 }
 
-func (s *Storage) URLExists(ctx context.Context, url string) (bool, error) {
-	return s.Postgres.URLExists(ctx, url)
+func (s *Storage) RestoreURL(ctx context.Context, domain, alias string) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.RestoreURL(ctx, domain, alias)
 }
-func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
-	return s.Postgres.SaveURL(ctx, urlToSave, alias)
+
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Batch)
+	defer cancel()
+	return s.Postgres.PurgeDeletedURLs(ctx, deletedBefore)
+}
+
+func (s *Storage) SaveUTMTemplate(ctx context.Context, t postgres.UTMTemplate) (postgres.UTMTemplate, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.SaveUTMTemplate(ctx, t)
+}
+
+func (s *Storage) GetUTMTemplate(ctx context.Context, owner, name string) (postgres.UTMTemplate, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.GetUTMTemplate(ctx, owner, name)
+}
+
+func (s *Storage) ListUTMTemplates(ctx context.Context, owner string) ([]postgres.UTMTemplate, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.ListUTMTemplates(ctx, owner)
+}
+
+func (s *Storage) DeleteUTMTemplate(ctx context.Context, owner, name string) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.DeleteUTMTemplate(ctx, owner, name)
+}
+
+func (s *Storage) SetRedirectRules(ctx context.Context, domain, alias string, rules []postgres.RedirectRule) ([]postgres.RedirectRule, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.SetRedirectRules(ctx, domain, alias, rules)
+}
+
+func (s *Storage) AddRedirectRule(ctx context.Context, domain, alias string, rule postgres.RedirectRule, limit int) ([]postgres.RedirectRule, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.AddRedirectRule(ctx, domain, alias, rule, limit)
+}
+
+func (s *Storage) DeleteRedirectRule(ctx context.Context, domain, alias string, index int) ([]postgres.RedirectRule, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.DeleteRedirectRule(ctx, domain, alias, index)
+}
+
+func (s *Storage) SetSplit(ctx context.Context, domain, alias string, split *postgres.Split) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.SetSplit(ctx, domain, alias, split)
+}
+
+func (s *Storage) RecordClicks(ctx context.Context, clicks []postgres.Click) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Batch)
+	defer cancel()
+	return s.Postgres.RecordClicks(ctx, clicks)
+}
+
+func (s *Storage) ClickStats(ctx context.Context, domain, alias string) ([]postgres.VariantClicks, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.ClickStats(ctx, domain, alias)
+}
+
+func (s *Storage) ConsumeClick(ctx context.Context, id int64) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.ConsumeClick(ctx, id)
+}
+
+func (s *Storage) SaveDomain(ctx context.Context, host string) (postgres.Domain, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.SaveDomain(ctx, host)
 }
 
-func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
-	return s.Postgres.GetURL(ctx, alias)
+func (s *Storage) ListDomains(ctx context.Context) ([]postgres.Domain, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Read)
+	defer cancel()
+	return s.Postgres.ListDomains(ctx)
 }
 
-func (s *Storage) DeleteURl(ctx context.Context, alias string) error {
-	return s.Postgres.DeleteURl(ctx, alias)
+func (s *Storage) DeleteDomain(ctx context.Context, host string) error {
+	ctx, cancel := withTimeout(ctx, s.Timeouts.Write)
+	defer cancel()
+	return s.Postgres.DeleteDomain(ctx, host)
 }
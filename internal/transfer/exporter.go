@@ -0,0 +1,224 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/storage/postgres"
+)
+
+// flushEvery - через сколько строк буфер сбрасывается в поток
+const flushEvery = 500
+
+// csvColumns - новые колонки добавляются в конец, чтобы не сдвигать старые
+var csvColumns = []string{"id", "alias", "url", "owner", "tags", "created_at", "deleted_at",
+	"domain", "password_hash", "max_clicks", "clicks_used", "active_from", "expires_at",
+	"redirect_type", "passthrough", "utm", "rules", "split", "title", "interstitial", "clicks", "variant_clicks"}
+
+// ExportRow - строка экспорта со всеми полями ссылки. Совместима с Row, поэтому выгрузку
+// можно импортировать обратно. Clicks и VariantClicks - статистика переходов для отчетов,
+// при импорте она не восстанавливается. В CSV utm, rules, split и variant_clicks записываются как JSON.
+type ExportRow struct {
+	ID           int64                   `json:"id"`
+	Alias        string                  `json:"alias"`
+	URL          string                  `json:"url"`
+	Domain       string                  `json:"domain,omitempty"`
+	Owner        string                  `json:"owner,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`
+	RedirectType int                     `json:"redirect_type,omitempty"`
+	Passthrough  string                  `json:"passthrough,omitempty"`
+	UTM          *postgres.UTM           `json:"utm,omitempty"`
+	Rules        []postgres.RedirectRule `json:"rules,omitempty"`
+	Split        *postgres.Split         `json:"split,omitempty"`
+	// PasswordHash - bcrypt-хеш пароля ссылки, сам пароль не хранится
+	PasswordHash string     `json:"password_hash,omitempty"`
+	MaxClicks    int        `json:"max_clicks,omitempty"`
+	ClicksUsed   int        `json:"clicks_used,omitempty"`
+	ActiveFrom   *time.Time `json:"active_from,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	// Clicks - все переходы по ссылке, VariantClicks - переходы по вариантам A/B-теста
+	Clicks        int64                    `json:"clicks"`
+	VariantClicks []postgres.VariantClicks `json:"variant_clicks,omitempty"`
+}
+
+type URLExporter interface {
+	ExportURLs(ctx context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error]
+}
+
+// Flusher сбрасывает записанные данные клиенту, например http.ResponseController
+type Flusher interface {
+	Flush() error
+}
+
+// Export пишет ссылки, подходящие под фильтр, в w по мере чтения из хранилища
+// и возвращает число выгруженных строк. flusher может быть nil.
+func Export(ctx context.Context, exporter URLExporter, w io.Writer, format Format, filter postgres.Filter, flusher Flusher) (int, error) {
+	var write func(ExportRow) error
+	var flush func() error
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return 0, err
+		}
+		write = func(row ExportRow) error {
+			utm, err := formatJSON(row.UTM, row.UTM != nil)
+			if err != nil {
+				return err
+			}
+			rules, err := formatJSON(row.Rules, len(row.Rules) > 0)
+			if err != nil {
+				return err
+			}
+			split, err := formatJSON(row.Split, row.Split != nil)
+			if err != nil {
+				return err
+			}
+			variantClicks, err := formatJSON(row.VariantClicks, len(row.VariantClicks) > 0)
+			if err != nil {
+				return err
+			}
+			return cw.Write([]string{
+				strconv.FormatInt(row.ID, 10),
+				row.Alias,
+				row.URL,
+				row.Owner,
+				strings.Join(row.Tags, tagsSeparator),
+				row.CreatedAt.Format(time.RFC3339),
+				formatTime(row.DeletedAt),
+				row.Domain,
+				row.PasswordHash,
+				formatCount(row.MaxClicks),
+				formatCount(row.ClicksUsed),
+				formatTime(row.ActiveFrom),
+				formatTime(row.ExpiresAt),
+				formatCount(row.RedirectType),
+				row.Passthrough,
+				utm,
+				rules,
+				split,
+				row.Title,
+				formatBool(row.Interstitial),
+				strconv.FormatInt(row.Clicks, 10),
+				variantClicks,
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		write = func(row ExportRow) error {
+			return enc.Encode(row)
+		}
+		flush = func() error { return nil }
+	default:
+		return 0, ErrUnknownFormat
+	}
+
+	flushAll := func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			return flusher.Flush()
+		}
+		return nil
+	}
+
+	count := 0
+	for link, err := range exporter.ExportURLs(ctx, filter) {
+		if err != nil {
+			return count, err
+		}
+		if err := write(exportRow(link)); err != nil {
+			return count, err
+		}
+		count++
+		if count%flushEvery == 0 {
+			if err := flushAll(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, flushAll()
+}
+
+func exportRow(link postgres.ExportedLink) ExportRow {
+	row := ExportRow{
+		ID:           link.ID,
+		Alias:        link.Alias,
+		URL:          link.URL,
+		Domain:       link.Domain,
+		Owner:        link.Owner,
+		Tags:         link.Tags,
+		RedirectType: link.RedirectType,
+		Passthrough:  link.Passthrough,
+		Rules:        link.Rules,
+		Split:        link.Split,
+		PasswordHash: link.PasswordHash,
+		MaxClicks:    link.MaxClicks,
+		ClicksUsed:   link.ClicksUsed,
+		ActiveFrom:   link.ActiveFrom,
+		ExpiresAt:    link.ExpiresAt,
+		Title:        link.Title,
+		Interstitial: link.Interstitial,
+		CreatedAt:    link.CreatedAt.UTC(),
+		DeletedAt:    link.DeletedAt,
+	}
+	if !link.UTM.IsEmpty() {
+		row.UTM = &link.UTM
+	}
+	for _, v := range link.Clicks {
+		row.Clicks += v.Clicks
+		// Переходы без A/B-теста не относятся ни к одному варианту
+		if v.Variant != "" {
+			row.VariantClicks = append(row.VariantClicks, v)
+		}
+	}
+	return row
+}
+
+// formatTime - пустая ячейка CSV для отсутствующего времени
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatJSON - пустая ячейка CSV, если значения нет
+func formatJSON(v any, present bool) (string, error) {
+	if !present {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// formatBool - пустая ячейка CSV для false
+func formatBool(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+// formatCount - пустая ячейка CSV для нуля
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,169 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+)
+
+// testHash - bcrypt-хеш пароля "s3cret"
+const testHash = "$2a$04$aED68bHuzegkrj6wtUyX6uC.HuzlDw.WJU.gMS2Ne7B8cCTOv9qV2"
+
+type fakeExporter struct {
+	links  []postgres.ExportedLink
+	err    error
+	filter postgres.Filter
+}
+
+func (f *fakeExporter) ExportURLs(_ context.Context, filter postgres.Filter) iter.Seq2[postgres.ExportedLink, error] {
+	f.filter = filter
+	return func(yield func(postgres.ExportedLink, error) bool) {
+		for _, link := range f.links {
+			if !yield(link, nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(postgres.ExportedLink{}, f.err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	activeFrom := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	links := []postgres.ExportedLink{
+		{Link: postgres.Link{ID: 1, Alias: "a", URL: "https://a.com/?q=1,2", Owner: "marketing", Tags: []string{"x", "y"}, CreatedAt: created}},
+		{
+			Link:   postgres.Link{ID: 2, Alias: "b", URL: "https://b.com", CreatedAt: created, DeletedAt: &deleted},
+			Clicks: []postgres.VariantClicks{{Clicks: 7}},
+		},
+		{Link: postgres.Link{
+			ID: 3, Alias: "c", URL: "https://c.com", Domain: "go.brand-a.com", PasswordHash: testHash,
+			MaxClicks: 5, ClicksUsed: 2, ActiveFrom: &activeFrom, ExpiresAt: &expiresAt, CreatedAt: created,
+		}},
+		{
+			Link: postgres.Link{
+				ID: 4, Alias: "d", URL: "https://d.com", RedirectType: 307, Passthrough: "append", UTM: postgres.UTM{Source: "mail"},
+				Rules: []postgres.RedirectRule{{Device: "ios", Target: "https://apps.apple.com"}},
+				Split: &postgres.Split{Variants: []postgres.Variant{{Name: "x", URL: "https://x.com", Weight: 1}}},
+				Title: "Docs", Interstitial: true, CreatedAt: created,
+			},
+			// Переходы до запуска A/B-теста учитываются только в общем числе
+			Clicks: []postgres.VariantClicks{{Clicks: 1}, {Variant: "x", Clicks: 3}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		format   transfer.Format
+		expected string
+	}{
+		{
+			name:   "CSV",
+			format: transfer.FormatCSV,
+			expected: "id,alias,url,owner,tags,created_at,deleted_at,domain,password_hash,max_clicks,clicks_used,active_from,expires_at," +
+				"redirect_type,passthrough,utm,rules,split,title,interstitial,clicks,variant_clicks\n" +
+				"1,a,\"https://a.com/?q=1,2\",marketing,x|y,2025-03-01T12:00:00Z,,,,,,,,,,,,,,,0,\n" +
+				"2,b,https://b.com,,,2025-03-01T12:00:00Z,2025-04-01T12:00:00Z,,,,,,,,,,,,,,7,\n" +
+				"3,c,https://c.com,,,2025-03-01T12:00:00Z,,go.brand-a.com," + testHash + ",5,2,2025-03-02T00:00:00Z,2025-05-01T00:00:00Z,,,,,,,,0,\n" +
+				`4,d,https://d.com,,,2025-03-01T12:00:00Z,,,,,,,,307,append,"{""source"":""mail""}",` +
+				`"[{""device"":""ios"",""target"":""https://apps.apple.com""}]","{""variants"":[{""name"":""x"",""url"":""https://x.com"",""weight"":1}]}",` +
+				`Docs,true,4,"[{""variant"":""x"",""clicks"":3}]"` + "\n",
+		},
+		{
+			name:   "JSONL",
+			format: transfer.FormatJSONL,
+			expected: `{"id":1,"alias":"a","url":"https://a.com/?q=1,2","owner":"marketing","tags":["x","y"],"created_at":"2025-03-01T12:00:00Z","clicks":0}` + "\n" +
+				`{"id":2,"alias":"b","url":"https://b.com","created_at":"2025-03-01T12:00:00Z","deleted_at":"2025-04-01T12:00:00Z","clicks":7}` + "\n" +
+				`{"id":3,"alias":"c","url":"https://c.com","domain":"go.brand-a.com","password_hash":"` + testHash + `","max_clicks":5,"clicks_used":2,` +
+				`"active_from":"2025-03-02T00:00:00Z","expires_at":"2025-05-01T00:00:00Z","created_at":"2025-03-01T12:00:00Z","clicks":0}` + "\n" +
+				`{"id":4,"alias":"d","url":"https://d.com","redirect_type":307,"passthrough":"append","utm":{"source":"mail"},` +
+				`"rules":[{"device":"ios","target":"https://apps.apple.com"}],"split":{"variants":[{"name":"x","url":"https://x.com","weight":1}]},` +
+				`"title":"Docs","interstitial":true,"created_at":"2025-03-01T12:00:00Z","clicks":4,"variant_clicks":[{"variant":"x","clicks":3}]}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &fakeExporter{links: links}
+			filter := postgres.Filter{Owner: "marketing"}
+			buf := &bytes.Buffer{}
+
+			count, err := transfer.Export(context.Background(), exporter, buf, tt.format, filter, nil)
+			require.NoError(t, err)
+			require.Equal(t, 4, count)
+			require.Equal(t, tt.expected, buf.String())
+			require.Equal(t, filter, exporter.filter)
+		})
+	}
+}
+
+func TestExportStorageError(t *testing.T) {
+	dbErr := errors.New("cursor closed")
+	exporter := &fakeExporter{links: []postgres.ExportedLink{{Link: postgres.Link{ID: 1, Alias: "a"}}}, err: dbErr}
+
+	count, err := transfer.Export(context.Background(), exporter, &bytes.Buffer{}, transfer.FormatJSONL, postgres.Filter{}, nil)
+	require.ErrorIs(t, err, dbErr)
+	require.Equal(t, 1, count)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	created := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	deleted := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	// Истекшая ссылка тоже должна вернуться при импорте
+	activeFrom := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	links := []postgres.Link{
+		{URL: "https://a.com", Alias: "a", Owner: "o", Tags: []string{"t"}, CreatedAt: created},
+		{
+			URL: "https://b.com", Alias: "b", Domain: "go.brand-a.com", PasswordHash: testHash,
+			MaxClicks: 5, ClicksUsed: 2, ActiveFrom: &activeFrom, ExpiresAt: &expiresAt, CreatedAt: created,
+		},
+		{
+			URL: "https://c.com", Alias: "c", RedirectType: 308, Passthrough: "keep", UTM: postgres.UTM{Source: "mail", Campaign: "spring"},
+			Rules: []postgres.RedirectRule{
+				{Device: "android", Target: "https://play.google.com"},
+				{Languages: []string{"de"}, Countries: []string{"DE", "AT"}, Target: "https://c.de"},
+			},
+			Split: &postgres.Split{Sticky: postgres.StickyCookie, Variants: []postgres.Variant{
+				{Name: "old", URL: "https://c.com/old", Weight: 1},
+				{Name: "new", URL: "https://c.com/new", Weight: 3},
+			}},
+			Title: "Promo", Interstitial: true, CreatedAt: created, DeletedAt: &deleted,
+		},
+	}
+	exported := make([]postgres.ExportedLink, len(links))
+	for i, link := range links {
+		link.ID = int64(i + 1)
+		exported[i] = postgres.ExportedLink{Link: link, Clicks: []postgres.VariantClicks{{Variant: "new", Clicks: 2}}}
+	}
+
+	for _, format := range []transfer.Format{transfer.FormatCSV, transfer.FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			_, err := transfer.Export(context.Background(), &fakeExporter{links: exported}, buf, format, postgres.Filter{}, nil)
+			require.NoError(t, err)
+
+			importer := &fakeImporter{}
+			report, err := transfer.Import(context.Background(), importer, buf, format, postgres.ConflictSkip)
+			require.NoError(t, err)
+			require.Equal(t, len(links), report.Imported)
+			require.Empty(t, report.Rejected)
+			require.Len(t, importer.rows, len(links))
+			for i, row := range importer.rows {
+				require.Equal(t, links[i], row.Link)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/links"
+	"url-shortener/internal/storage/postgres"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+var ErrUnknownFormat = errors.New("unknown format, expected csv or jsonl")
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSONL:
+		return f, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+// ParseTime разбирает границу периода в формате RFC 3339 или 2006-01-02
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// tagsSeparator разделяет теги в одной CSV-колонке
+const tagsSeparator = "|"
+
+// maxLineSize ограничивает длину одной строки JSONL
+const maxLineSize = 1 << 20
+
+// Row - строка импорта. Поля совпадают с links.Request, но алиас обязателен,
+// а пароль, счетчик переходов, правила редиректа и даты создания и удаления
+// переносятся из выгрузки как есть.
+type Row struct {
+	Alias        string                  `json:"alias"`
+	URL          string                  `json:"url"`
+	Domain       string                  `json:"domain,omitempty"`
+	Owner        string                  `json:"owner,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`
+	RedirectType int                     `json:"redirect_type,omitempty"`
+	Passthrough  string                  `json:"passthrough,omitempty"`
+	UTM          *postgres.UTM           `json:"utm,omitempty"`
+	Rules        []postgres.RedirectRule `json:"rules,omitempty"`
+	Split        *postgres.Split         `json:"split,omitempty"`
+	PasswordHash string                  `json:"password_hash,omitempty"`
+	MaxClicks    int                     `json:"max_clicks,omitempty"`
+	ClicksUsed   int                     `json:"clicks_used,omitempty"`
+	ActiveFrom   *time.Time              `json:"active_from,omitempty"`
+	ExpiresAt    *time.Time              `json:"expires_at,omitempty"`
+	Title        string                  `json:"title,omitempty"`
+	Interstitial bool                    `json:"interstitial,omitempty"`
+	// CreatedAt без значения - время импорта. DeletedAt переносит удаленную ссылку удаленной.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Conflict struct {
+	Line  int    `json:"line"`
+	Alias string `json:"alias"`
+}
+
+type Rejection struct {
+	Line   int    `json:"line"`
+	Alias  string `json:"alias,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type Report struct {
+	Imported    int         `json:"imported"`
+	Overwritten int         `json:"overwritten"`
+	Skipped     int         `json:"skipped"`
+	Conflicts   []Conflict  `json:"conflicts,omitempty"`
+	Rejected    []Rejection `json:"rejected,omitempty"`
+}
+
+type URLImporter interface {
+	ImportURLs(ctx context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error)
+}
+
+// Import читает строки из r, проверяет их по тем же правилам, что и API (links.Request),
+// и потоково передает корректные строки в хранилище. Некорректные строки
+// попадают в отчет и не прерывают импорт.
+func Import(ctx context.Context, importer URLImporter, r io.Reader, format Format, policy postgres.ConflictPolicy) (Report, error) {
+	var report Report
+
+	var source iter.Seq2[parsedRow, error]
+	switch format {
+	case FormatCSV:
+		source = readCSV(r)
+	case FormatJSONL:
+		source = readJSONL(r)
+	default:
+		return report, ErrUnknownFormat
+	}
+
+	validate := validator.New()
+	rows := func(yield func(postgres.ImportRow, error) bool) {
+		for parsed, err := range source {
+			if err != nil {
+				yield(postgres.ImportRow{}, err)
+				return
+			}
+			if parsed.err != nil {
+				report.Rejected = append(report.Rejected, Rejection{Line: parsed.line, Alias: parsed.row.Alias, Reason: parsed.err.Error()})
+				continue
+			}
+			if reason := validateRow(validate, parsed.row); reason != "" {
+				report.Rejected = append(report.Rejected, Rejection{Line: parsed.line, Alias: parsed.row.Alias, Reason: reason})
+				continue
+			}
+			if !yield(postgres.ImportRow{Line: parsed.line, Link: parsed.row.link()}, nil) {
+				return
+			}
+		}
+	}
+
+	stats, err := importer.ImportURLs(ctx, rows, policy)
+	for _, c := range stats.Conflicts {
+		report.Conflicts = append(report.Conflicts, Conflict{Line: c.Line, Alias: c.Link.Alias})
+	}
+	for _, u := range stats.UnknownDomains {
+		report.Rejected = append(report.Rejected, Rejection{Line: u.Line, Alias: u.Link.Alias, Reason: "unknown domain"})
+	}
+	if err != nil {
+		// Импорт идет одной транзакцией: при ошибке ничего не сохранено
+		return report, err
+	}
+	report.Imported = stats.Created
+	report.Overwritten = stats.Overwritten
+	report.Skipped = stats.Skipped
+	return report, nil
+}
+
+func (row Row) request() links.Request {
+	return links.Request{
+		URL:          row.URL,
+		Alias:        row.Alias,
+		Domain:       row.Domain,
+		Owner:        row.Owner,
+		Tags:         row.Tags,
+		RedirectType: row.RedirectType,
+		Passthrough:  row.Passthrough,
+		UTM:          row.UTM,
+		Split:        row.Split,
+		MaxClicks:    row.MaxClicks,
+		ActiveFrom:   row.ActiveFrom,
+		ExpiresAt:    row.ExpiresAt,
+		Title:        row.Title,
+		Interstitial: row.Interstitial,
+	}
+}
+
+func (row Row) link() postgres.Link {
+	link := row.request().Link(row.Alias)
+	link.Rules = row.Rules
+	link.PasswordHash = row.PasswordHash
+	link.ClicksUsed = row.ClicksUsed
+	if row.CreatedAt != nil {
+		link.CreatedAt = *row.CreatedAt
+	}
+	link.DeletedAt = row.DeletedAt
+	return link
+}
+
+func validateRow(validate *validator.Validate, row Row) string {
+	if row.Alias == "" {
+		return "field Alias is a required field"
+	}
+	if err := validate.Struct(row.request()); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			return response.ValidationError(errs).Error
+		}
+		return err.Error()
+	}
+	if err := links.ValidateSplit(row.Split); err != nil {
+		return err.Error()
+	}
+	if err := links.ValidateRules(row.Rules); err != nil {
+		return err.Error()
+	}
+	if row.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(row.PasswordHash)); err != nil {
+			return "password_hash is not a bcrypt hash"
+		}
+	}
+	if row.ClicksUsed < 0 || (row.MaxClicks > 0 && row.ClicksUsed > row.MaxClicks) {
+		return "clicks_used must be between 0 and max_clicks"
+	}
+	// Истекшие ссылки из выгрузки импортируются, поэтому проверяется только порядок границ
+	if row.ActiveFrom != nil && row.ExpiresAt != nil && !row.ExpiresAt.After(*row.ActiveFrom) {
+		return links.ErrExpiresBeforeActive.Error()
+	}
+	return ""
+}
+
+// parsedRow - разобранная строка источника. err заполняется, если строку
+// не удалось разобрать, но чтение можно продолжать.
+type parsedRow struct {
+	line int
+	row  Row
+	err  error
+}
+
+// readCSV читает CSV с колонками alias,url,owner,tags. Если первая строка
+// содержит заголовок с колонками alias и url, порядок колонок берется из него,
+// и можно передать остальные поля Row. utm, rules и split записываются как JSON.
+func readCSV(r io.Reader) iter.Seq2[parsedRow, error] {
+	return func(yield func(parsedRow, error) bool) {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+
+		columns := map[string]int{"alias": 0, "url": 1, "owner": 2, "tags": 3}
+		first := true
+		for {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if !yield(parsedRow{line: parseErr.Line, err: parseErr.Err}, nil) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(parsedRow{}, err)
+				return
+			}
+
+			line, _ := cr.FieldPos(0)
+			if first {
+				first = false
+				if header, ok := csvHeader(record); ok {
+					columns = header
+					continue
+				}
+			}
+
+			field := func(name string) string {
+				i, ok := columns[name]
+				if !ok || i >= len(record) {
+					return ""
+				}
+				return strings.TrimSpace(record[i])
+			}
+			row, err := csvRow(field)
+			if !yield(parsedRow{line: line, row: row, err: err}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// csvRow собирает строку из колонок CSV. Ошибка - значение колонки не разобрано.
+func csvRow(field func(name string) string) (Row, error) {
+	row := Row{
+		Alias:        field("alias"),
+		URL:          field("url"),
+		Domain:       field("domain"),
+		Owner:        field("owner"),
+		Passthrough:  field("passthrough"),
+		PasswordHash: field("password_hash"),
+		Title:        field("title"),
+	}
+	if tags := field("tags"); tags != "" {
+		row.Tags = strings.Split(tags, tagsSeparator)
+	}
+	var err error
+	if row.RedirectType, err = csvInt(field("redirect_type")); err != nil {
+		return row, fmt.Errorf("invalid redirect_type %q", field("redirect_type"))
+	}
+	if err = csvJSON(field("utm"), &row.UTM); err != nil {
+		return row, fmt.Errorf("invalid utm: %w", err)
+	}
+	if err = csvJSON(field("rules"), &row.Rules); err != nil {
+		return row, fmt.Errorf("invalid rules: %w", err)
+	}
+	if err = csvJSON(field("split"), &row.Split); err != nil {
+		return row, fmt.Errorf("invalid split: %w", err)
+	}
+	if v := field("interstitial"); v != "" {
+		if row.Interstitial, err = strconv.ParseBool(v); err != nil {
+			return row, fmt.Errorf("invalid interstitial %q", v)
+		}
+	}
+	if row.MaxClicks, err = csvInt(field("max_clicks")); err != nil {
+		return row, fmt.Errorf("invalid max_clicks %q", field("max_clicks"))
+	}
+	if row.ClicksUsed, err = csvInt(field("clicks_used")); err != nil {
+		return row, fmt.Errorf("invalid clicks_used %q", field("clicks_used"))
+	}
+	if row.ActiveFrom, err = csvTime(field("active_from")); err != nil {
+		return row, fmt.Errorf("invalid active_from %q, expected RFC 3339", field("active_from"))
+	}
+	if row.ExpiresAt, err = csvTime(field("expires_at")); err != nil {
+		return row, fmt.Errorf("invalid expires_at %q, expected RFC 3339", field("expires_at"))
+	}
+	if row.CreatedAt, err = csvTime(field("created_at")); err != nil {
+		return row, fmt.Errorf("invalid created_at %q, expected RFC 3339", field("created_at"))
+	}
+	if row.DeletedAt, err = csvTime(field("deleted_at")); err != nil {
+		return row, fmt.Errorf("invalid deleted_at %q, expected RFC 3339", field("deleted_at"))
+	}
+	return row, nil
+}
+
+// csvInt, csvTime и csvJSON читают необязательные колонки: пустая ячейка - нулевое значение
+func csvInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func csvTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func csvJSON(v string, dst any) error {
+	if v == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(v), dst)
+}
+
+func csvHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int, len(record))
+	for i, name := range record {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	_, hasAlias := columns["alias"]
+	_, hasURL := columns["url"]
+	return columns, hasAlias && hasURL
+}
+
+func readJSONL(r io.Reader) iter.Seq2[parsedRow, error] {
+	return func(yield func(parsedRow, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var row Row
+			if err := json.Unmarshal([]byte(text), &row); err != nil {
+				if !yield(parsedRow{line: line, err: fmt.Errorf("invalid json: %w", err)}, nil) {
+					return
+				}
+				continue
+			}
+			if !yield(parsedRow{line: line, row: row}, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(parsedRow{}, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package transfer_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/transfer"
+)
+
+// fakeImporter собирает переданные строки, считает конфликтом уже занятые алиасы
+// и не знает домен go.unknown.com
+type fakeImporter struct {
+	existing map[string]bool
+	rows     []postgres.ImportRow
+}
+
+func (f *fakeImporter) ImportURLs(_ context.Context, rows iter.Seq2[postgres.ImportRow, error], policy postgres.ConflictPolicy) (postgres.ImportStats, error) {
+	var stats postgres.ImportStats
+	for row, err := range rows {
+		if err != nil {
+			return stats, err
+		}
+		f.rows = append(f.rows, row)
+		if row.Link.Domain == "go.unknown.com" {
+			stats.UnknownDomains = append(stats.UnknownDomains, row)
+			continue
+		}
+		if !f.existing[row.Link.Alias] {
+			stats.Created++
+			continue
+		}
+		stats.Conflicts = append(stats.Conflicts, row)
+		switch policy {
+		case postgres.ConflictFail:
+			return stats, postgres.ErrURLExists
+		case postgres.ConflictOverwrite:
+			stats.Overwritten++
+		default:
+			stats.Skipped++
+		}
+	}
+	return stats, nil
+}
+
+func TestImport(t *testing.T) {
+	activeFrom := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2099, 2, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		format         transfer.Format
+		input          string
+		policy         postgres.ConflictPolicy
+		expectedErr    error
+		expectedLinks  []postgres.Link
+		expectedReport transfer.Report
+	}{
+		{
+			name:   "CSV with header and rejected rows",
+			format: transfer.FormatCSV,
+			input: "url,alias,tags,owner\n" +
+				"https://a.com,a,x|y,marketing\n" +
+				"not-a-url,b,,\n" +
+				"https://c.com,,,\n" +
+				"https://d.com,d,,\n",
+			policy: postgres.ConflictSkip,
+			expectedLinks: []postgres.Link{
+				{URL: "https://a.com", Alias: "a", Owner: "marketing", Tags: []string{"x", "y"}},
+				{URL: "https://d.com", Alias: "d"},
+			},
+			expectedReport: transfer.Report{
+				Imported: 2,
+				Rejected: []transfer.Rejection{
+					{Line: 3, Alias: "b", Reason: "field URL is not a valid URL"},
+					{Line: 4, Reason: "field Alias is a required field"},
+				},
+			},
+		},
+		{
+			name:   "CSV without header",
+			format: transfer.FormatCSV,
+			input:  "a,https://a.com\nexisting,https://e.com\n",
+			policy: postgres.ConflictSkip,
+			expectedLinks: []postgres.Link{
+				{URL: "https://a.com", Alias: "a"},
+				{URL: "https://e.com", Alias: "existing"},
+			},
+			expectedReport: transfer.Report{
+				Imported:  1,
+				Skipped:   1,
+				Conflicts: []transfer.Conflict{{Line: 2, Alias: "existing"}},
+			},
+		},
+		{
+			name:   "JSONL with broken line",
+			format: transfer.FormatJSONL,
+			input: `{"alias": "a", "url": "https://a.com", "tags": ["t"]}` + "\n" +
+				`{"alias": "b", "url": ` + "\n" +
+				"\n" +
+				`{"alias": "existing", "url": "https://e.com"}` + "\n",
+			policy: postgres.ConflictOverwrite,
+			expectedLinks: []postgres.Link{
+				{URL: "https://a.com", Alias: "a", Tags: []string{"t"}},
+				{URL: "https://e.com", Alias: "existing"},
+			},
+			expectedReport: transfer.Report{
+				Imported:    1,
+				Overwritten: 1,
+				Conflicts:   []transfer.Conflict{{Line: 4, Alias: "existing"}},
+				Rejected: []transfer.Rejection{
+					{Line: 2, Reason: "invalid json: unexpected end of JSON input"},
+				},
+			},
+		},
+		{
+			name:   "CSV with domain and protection columns",
+			format: transfer.FormatCSV,
+			input: "alias,url,domain,password_hash,max_clicks,clicks_used,active_from,expires_at\n" +
+				"a,https://a.com,go.brand-a.com," + testHash + ",5,2,2099-01-01T00:00:00Z,2099-02-01T00:00:00Z\n" +
+				"b,https://b.com,go.unknown.com,,,,,\n" +
+				"c,https://c.com,,plain-password,,,,\n" +
+				"d,https://d.com,,,1,2,,\n" +
+				"e,https://e.com,,,many,,,\n" +
+				"f,https://f.com,,,,,2099-02-01T00:00:00Z,2099-01-01T00:00:00Z\n",
+			policy: postgres.ConflictSkip,
+			expectedLinks: []postgres.Link{
+				{
+					URL: "https://a.com", Alias: "a", Domain: "go.brand-a.com", PasswordHash: testHash,
+					MaxClicks: 5, ClicksUsed: 2, ActiveFrom: &activeFrom, ExpiresAt: &expiresAt,
+				},
+				{URL: "https://b.com", Alias: "b", Domain: "go.unknown.com"},
+			},
+			expectedReport: transfer.Report{
+				Imported: 1,
+				Rejected: []transfer.Rejection{
+					{Line: 4, Alias: "c", Reason: "password_hash is not a bcrypt hash"},
+					{Line: 5, Alias: "d", Reason: "clicks_used must be between 0 and max_clicks"},
+					{Line: 6, Alias: "e", Reason: `invalid max_clicks "many"`},
+					{Line: 7, Alias: "f", Reason: "expires_at must be after active_from"},
+					{Line: 3, Alias: "b", Reason: "unknown domain"},
+				},
+			},
+		},
+		{
+			name:   "JSONL with every link field",
+			format: transfer.FormatJSONL,
+			input: `{"alias": "a", "url": "https://a.com", "redirect_type": 307, "passthrough": "append", "utm": {"source": "mail"},` +
+				` "rules": [{"device": "ios", "target": "https://apps.apple.com"}], "split": {"variants": [{"name": "x", "url": "https://x.com", "weight": 1}]},` +
+				` "title": "Docs", "interstitial": true, "created_at": "2025-03-01T12:00:00Z", "deleted_at": "2025-04-01T12:00:00Z"}` + "\n" +
+				`{"alias": "b", "url": "https://b.com", "rules": [{"target": "https://b.com/app"}]}` + "\n" +
+				`{"alias": "c", "url": "https://c.com", "split": {"variants": [{"name": "x", "url": "https://x.com"}]}}` + "\n",
+			policy: postgres.ConflictSkip,
+			expectedLinks: []postgres.Link{
+				{
+					URL: "https://a.com", Alias: "a", RedirectType: 307, Passthrough: "append", UTM: postgres.UTM{Source: "mail"},
+					Rules: []postgres.RedirectRule{{Device: "ios", Target: "https://apps.apple.com"}},
+					Split: &postgres.Split{Variants: []postgres.Variant{{Name: "x", URL: "https://x.com", Weight: 1}}},
+					Title: "Docs", Interstitial: true, CreatedAt: created, DeletedAt: &deleted,
+				},
+			},
+			expectedReport: transfer.Report{
+				Imported: 1,
+				Rejected: []transfer.Rejection{
+					{Line: 2, Alias: "b", Reason: "rule 0: rule has no conditions"},
+					{Line: 3, Alias: "c", Reason: "split needs at least one variant with positive weight"},
+				},
+			},
+		},
+		{
+			name:   "CSV with json columns",
+			format: transfer.FormatCSV,
+			input: "alias,url,utm,rules,interstitial,deleted_at\n" +
+				`a,https://a.com,"{""source"":""mail""}","[{""countries"":[""DE""],""target"":""https://de.a.com""}]",true,2025-04-01T12:00:00Z` + "\n" +
+				"b,https://b.com,{broken,,,\n" +
+				"c,https://c.com,,,maybe,\n",
+			policy: postgres.ConflictSkip,
+			expectedLinks: []postgres.Link{
+				{
+					URL: "https://a.com", Alias: "a", UTM: postgres.UTM{Source: "mail"},
+					Rules:        []postgres.RedirectRule{{Countries: []string{"DE"}, Target: "https://de.a.com"}},
+					Interstitial: true, DeletedAt: &deleted,
+				},
+			},
+			expectedReport: transfer.Report{
+				Imported: 1,
+				Rejected: []transfer.Rejection{
+					{Line: 3, Alias: "b", Reason: "invalid utm: invalid character 'b' looking for beginning of object key string"},
+					{Line: 4, Alias: "c", Reason: `invalid interstitial "maybe"`},
+				},
+			},
+		},
+		{
+			name:        "Fail on conflict",
+			format:      transfer.FormatJSONL,
+			input:       `{"alias": "existing", "url": "https://e.com"}` + "\n" + `{"alias": "z", "url": "https://z.com"}`,
+			policy:      postgres.ConflictFail,
+			expectedErr: postgres.ErrURLExists,
+			expectedLinks: []postgres.Link{
+				{URL: "https://e.com", Alias: "existing"},
+			},
+			expectedReport: transfer.Report{
+				Conflicts: []transfer.Conflict{{Line: 1, Alias: "existing"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer := &fakeImporter{existing: map[string]bool{"existing": true}}
+
+			report, err := transfer.Import(context.Background(), importer, strings.NewReader(tt.input), tt.format, tt.policy)
+			if tt.expectedErr != nil {
+				require.True(t, errors.Is(err, tt.expectedErr))
+			} else {
+				require.NoError(t, err)
+			}
+
+			links := make([]postgres.Link, 0, len(importer.rows))
+			for _, row := range importer.rows {
+				links = append(links, row.Link)
+			}
+			require.Equal(t, tt.expectedLinks, links)
+			require.Equal(t, tt.expectedReport, report)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := transfer.ParseFormat("CSV")
+	require.NoError(t, err)
+	require.Equal(t, transfer.FormatCSV, f)
+
+	f, err = transfer.ParseFormat("ndjson")
+	require.NoError(t, err)
+	require.Equal(t, transfer.FormatJSONL, f)
+
+	_, err = transfer.ParseFormat("xml")
+	require.Error(t, err)
+}
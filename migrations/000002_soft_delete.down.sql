@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_deleted_at;
+
+DELETE FROM url WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE url DROP COLUMN IF EXISTS deleted_at;
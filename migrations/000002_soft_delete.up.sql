@@ -0,0 +1,3 @@
+ALTER TABLE url ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_url_deleted_at ON url(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_url_tags;
+DROP INDEX IF EXISTS idx_url_created_at;
+DROP INDEX IF EXISTS idx_url_owner;
+
+ALTER TABLE url
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS owner,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,8 @@
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS owner TEXT,
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_url_owner ON url(owner);
+CREATE INDEX IF NOT EXISTS idx_url_created_at ON url(created_at);
+CREATE INDEX IF NOT EXISTS idx_url_tags ON url USING GIN(tags);
@@ -0,0 +1,4 @@
+-- NULL означает статус по умолчанию из конфига
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS redirect_type SMALLINT
+        CHECK (redirect_type IN (301, 302, 307, 308));
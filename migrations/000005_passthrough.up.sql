@@ -0,0 +1,4 @@
+-- NULL отключает перенос пути и параметров запроса в целевой URL
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS passthrough TEXT
+        CHECK (passthrough IN ('keep', 'replace', 'append'));
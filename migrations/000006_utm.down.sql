@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN IF EXISTS utm;
+
+DROP TABLE IF EXISTS utm_templates;
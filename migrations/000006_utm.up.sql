@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS utm_templates (
+    owner      TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    source     TEXT NOT NULL DEFAULT '',
+    medium     TEXT NOT NULL DEFAULT '',
+    campaign   TEXT NOT NULL DEFAULT '',
+    content    TEXT NOT NULL DEFAULT '',
+    term       TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (owner, name)
+);
+
+-- utm-метки ссылки, NULL - без меток
+ALTER TABLE url ADD COLUMN IF NOT EXISTS utm JSONB;
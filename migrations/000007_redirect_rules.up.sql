@@ -0,0 +1,2 @@
+-- Упорядоченные правила редиректа по устройству, языку и стране, NULL - без правил
+ALTER TABLE url ADD COLUMN IF NOT EXISTS rules JSONB;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS clicks;
+
+ALTER TABLE url DROP COLUMN IF EXISTS split;
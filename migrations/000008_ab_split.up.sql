@@ -0,0 +1,12 @@
+-- Взвешенные варианты цели для A/B-тестов, NULL - ссылка ведет на url
+ALTER TABLE url ADD COLUMN IF NOT EXISTS split JSONB;
+
+-- Переходы по ссылкам. variant - имя варианта A/B-теста, пустая строка без теста.
+CREATE TABLE IF NOT EXISTS clicks (
+    id         BIGSERIAL PRIMARY KEY,
+    url_id     INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    variant    TEXT NOT NULL DEFAULT '',
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_url_variant ON clicks(url_id, variant);
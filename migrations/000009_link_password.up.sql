@@ -0,0 +1,2 @@
+-- bcrypt-хеш пароля ссылки, NULL - ссылка открывается без пароля
+ALTER TABLE url ADD COLUMN IF NOT EXISTS password_hash TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE url
+    DROP COLUMN IF EXISTS clicks_used,
+    DROP COLUMN IF EXISTS max_clicks;
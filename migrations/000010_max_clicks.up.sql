@@ -0,0 +1,5 @@
+-- Лимит переходов по ссылке. NULL - без лимита. clicks_used меняется только
+-- условным UPDATE, поэтому лимит соблюдается при параллельных редиректах.
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS max_clicks INTEGER CHECK (max_clicks > 0),
+    ADD COLUMN IF NOT EXISTS clicks_used INTEGER NOT NULL DEFAULT 0;
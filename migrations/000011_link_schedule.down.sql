@@ -0,0 +1,4 @@
+ALTER TABLE url
+    DROP CONSTRAINT IF EXISTS url_schedule_check,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS active_from;
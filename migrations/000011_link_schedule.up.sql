@@ -0,0 +1,5 @@
+-- Окно, в котором ссылка редиректит. NULL - без ограничения с этой стороны.
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD CONSTRAINT url_schedule_check CHECK (expires_at > active_from);
@@ -0,0 +1,3 @@
+ALTER TABLE url
+    DROP COLUMN IF EXISTS interstitial,
+    DROP COLUMN IF EXISTS title;
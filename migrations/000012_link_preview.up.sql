@@ -0,0 +1,5 @@
+-- title показывается на странице предпросмотра. interstitial - вместо редиректа
+-- всегда показывать страницу с адресом назначения.
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS title TEXT,
+    ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,7 @@
+-- Ссылки на доменах брендов удаляются: их алиасы могут повторяться и не пройдут глобальный UNIQUE
+DELETE FROM url WHERE domain IS NOT NULL;
+
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_alias_domain_key;
+ALTER TABLE url ADD CONSTRAINT url_alias_key UNIQUE (alias);
+ALTER TABLE url DROP COLUMN IF EXISTS domain;
+DROP TABLE IF EXISTS domains;
@@ -0,0 +1,13 @@
+-- Короткие домены брендов. Ссылки с domain IS NULL открываются на основном домене.
+CREATE TABLE IF NOT EXISTS domains (
+    id         SERIAL PRIMARY KEY,
+    host       TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE url ADD COLUMN IF NOT EXISTS domain TEXT REFERENCES domains(host);
+
+-- Алиас уникален в пределах домена. NULLS NOT DISTINCT (Postgres 15+) не дает
+-- завести два одинаковых алиаса на основном домене.
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_alias_key;
+ALTER TABLE url ADD CONSTRAINT url_alias_domain_key UNIQUE NULLS NOT DISTINCT (alias, domain);
@@ -0,0 +1,5 @@
+-- Из ссылок на один url остается самая ранняя, иначе глобальный UNIQUE не создать
+DELETE FROM url a USING url b WHERE a.url = b.url AND a.id > b.id;
+
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_url_domain_key;
+ALTER TABLE url ADD CONSTRAINT url_url_key UNIQUE (url);
@@ -0,0 +1,4 @@
+-- url уникален в пределах домена, как и алиас: одна и та же страница может
+-- получить короткую ссылку на каждом домене бренда.
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_url_key;
+ALTER TABLE url ADD CONSTRAINT url_url_domain_key UNIQUE NULLS NOT DISTINCT (url, domain);
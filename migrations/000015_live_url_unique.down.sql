@@ -0,0 +1,7 @@
+-- Удаленные ссылки на уже занятый url удаляются: остается живая, а без нее самая ранняя
+DELETE FROM url a USING url b
+WHERE a.url = b.url AND a.domain IS NOT DISTINCT FROM b.domain AND a.id <> b.id
+    AND a.deleted_at IS NOT NULL AND (b.deleted_at IS NULL OR b.id < a.id);
+
+DROP INDEX IF EXISTS url_url_domain_live_key;
+ALTER TABLE url ADD CONSTRAINT url_url_domain_key UNIQUE NULLS NOT DISTINCT (url, domain);
@@ -0,0 +1,4 @@
+-- Удаленная ссылка не занимает url: его можно сократить заново, а алиас удаленной
+-- ссылки остается зарезервированным до очистки (url_alias_domain_key по всем строкам).
+ALTER TABLE url DROP CONSTRAINT IF EXISTS url_url_domain_key;
+CREATE UNIQUE INDEX IF NOT EXISTS url_url_domain_live_key ON url (url, domain) NULLS NOT DISTINCT WHERE deleted_at IS NULL;